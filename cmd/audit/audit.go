@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/database"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/config"
+	filesystem "github.com/thedhejavu/ev-blockchain-protocol/pkg/fs"
+)
+
+func getStore() database.Store {
+	store, err := database.NewStore("badgerdb", "4000")
+	if err != nil {
+		logger.Panic(err)
+	}
+	return store
+}
+
+func NewCommands() *cobra.Command {
+	var election string
+	var certifiedFile string
+	var certificatesFile string
+	var outFile string
+
+	var auditCommand = &cobra.Command{
+		Use:   "audit",
+		Short: "Independently recount and audit an election",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if election == "" {
+				logger.Fatal("Error: election public key is required")
+			}
+
+			// Certified results use the same format as the QueryRaceResults RPC
+			var certified map[string]blockchain.RaceResult
+			if certifiedFile != "" {
+				content, err := ioutil.ReadFile(certifiedFile)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				if err = json.Unmarshal(content, &certified); err != nil {
+					logger.Fatal(err)
+				}
+			}
+
+			// Voter certificates published by the commission, as PEM blocks in
+			// the order of the accredited voters
+			var certificates [][]byte
+			if certificatesFile != "" {
+				content, err := ioutil.ReadFile(certificatesFile)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				for {
+					var block *pem.Block
+					if block, content = pem.Decode(content); block == nil {
+						break
+					}
+					certificates = append(certificates, pem.EncodeToMemory(block))
+				}
+				if len(certificates) == 0 {
+					logger.Fatalf("Error: no certificate found in %s", certificatesFile)
+				}
+			}
+
+			bc := blockchain.NewBlockchain(getStore(), config.Config{})
+			bc = bc.ReInit()
+
+			report, err := bc.Audit([]byte(election), certified)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if certificates != nil {
+				if err = bc.AuditCertificates(report, certificates); err != nil {
+					logger.Fatal(err)
+				}
+			}
+
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if outFile != "" {
+				err = ioutil.WriteFile(outFile, data, filesystem.OwnerReadWrite)
+				if err != nil {
+					logger.Fatal(err)
+				}
+			} else {
+				fmt.Println(string(data))
+			}
+
+			if report.Passed() == false {
+				os.Exit(1)
+			}
+		},
+	}
+
+	auditCommand.Flags().StringVar(&election, "election", "", "Election public key as the plain string recorded on-chain, e.g. 5_election_12345678")
+	auditCommand.Flags().StringVar(&certifiedFile, "certified", "", "JSON file with the certified result")
+	auditCommand.Flags().StringVar(&certificatesFile, "certificates", "", "PEM file with the voter certificates published by the commission, in the order of the accredited voters")
+	auditCommand.Flags().StringVar(&outFile, "out", "", "Write the report to a file instead of stdout")
+
+	return auditCommand
+}
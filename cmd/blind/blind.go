@@ -0,0 +1,279 @@
+package blind
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/blindsig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+	filesystem "github.com/thedhejavu/ev-blockchain-protocol/pkg/fs"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/prompt"
+	"github.com/thedhejavu/ev-blockchain-protocol/rpc"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+// ID of the keystore of the blind signature key
+const blindKeyID = "blind"
+
+// Token is the ballot token a voter keeps between the blind signature request
+// and the ballot. It must stay private, anyone holding it can cast the ballot.
+type Token struct {
+	Election   string `json:"election"`
+	BlindKey   []byte `json:"blind_key"`
+	PrivateKey []byte `json:"private_key"` // One-time key signing the ballot, SEC1 DER
+	Token      []byte `json:"token"`       // Public key of the one-time key
+	Blinded    []byte `json:"blinded"`
+	Unblinder  []byte `json:"unblinder"`
+	Signature  []byte `json:"signature,omitempty"` // Unblinded commission signature
+}
+
+// BlindBallot is the signature of a blind ballot, in the format of the
+// signature, token and token_signature fields of the ballot input
+type BlindBallot struct {
+	Signature      []byte `json:"signature"`
+	Token          []byte `json:"token"`
+	TokenSignature []byte `json:"token_signature"`
+}
+
+func readFile(file string) []byte {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return content
+}
+
+func writeFile(file string, content []byte) {
+	if err := ioutil.WriteFile(file, content, filesystem.OwnerReadWrite); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func decodeHex(name, value string) []byte {
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		logger.Fatalf("Invalid %s: %s", name, err)
+	}
+	return data
+}
+
+// readBlindKey decrypts the blind signature key written by the init command
+func readBlindKey(file string) *rsa.PrivateKey {
+	ks, err := wallet.ParseKeystore(readFile(file))
+	if err != nil {
+		logger.Fatalf("%s: %s", file, err)
+	}
+	passphrase, err := prompt.Passphrase("Passphrase of the blind signature key: ")
+	if err != nil {
+		logger.Fatal(err)
+	}
+	data, err := ks.DecryptKey(passphrase)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	priv, err := blindsig.ParsePrivateKey(data)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return priv
+}
+
+func readToken(file string) Token {
+	var token Token
+	if err := json.Unmarshal(readFile(file), &token); err != nil {
+		logger.Fatalf("%s: %s", file, err)
+	}
+	return token
+}
+
+func writeToken(file string, token Token) {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		logger.Fatal(err)
+	}
+	writeFile(file, data)
+}
+
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Println(string(data))
+}
+
+func NewCommands() *cobra.Command {
+	var blindCommand = &cobra.Command{
+		Use:   "blind",
+		Short: "Issue and use blind-signed ballot tokens",
+	}
+
+	var out string
+	var initCommand = &cobra.Command{
+		Use:   "init",
+		Short: "Create the blind signature key of the commission",
+		Long: "The private key is written to the file encrypted with a passphrase, " +
+			"the public key printed is the blind_key of the election.",
+		Args: cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if out == "" {
+				logger.Fatal("Error: key file is required")
+			}
+			priv, err := blindsig.GenerateKey()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			public, err := blindsig.MarshalPublicKey(&priv.PublicKey)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			passphrase, err := prompt.NewPassphrase("Passphrase of the blind signature key: ")
+			if err != nil {
+				logger.Fatal(err)
+			}
+			ks, err := wallet.EncryptKey(blindKeyID, blindsig.MarshalPrivateKey(priv), public, passphrase, wallet.StandardKDFParams)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			data, err := json.MarshalIndent(ks, "", "  ")
+			if err != nil {
+				logger.Fatal(err)
+			}
+			writeFile(out, data)
+			fmt.Print(string(public))
+		},
+	}
+
+	var election string
+	var node string
+	var requestCommand = &cobra.Command{
+		Use:   "request",
+		Short: "Create a ballot token and print it blinded for the commission",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if election == "" {
+				logger.Fatal("Error: election public key is required")
+			}
+			if out == "" {
+				logger.Fatal("Error: token file is required")
+			}
+			txElection, err := rpc.NewRemoteSource(node).FindTxWithTxOutput([]byte(election), blockchain.ELECTION_TX_TYPE)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			blindKey := txElection.Output.ElectionTx.BlindKey
+			if len(blindKey) == 0 {
+				logger.Fatal(blockchain.ErrNoBlindKey)
+			}
+			pub, err := blindsig.ParsePublicKey(blindKey)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			privateKey, token := wallet.NewKeyPair()
+			der, err := x509.MarshalECPrivateKey(privateKey)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			blinded, unblinder, err := blindsig.Blind(pub, token)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			writeToken(out, Token{
+				Election:   election,
+				BlindKey:   blindKey,
+				PrivateKey: der,
+				Token:      token,
+				Blinded:    blinded,
+				Unblinder:  unblinder,
+			})
+			fmt.Println(hex.EncodeToString(blinded))
+		},
+	}
+
+	var keyFile string
+	var signCommand = &cobra.Command{
+		Use:   "sign <blinded>",
+		Short: "Sign the hex encoded blinded token of an accredited voter",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			blinded := decodeHex("blinded token", args[0])
+			priv := readBlindKey(keyFile)
+			sig, err := blindsig.Sign(priv, blinded)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			fmt.Println(hex.EncodeToString(sig))
+		},
+	}
+
+	var tokenFile string
+	var unblindCommand = &cobra.Command{
+		Use:   "unblind <blind-signature>",
+		Short: "Unblind the hex encoded signature of the commission into the token",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			blindSig := decodeHex("blind signature", args[0])
+			token := readToken(tokenFile)
+			pub, err := blindsig.ParsePublicKey(token.BlindKey)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if token.Signature, err = blindsig.Unblind(pub, token.Token, blindSig, token.Unblinder); err != nil {
+				logger.Fatal(err)
+			}
+			writeToken(tokenFile, token)
+			logger.Infof("Token %x is signed by the commission", token.Token)
+		},
+	}
+
+	var signBallotCommand = &cobra.Command{
+		Use:   "sign-ballot <ballot>",
+		Short: "Sign hex encoded ballot data with the token",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			data := decodeHex("ballot", args[0])
+			token := readToken(tokenFile)
+			if len(token.Signature) == 0 {
+				logger.Fatalf("%s: token is not signed yet", blockchain.ErrInvalidBlindToken)
+			}
+			privateKey, err := x509.ParseECPrivateKey(token.PrivateKey)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			mu := multisig.NewMultisig(1)
+			mu.AddSignature(data, token.Token, *privateKey)
+			printJSON(BlindBallot{mu.Sigs[0], token.Token, token.Signature})
+		},
+	}
+
+	initCommand.Flags().StringVar(&out, "out", "", "Keystore file of the private blind signature key")
+
+	requestCommand.Flags().StringVar(&election, "election", "", "Election public key as the plain string recorded on-chain, e.g. 5_election_12345678")
+	requestCommand.Flags().StringVar(&out, "out", "", "File keeping the token until the ballot is cast")
+	requestCommand.Flags().StringVar(&node, "node", "http://localhost:4000/json-rpc", "RPC endpoint of a full node")
+
+	signCommand.Flags().StringVar(&keyFile, "key", "", "Keystore file of the blind signature key of the commission")
+
+	unblindCommand.Flags().StringVar(&tokenFile, "token", "", "File of the token")
+
+	signBallotCommand.Flags().StringVar(&tokenFile, "token", "", "File of the token")
+
+	blindCommand.AddCommand(
+		initCommand,
+		requestCommand,
+		signCommand,
+		unblindCommand,
+		signBallotCommand,
+	)
+
+	return blindCommand
+}
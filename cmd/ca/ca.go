@@ -0,0 +1,270 @@
+package ca
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	walletcmd "github.com/thedhejavu/ev-blockchain-protocol/cmd/wallet"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/identity"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	filesystem "github.com/thedhejavu/ev-blockchain-protocol/pkg/fs"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/prompt"
+	"github.com/thedhejavu/ev-blockchain-protocol/rpc"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+const day = 24 * time.Hour
+
+// Revocation is the commission data to sign before a revocation is submitted
+type Revocation struct {
+	Data      string   `json:"data"`
+	Serials   []string `json:"serials"`
+	Timestamp int64    `json:"timestamp"`
+}
+
+// unlockCA decrypts the commission wallet whose main key runs the authority
+func unlockCA(userId string) wallet.WalletGroup {
+	wallets, err := wallet.InitializeWallets()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	passphrase, err := prompt.Passphrase(fmt.Sprintf("Passphrase of wallet %s: ", userId))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	w, err := wallets.Unlock(userId, passphrase)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return w
+}
+
+func readFile(file string) []byte {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return content
+}
+
+func writeFile(file string, content []byte) {
+	if file == "" {
+		fmt.Print(string(content))
+		return
+	}
+	if err := ioutil.WriteFile(file, content, filesystem.OwnerReadWrite); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func NewCommands() *cobra.Command {
+	var caCommand = &cobra.Command{
+		Use:   "ca",
+		Short: "Certify voter identities as the election commission",
+	}
+
+	var userId string
+	var name string
+	var days int
+	var out string
+	var initCommand = &cobra.Command{
+		Use:   "init",
+		Short: "Create the self-signed certificate of the commission authority",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if name == "" {
+				logger.Fatal("Error: authority name is required")
+			}
+			w := unlockCA(userId)
+			caCert, err := identity.NewCA(name, &w.Main.PrivateKey, time.Duration(days)*day)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			writeFile(out, caCert)
+		},
+	}
+
+	var caFile string
+	var voter string
+	var id identity.Identity
+	var issueCommand = &cobra.Command{
+		Use:   "issue",
+		Short: "Issue the certificate of a voter main key",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			voterKey, err := hex.DecodeString(voter)
+			if err != nil {
+				logger.Fatalf("Invalid voter key: %s", err)
+			}
+			pub, err := keys.ParseAny(voterKey)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w := unlockCA(userId)
+			cert, err := identity.Issue(readFile(caFile), &w.Main.PrivateKey, pub.ECDSA(), id, time.Duration(days)*day)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			writeFile(out, cert)
+		},
+	}
+
+	var certFile string
+	var election string
+	var node string
+	var verifyCommand = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the certificate of a voter, and its revocation when an election is given",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			voterKey, err := hex.DecodeString(voter)
+			if err != nil {
+				logger.Fatalf("Invalid voter key: %s", err)
+			}
+			cert, err := identity.Verify(readFile(caFile), readFile(certFile), voterKey, time.Now())
+			if err != nil {
+				logger.Fatal(err)
+			}
+			serial := hex.EncodeToString(identity.Serial(cert))
+			if election != "" {
+				revoked, err := rpc.NewRemoteSource(node).GetRevokedCertificates([]byte(election))
+				if err != nil {
+					logger.Fatal(err)
+				}
+				for _, r := range revoked {
+					if r == serial {
+						logger.Fatalf("%s: serial %s", blockchain.ErrRevokedCertificate, serial)
+					}
+				}
+			}
+
+			data, err := json.MarshalIndent(struct {
+				identity.Identity
+				Serial string `json:"serial"`
+			}{identity.IdentityOf(cert), serial}, "", "  ")
+			if err != nil {
+				logger.Fatal(err)
+			}
+			fmt.Println(string(data))
+		},
+	}
+
+	var certFiles []string
+	var serials []string
+	var signatureFiles []string
+	var timestamp int64
+	var revokeCommand = &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke voter certificates of an election on-chain",
+		Long: "Without signatures the data the commission members sign with " +
+			"`wallet sign` is printed, the revocation is submitted once their " +
+			"signatures are given with the same timestamp.",
+		Args: cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if election == "" {
+				logger.Fatal("Error: election public key is required")
+			}
+			for _, file := range certFiles {
+				cert, err := identity.Parse(readFile(file))
+				if err != nil {
+					logger.Fatalf("%s: %s", file, err)
+				}
+				serials = append(serials, hex.EncodeToString(identity.Serial(cert)))
+			}
+			if len(serials) == 0 {
+				logger.Fatal("Error: no certificate to revoke")
+			}
+			if timestamp == 0 {
+				timestamp = time.Now().Unix()
+			}
+
+			source := rpc.NewRemoteSource(node)
+			txElection, err := source.FindTxWithTxOutput([]byte(election), blockchain.ELECTION_TX_TYPE)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			revocation := blockchain.TxRevocationOutput{
+				TxID:           txElection.ID,
+				ElectionPubKey: []byte(election),
+				Timestamp:      timestamp,
+			}
+			for _, serial := range serials {
+				data, err := hex.DecodeString(strings.TrimPrefix(serial, "0x"))
+				if err != nil {
+					logger.Fatalf("Invalid serial %s: %s", serial, err)
+				}
+				revocation.Serials = append(revocation.Serials, data)
+			}
+
+			if len(signatureFiles) == 0 {
+				data, err := json.MarshalIndent(Revocation{
+					hex.EncodeToString(revocation.ToByte()),
+					serials,
+					timestamp,
+				}, "", "  ")
+				if err != nil {
+					logger.Fatal(err)
+				}
+				fmt.Println(string(data))
+				return
+			}
+
+			for _, file := range signatureFiles {
+				var contribution walletcmd.Contribution
+				if err = json.Unmarshal(readFile(file), &contribution); err != nil {
+					logger.Fatalf("%s: %s", file, err)
+				}
+				revocation.Signers = append(revocation.Signers, contribution.Signer)
+				revocation.SigWitnesses = append(revocation.SigWitnesses, contribution.SigWitness)
+			}
+			txId, err := source.RevokeCertificates([]byte(election), revocation)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			logger.Infof("Revocation recorded in transaction %x", txId)
+		},
+	}
+
+	initCommand.Flags().StringVar(&userId, "user", "", "Commission wallet holding the authority key")
+	initCommand.Flags().StringVar(&name, "name", "", "Name of the commission authority")
+	initCommand.Flags().IntVar(&days, "days", 365, "Validity of the certificate in days")
+	initCommand.Flags().StringVar(&out, "out", "", "Write the certificate to a file instead of stdout")
+
+	issueCommand.Flags().StringVar(&userId, "user", "", "Commission wallet holding the authority key")
+	issueCommand.Flags().StringVar(&caFile, "ca", "", "Certificate of the commission authority")
+	issueCommand.Flags().StringVar(&voter, "voter", "", "Hex encoded main public key of the voter")
+	issueCommand.Flags().StringVar(&id.VoterID, "voter-id", "", "Identity number of the voter")
+	issueCommand.Flags().StringVar(&id.Name, "name", "", "Name of the voter")
+	issueCommand.Flags().StringVar(&id.Region, "region", "", "Region the voter is registered in")
+	issueCommand.Flags().IntVar(&days, "days", 90, "Validity of the certificate in days")
+	issueCommand.Flags().StringVar(&out, "out", "", "Write the certificate to a file instead of stdout")
+
+	verifyCommand.Flags().StringVar(&caFile, "ca", "", "Certificate of the commission authority")
+	verifyCommand.Flags().StringVar(&certFile, "cert", "", "Certificate of the voter")
+	verifyCommand.Flags().StringVar(&voter, "voter", "", "Hex encoded main public key of the voter")
+	verifyCommand.Flags().StringVar(&election, "election", "", "Election public key as the plain string recorded on-chain, e.g. 5_election_12345678, checks the revocations recorded on-chain")
+	verifyCommand.Flags().StringVar(&node, "node", "http://localhost:4000/json-rpc", "RPC endpoint of a full node")
+
+	revokeCommand.Flags().StringVar(&election, "election", "", "Election public key as the plain string recorded on-chain, e.g. 5_election_12345678")
+	revokeCommand.Flags().StringSliceVar(&certFiles, "cert", nil, "Certificate to revoke")
+	revokeCommand.Flags().StringSliceVar(&serials, "serial", nil, "Hex serial of a certificate to revoke")
+	revokeCommand.Flags().StringSliceVar(&signatureFiles, "signatures", nil, "Signature files of the commission members")
+	revokeCommand.Flags().Int64Var(&timestamp, "timestamp", 0, "Timestamp of the revocation, defaults to now")
+	revokeCommand.Flags().StringVar(&node, "node", "http://localhost:4000/json-rpc", "RPC endpoint of a full node")
+
+	caCommand.AddCommand(
+		initCommand,
+		issueCommand,
+		verifyCommand,
+		revokeCommand,
+	)
+
+	return caCommand
+}
@@ -14,12 +14,14 @@ import (
 	"github.com/thedhejavu/ev-blockchain-protocol/pkg/config"
 	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
 	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/ringsig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/prompt"
 	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
 )
 
 const numOfKeys = 3
 
 var (
+	passphrase     []byte
 	DefaultCurve   = elliptic.P256()
 	keyring        *ringsig.PublicKeyRing
 	privKey        *ecdsa.PrivateKey
@@ -42,6 +44,32 @@ func GenerateMainWallet() {
 	keyRingByte = append(keyRingByte, sysWallet.Main.PublicKey)
 }
 
+// aggregateSignatures replaces the commission signatures with a single MuSig2
+// signature of the last signers
+func aggregateSignatures(mu *multisig.MultiSig, data []byte) {
+	if err := mu.Aggregate(data, privKeys[len(privKeys)-sigCount:]); err != nil {
+		logger.Panic(err)
+	}
+}
+
+// walletPassphrase asks once for the passphrase of the engine wallets
+func walletPassphrase() []byte {
+	if passphrase == nil {
+		var err error
+		if passphrase, err = prompt.Passphrase("Wallets passphrase: "); err != nil {
+			logger.Panic(err)
+		}
+	}
+	return passphrase
+}
+
+// saveWallet encrypts the wallet into its keystore
+func saveWallet(wallets *wallet.Wallets, userId string) {
+	if err := wallets.Save(userId, walletPassphrase()); err != nil {
+		logger.Panic(err)
+	}
+}
+
 func getStore() database.Store {
 	store, err := database.NewStore("badgerdb", "4000")
 	if err != nil {
@@ -49,6 +77,78 @@ func getStore() database.Store {
 	}
 	return store
 }
+
+// newSignerWallets creates and saves the wallets of the commission members
+func newSignerWallets() []wallet.WalletGroup {
+	wallets, err := wallet.InitializeWallets()
+	if err != nil {
+		logger.Panic(err)
+	}
+	var signerWallets []wallet.WalletGroup
+	for i := 0; i < sigCount; i++ {
+		userId := wallets.AddWallet(fmt.Sprintf("signers_%d", i))
+		saveWallet(wallets, userId)
+		w, err := wallets.GetWallet(userId)
+		if err != nil {
+			logger.Panic(err)
+		}
+		signerWallets = append(signerWallets, w)
+	}
+	return signerWallets
+}
+
+// signerWallets unlocks the wallets of the commission members
+func signerWallets() []wallet.WalletGroup {
+	wallets, err := wallet.InitializeWallets()
+	if err != nil {
+		logger.Panic(err)
+	}
+	var signerWallets []wallet.WalletGroup
+	for i := 0; i < sigCount; i++ {
+		w, err := wallets.Unlock(fmt.Sprintf("signers_%d", i), walletPassphrase())
+		if err != nil {
+			logger.Panic(err)
+		}
+		signerWallets = append(signerWallets, w)
+	}
+	return signerWallets
+}
+
+// voterWallets unlocks the wallets of the accredited voters, the missing ones
+// are created
+func voterWallets(count int) []wallet.WalletGroup {
+	wallets, err := wallet.InitializeWallets()
+	if err != nil {
+		logger.Panic(err)
+	}
+	var voterWallets []wallet.WalletGroup
+	for i := 0; i < count; i++ {
+		userId := fmt.Sprintf("voters_%d", i)
+		if _, err := wallets.Keystore(userId); err != nil {
+			saveWallet(wallets, wallets.AddWallet(userId))
+		}
+		w, err := wallets.Unlock(userId, walletPassphrase())
+		if err != nil {
+			logger.Panic(err)
+		}
+		voterWallets = append(voterWallets, w)
+	}
+	return voterWallets
+}
+
+// getBlockchain opens the blockchain of the engine, the blocks it adds are
+// signed with the node key
+func getBlockchain() *blockchain.Blockchain {
+	bc := blockchain.NewBlockchain(getStore(), config.Config{})
+	bc = bc.ReInit()
+	signer, err := bc.NodeKeySigner()
+	if err != nil {
+		logger.Panic(err)
+	}
+	bc.SetSigner(signer)
+	return bc
+}
+
 func NewCommands() []*cobra.Command {
 	GenerateMainWallet()
 
@@ -108,14 +208,16 @@ func NewCommands() []*cobra.Command {
 	var stop bool
 	var castBallot bool
 	var getBallot bool
+	var ringSize int
+	var voterCount int
+	var aggregate bool
 
 	var electionCommand = &cobra.Command{
 		Use:   "election",
 		Short: "manage elections",
 		Args:  cobra.MinimumNArgs(0),
 		Run: func(cmd *cobra.Command, args []string) {
-			bc := blockchain.NewBlockchain(getStore(), config.Config{})
-			bc = bc.ReInit()
+			bc := getBlockchain()
 
 			if start {
 				var eTx *blockchain.Transaction
@@ -137,17 +239,10 @@ func NewCommands() []*cobra.Command {
 					totalPeople,
 				)
 
+				commission := newSignerWallets()
 				mu := multisig.NewMultisig(sigCount)
-				for i := 0; i < sigCount; i++ {
-					// Initialize system identity wallet
-					wallets, _ := wallet.InitializeWallets()
-					// Add new identity to the wallet with the User ID
-					userId := wallets.AddWallet(fmt.Sprintf("signers_%d", i))
-					wallets.Save()
-					w, err := wallets.GetWallet(userId)
-					if err != nil {
-						logger.Panic(err)
-					}
+				for i := range commission {
+					w := &commission[i]
 					mu.AddSignature(
 						txOut.ElectionTx.ToByte(),
 						w.Main.PublicKey,
@@ -156,6 +251,9 @@ func NewCommands() []*cobra.Command {
 
 					privKeys = append(privKeys, &w.Main.PrivateKey)
 				}
+				if aggregate {
+					aggregateSignatures(mu, txOut.ElectionTx.ToByte())
+				}
 
 				SigWitnesses = mu.Sigs
 				signers = mu.PubKeys
@@ -187,15 +285,10 @@ func NewCommands() []*cobra.Command {
 					signers,
 					SigWitnesses,
 				)
+				commission := signerWallets()
 				mu := multisig.NewMultisig(sigCount)
-				for i := 0; i < sigCount; i++ {
-					// Initialize system identity wallet
-					wallets, _ := wallet.InitializeWallets()
-					userId := fmt.Sprintf("signers_%d", i)
-					w, err := wallets.GetWallet(userId)
-					if err != nil {
-						logger.Panic(err)
-					}
+				for i := range commission {
+					w := &commission[i]
 					mu.AddSignature(
 						txIn.ElectionTx.ToByte(),
 						w.Main.PublicKey,
@@ -203,6 +296,9 @@ func NewCommands() []*cobra.Command {
 					)
 					privKeys = append(privKeys, &w.Main.PrivateKey)
 				}
+				if aggregate {
+					aggregateSignatures(mu, txIn.ElectionTx.ToByte())
+				}
 
 				electionTx, _ = blockchain.NewTransaction(
 					blockchain.ELECTION_TX_TYPE,
@@ -227,8 +323,7 @@ func NewCommands() []*cobra.Command {
 		Short: "manage accreditation txs",
 		Args:  cobra.MinimumNArgs(0),
 		Run: func(cmd *cobra.Command, args []string) {
-			bc := blockchain.NewBlockchain(getStore(), config.Config{})
-			bc = bc.ReInit()
+			bc := getBlockchain()
 
 			if start {
 				var eaTx *blockchain.Transaction
@@ -244,15 +339,14 @@ func NewCommands() []*cobra.Command {
 					nil,
 					time.Now().Unix(),
 				)
+				signers := signerWallets()
+				// Commit to the ring secret before the voters are accredited
+				secret := blockchain.RingSecret(signers[0].Main.PrivateKey, electionPubkey)
+				txAccreditationOut.AccreditationTx.RingCommitment = blockchain.RingCommitment(secret)
+
 				mu := multisig.NewMultisig(sigCount)
-				for i := 0; i < sigCount; i++ {
-					// Initialize system identity wallet
-					wallets, _ := wallet.InitializeWallets()
-					userId := fmt.Sprintf("signers_%d", i)
-					w, err := wallets.GetWallet(userId)
-					if err != nil {
-						logger.Panic(err)
-					}
+				for i := range signers {
+					w := &signers[i]
 					mu.AddSignature(
 						txAccreditationOut.AccreditationTx.ToByte(),
 						w.Main.PublicKey,
@@ -260,6 +354,9 @@ func NewCommands() []*cobra.Command {
 					)
 					privKeys = append(privKeys, &w.Main.PrivateKey)
 				}
+				if aggregate {
+					aggregateSignatures(mu, txAccreditationOut.AccreditationTx.ToByte())
+				}
 
 				eaTx, _ = blockchain.NewTransaction(
 					blockchain.ACCREDITATION_TX_TYPE,
@@ -285,24 +382,28 @@ func NewCommands() []*cobra.Command {
 				txAcOut, _ := bc.FindTxWithAcOutByPubkey(electionPubkey)
 				fmt.Printf("%x", txAcOut.ID)
 				// return
+				// Publish the accredited voters, ballot rings are drawn from them
+				var voters [][]byte
+				for _, w := range voterWallets(voterCount) {
+					voters = append(voters, w.Main.PublicKey)
+				}
 				txAcIn := blockchain.NewAccreditationTxInput(
 					electionPubkey,
 					txElectionOut.ID,
 					txAcOut.ID,
 					nil,
 					nil,
-					100,
+					int64(len(voters)),
 					time.Now().Unix(),
 				)
+				signers := signerWallets()
+				txAcIn.AccreditationTx.Voters = voters
+				txAcIn.AccreditationTx.RingSize = int64(ringSize)
+				txAcIn.AccreditationTx.RingSecret = blockchain.RingSecret(signers[0].Main.PrivateKey, electionPubkey)
+
 				mu := multisig.NewMultisig(sigCount)
-				for i := 0; i < sigCount; i++ {
-					// Initialize system identity wallet
-					wallets, _ := wallet.InitializeWallets()
-					userId := fmt.Sprintf("signers_%d", i)
-					w, err := wallets.GetWallet(userId)
-					if err != nil {
-						logger.Panic(err)
-					}
+				for i := range signers {
+					w := &signers[i]
 					mu.AddSignature(
 						txAcIn.AccreditationTx.ToByte(),
 						w.Main.PublicKey,
@@ -310,6 +411,9 @@ func NewCommands() []*cobra.Command {
 					)
 					privKeys = append(privKeys, &w.Main.PrivateKey)
 				}
+				if aggregate {
+					aggregateSignatures(mu, txAcIn.AccreditationTx.ToByte())
+				}
 
 				acTx, _ = blockchain.NewTransaction(
 					blockchain.ACCREDITATION_TX_TYPE,
@@ -336,8 +440,7 @@ func NewCommands() []*cobra.Command {
 		Short: "manage voting txs",
 		Args:  cobra.MinimumNArgs(0),
 		Run: func(cmd *cobra.Command, args []string) {
-			bc := blockchain.NewBlockchain(getStore(), config.Config{})
-			bc = bc.ReInit()
+			bc := getBlockchain()
 
 			if start {
 				var vtTx *blockchain.Transaction
@@ -353,15 +456,10 @@ func NewCommands() []*cobra.Command {
 					nil,
 					time.Now().Unix(),
 				)
+				signers := signerWallets()
 				mu := multisig.NewMultisig(sigCount)
-				for i := 0; i < sigCount; i++ {
-					// Initialize system identity wallet
-					wallets, _ := wallet.InitializeWallets()
-					userId := fmt.Sprintf("signers_%d", i)
-					w, err := wallets.GetWallet(userId)
-					if err != nil {
-						logger.Panic(err)
-					}
+				for i := range signers {
+					w := &signers[i]
 					mu.AddSignature(
 						txVotingOut.VotingTx.ToByte(),
 						w.Main.PublicKey,
@@ -369,6 +467,9 @@ func NewCommands() []*cobra.Command {
 					)
 					privKeys = append(privKeys, &w.Main.PrivateKey)
 				}
+				if aggregate {
+					aggregateSignatures(mu, txVotingOut.VotingTx.ToByte())
+				}
 
 				vtTx, _ = blockchain.NewTransaction(
 					blockchain.VOTING_TX_TYPE,
@@ -402,15 +503,10 @@ func NewCommands() []*cobra.Command {
 					nil,
 					time.Now().Unix(),
 				)
+				signers := signerWallets()
 				mu := multisig.NewMultisig(sigCount)
-				for i := 0; i < sigCount; i++ {
-					// Initialize system identity wallet
-					wallets, _ := wallet.InitializeWallets()
-					userId := fmt.Sprintf("signers_%d", i)
-					w, err := wallets.GetWallet(userId)
-					if err != nil {
-						logger.Panic(err)
-					}
+				for i := range signers {
+					w := &signers[i]
 					mu.AddSignature(
 						txVotingIn.VotingTx.ToByte(),
 						w.Main.PublicKey,
@@ -418,6 +514,9 @@ func NewCommands() []*cobra.Command {
 					)
 					privKeys = append(privKeys, &w.Main.PrivateKey)
 				}
+				if aggregate {
+					aggregateSignatures(mu, txVotingIn.VotingTx.ToByte())
+				}
 
 				vTx, _ = blockchain.NewTransaction(
 					blockchain.VOTING_TX_TYPE,
@@ -444,8 +543,7 @@ func NewCommands() []*cobra.Command {
 		Short: "manage ballot txs",
 		Args:  cobra.MinimumNArgs(0),
 		Run: func(cmd *cobra.Command, args []string) {
-			bc := blockchain.NewBlockchain(getStore(), config.Config{})
-			bc = bc.ReInit()
+			bc := getBlockchain()
 
 			if getBallot {
 				logger.Info("Get Ballot!!!!!!!!")
@@ -467,15 +565,10 @@ func NewCommands() []*cobra.Command {
 					time.Now().Unix(),
 				)
 
+				signers := signerWallets()
 				mu := multisig.NewMultisig(sigCount)
-				for i := 0; i < sigCount; i++ {
-					// Initialize system identity wallet
-					wallets, _ := wallet.InitializeWallets()
-					userId := fmt.Sprintf("signers_%d", i)
-					w, err := wallets.GetWallet(userId)
-					if err != nil {
-						logger.Panic(err)
-					}
+				for i := range signers {
+					w := &signers[i]
 					mu.AddSignature(
 						bTxOut.BallotTx.ToByte(),
 						w.Main.PublicKey,
@@ -483,6 +576,9 @@ func NewCommands() []*cobra.Command {
 					)
 					privKeys = append(privKeys, &w.Main.PrivateKey)
 				}
+				if aggregate {
+					aggregateSignatures(mu, bTxOut.BallotTx.ToByte())
+				}
 
 				bTx, _ = blockchain.NewTransaction(
 					blockchain.BALLOT_TX_TYPE,
@@ -494,14 +590,13 @@ func NewCommands() []*cobra.Command {
 				bTx.Output.BallotTx.SigWitnesses = mu.Sigs
 				bTx.Output.BallotTx.Signers = mu.PubKeys
 
-				// Generate Decoy keys
-				for i := 0; i < numOfKeys-1; i++ {
-					w := wallet.MakeWalletGroup()
-					// add the public key part to the ring
-					keyring.Add(w.Main.PrivateKey.PublicKey)
-					keyRingByte = append(keyRingByte, w.Main.PublicKey)
+				// Issue the ballot to the ring of the first voter
+				voter := voterWallets(1)[0]
+				ring, err := bc.GetRing(electionPubkey, voter.Main.PublicKey)
+				if err != nil {
+					logger.Fatal(err)
 				}
-				bTx.Output.BallotTx.PubKeys = keyRingByte
+				bTx.Output.BallotTx.PubKeys = ring
 
 				block, err := bc.AddBlock([]*blockchain.Transaction{bTx})
 
@@ -536,18 +631,25 @@ func NewCommands() []*cobra.Command {
 					blockchain.TxOutput{},
 				)
 
-				// Sign message
-				signature, err := ringsig.Sign(
-					&sysWallet.Main.PrivateKey,
+				// Sign with the ring the ballot was issued to
+				voter := voterWallets(1)[0]
+				ring := txBallotOut.Output.BallotTx.PubKeys
+				keyring, err := ringsig.ParsePublicKeyRing(ring)
+				if err != nil {
+					log.Panic(err)
+				}
+				signature, err := ringsig.SignLinkable(
+					&voter.Main.PrivateKey,
 					keyring,
 					bTxIn.BallotTx.ToByte(),
+					electionPubkey,
 				)
 				if err != nil {
 					log.Panic(err)
 				}
 
 				bTx.Input.BallotTx.Signature = signature.ToByte()
-				bTx.Input.BallotTx.PubKeys = keyRingByte
+				bTx.Input.BallotTx.PubKeys = ring
 
 				block, err := bc.AddBlock([]*blockchain.Transaction{bTx})
 
@@ -571,6 +673,12 @@ func NewCommands() []*cobra.Command {
 
 	ballotCommand.Flags().BoolVar(&getBallot, "get", false, "Get ballot")
 	ballotCommand.Flags().BoolVar(&castBallot, "cast", false, "Cast Ballot")
+	accreditationCommand.Flags().IntVar(&ringSize, "ring-size", numOfKeys, "Number of voter keys in the ballot rings")
+	accreditationCommand.Flags().IntVar(&voterCount, "voters", numOfKeys*2, "Number of accredited voters")
+
+	for _, c := range []*cobra.Command{electionCommand, accreditationCommand, votingCommand, ballotCommand} {
+		c.Flags().BoolVar(&aggregate, "aggregate", false, "Sign with a single aggregate commission signature")
+	}
 
 	return []*cobra.Command{
 		mainCommand,
@@ -2,9 +2,15 @@ package main
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/thedhejavu/ev-blockchain-protocol/cmd/audit"
+	"github.com/thedhejavu/ev-blockchain-protocol/cmd/blind"
+	"github.com/thedhejavu/ev-blockchain-protocol/cmd/ca"
 	"github.com/thedhejavu/ev-blockchain-protocol/cmd/engine"
+	"github.com/thedhejavu/ev-blockchain-protocol/cmd/receipt"
 	"github.com/thedhejavu/ev-blockchain-protocol/cmd/server"
+	"github.com/thedhejavu/ev-blockchain-protocol/cmd/signer"
 	"github.com/thedhejavu/ev-blockchain-protocol/cmd/wallet"
+	walletstore "github.com/thedhejavu/ev-blockchain-protocol/wallet"
 )
 
 func main() {
@@ -12,11 +18,19 @@ func main() {
 		Use: "ev",
 		Run: func(cmd *cobra.Command, args []string) {},
 	}
+	app.PersistentFlags().StringVar(&walletstore.DefaultStore, "wallet-store", walletstore.STORE_FILE, "Storage of the wallets, file or badgerdb")
+
 	engine := engine.NewCommands()
 	app.AddCommand(engine...)
 	app.AddCommand(
 		wallet.NewCommands(),
 		server.NewCommands(),
+		audit.NewCommands(),
+		receipt.NewCommands(),
+		ca.NewCommands(),
+		signer.NewCommands(),
+		blind.NewCommands(),
 	)
 	app.Execute()
+	walletstore.CloseStores()
 }
@@ -0,0 +1,78 @@
+package receipt
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/rpc"
+)
+
+func NewCommands() *cobra.Command {
+	var receiptCommand = &cobra.Command{
+		Use:   "receipt",
+		Short: "Manage ballot receipts",
+	}
+
+	var receiptFile string
+	var node string
+	var nodePubKey string
+	var verifyCommand = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify a ballot receipt was recorded as cast",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if receiptFile == "" {
+				logger.Fatal("Error: receipt file is required")
+			}
+
+			// Receipts use the same format as the CastBallot RPC response
+			content, err := ioutil.ReadFile(receiptFile)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			var receipt blockchain.Receipt
+			if err = json.Unmarshal(content, &receipt); err != nil {
+				logger.Fatal(err)
+			}
+
+			// Only trust receipts issued by the given node
+			if nodePubKey != "" {
+				key, err := hex.DecodeString(nodePubKey)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				if keys.Equal(key, receipt.NodePubKey) == false {
+					logger.Error("Receipt was not issued by the given node")
+					os.Exit(1)
+				}
+			}
+
+			// The node checks the receipt against its chain and trusted node keys
+			result, err := rpc.NewRemoteSource(node).VerifyReceipt(receipt)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if result.Valid == false {
+				logger.Error(result.Reason)
+				os.Exit(1)
+			}
+			logger.Infof("Ballot %x is recorded in block %x at height %d", receipt.TxID, receipt.BlockHash, receipt.Height)
+		},
+	}
+
+	verifyCommand.Flags().StringVar(&receiptFile, "file", "", "JSON file with the ballot receipt")
+	verifyCommand.Flags().StringVar(&node, "node", "http://localhost:4000/json-rpc", "RPC endpoint of a full node")
+	verifyCommand.Flags().StringVar(&nodePubKey, "node-key", "", "Hex encoded public key of the node expected to sign the receipt")
+
+	receiptCommand.AddCommand(
+		verifyCommand,
+	)
+
+	return receiptCommand
+}
@@ -1,26 +1,92 @@
 package server
 
 import (
+	"encoding/hex"
 	"fmt"
 
+	logger "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/remotesigner"
 	"github.com/thedhejavu/ev-blockchain-protocol/rpc"
 )
 
+// decodeKeys decodes the hex encoded public keys of the flag
+func decodeKeys(values []string) [][]byte {
+	var decoded [][]byte
+	for _, v := range values {
+		key, err := hex.DecodeString(v)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		decoded = append(decoded, key)
+	}
+	return decoded
+}
+
 func NewCommands() *cobra.Command {
 	var rpcPort string
+	var walletId string
+	var light bool
+	var remote string
+	var validators []string
+	var genesis string
+	var receiptKeys []string
+	var signerAddr string
+	var signerTLS remotesigner.TLSConfig
 	var rpcCommand = &cobra.Command{
 		Use:   "rpc",
 		Short: "Manage RPC Server",
 		Args:  cobra.MinimumNArgs(0),
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Println(rpcPort)
-			rpc.StartServer(rpcPort)
+			if light == false {
+				var signer blockchain.NodeSigner
+				if signerAddr != "" {
+					if walletId != "" {
+						logger.Fatal("Error: a node uses either a wallet or a remote signer")
+					}
+					var config *remotesigner.TLSConfig
+					if signerTLS.CertFile != "" {
+						config = &signerTLS
+					}
+					client, err := remotesigner.Dial(signerAddr, config)
+					if err != nil {
+						logger.Fatal(err)
+					}
+					logger.Infof("Signing with remote signer %s, key %x", signerAddr, client.PublicKey())
+					signer = client
+				} else {
+					signer = rpc.WalletSigner(walletId)
+				}
+				rpc.StartServer(rpcPort, signer, decodeKeys(receiptKeys))
+				return
+			}
+
+			if remote == "" {
+				logger.Fatal("Error: remote full node is required in light mode")
+			}
+			validatorKeys := decodeKeys(validators)
+			genesisHash, err := hex.DecodeString(genesis)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			rpc.StartLightServer(rpcPort, remote, validatorKeys, genesisHash)
 		},
 	}
 
 	rpcCommand.Flags().StringVar(&rpcPort, "port", "4000", "RPC server Port")
+	rpcCommand.Flags().StringVar(&walletId, "wallet", "", "ID of the node wallet signing ballot receipts and blocks")
+	rpcCommand.Flags().BoolVar(&light, "light", false, "Only store block headers and fetch transactions from a full node")
+	rpcCommand.Flags().StringVar(&remote, "remote", "", "JSON-RPC URL of the full node followed in light mode")
+	rpcCommand.Flags().StringSliceVar(&validators, "validators", nil, "Hex encoded public key of the validator signing blocks, light nodes follow a single validator")
+	rpcCommand.Flags().StringSliceVar(&receiptKeys, "receipt-keys", nil, "Hex encoded public keys of the other nodes whose ballot receipts are trusted")
+	rpcCommand.Flags().StringVar(&genesis, "genesis", "", "Hex encoded hash of the trusted genesis block")
+	rpcCommand.Flags().StringVar(&signerAddr, "signer", "", "Address of the remote signer holding the node keys, unix:///path or tcp://host:port")
+	rpcCommand.Flags().StringVar(&signerTLS.CertFile, "signer-cert", "", "Client certificate of the node for a TCP remote signer")
+	rpcCommand.Flags().StringVar(&signerTLS.KeyFile, "signer-key", "", "Client key of the node for a TCP remote signer")
+	rpcCommand.Flags().StringVar(&signerTLS.CAFile, "signer-ca", "", "CA certificate authenticating the TCP remote signer")
 
 	return rpcCommand
 }
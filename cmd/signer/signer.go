@@ -0,0 +1,88 @@
+package signer
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	filesystem "github.com/thedhejavu/ev-blockchain-protocol/pkg/fs"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/prompt"
+	"github.com/thedhejavu/ev-blockchain-protocol/remotesigner"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+var signerPath = path.Join(wallet.Root, "/storage/signer")
+
+func NewCommands() *cobra.Command {
+	var walletId string
+	var listen string
+	var policyFile string
+	var watermarkFile string
+	var tlsConfig remotesigner.TLSConfig
+
+	var signerCommand = &cobra.Command{
+		Use:   "signer",
+		Short: "Run the remote signer holding the keys of a node",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if walletId == "" {
+				logger.Fatal("Error: wallet is required")
+			}
+			if policyFile == "" {
+				logger.Fatal("Error: policy is required")
+			}
+			policy, err := remotesigner.LoadPolicy(policyFile)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if err = filesystem.ExistOrCreate(filepath.Dir(watermarkFile)); err != nil {
+				logger.Fatal(err)
+			}
+
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			passphrase, err := prompt.Passphrase(fmt.Sprintf("Passphrase of wallet %s: ", walletId))
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w, err := wallets.Unlock(walletId, passphrase)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			server, err := remotesigner.NewServer(
+				blockchain.NewLocalSigner(w.Main.PrivateKey, w.Main.PublicKey),
+				policy,
+				watermarkFile,
+			)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			var config *remotesigner.TLSConfig
+			if tlsConfig.CertFile != "" {
+				config = &tlsConfig
+			}
+			listener, err := remotesigner.Listen(listen, config)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			logger.Infof("Signer of key %x listening on %s", w.Main.PublicKey, listen)
+			logger.Fatal(server.Serve(listener))
+		},
+	}
+
+	signerCommand.Flags().StringVar(&walletId, "wallet", "", "ID of the wallet holding the node and commission key")
+	signerCommand.Flags().StringVar(&listen, "listen", "unix://"+path.Join(signerPath, "signer.sock"), "Address to listen on, unix:///path or tcp://host:port")
+	signerCommand.Flags().StringVar(&policyFile, "policy", "", "JSON policy listing the elections, phases, blocks and receipts to sign")
+	signerCommand.Flags().StringVar(&watermarkFile, "watermark", path.Join(signerPath, "watermark.json"), "File keeping the last signed block")
+	signerCommand.Flags().StringVar(&tlsConfig.CertFile, "tls-cert", "", "Server certificate for TCP connections")
+	signerCommand.Flags().StringVar(&tlsConfig.KeyFile, "tls-key", "", "Server key for TCP connections")
+	signerCommand.Flags().StringVar(&tlsConfig.CAFile, "tls-ca", "", "CA certificate of the nodes allowed to connect over TCP")
+
+	return signerCommand
+}
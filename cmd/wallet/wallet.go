@@ -1,12 +1,76 @@
 package wallet
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
 	"github.com/google/uuid"
 	logger "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/ringsig"
+	filesystem "github.com/thedhejavu/ev-blockchain-protocol/pkg/fs"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/prompt"
+	"github.com/thedhejavu/ev-blockchain-protocol/rpc"
 	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
 )
 
+// Wallet import and export formats
+const (
+	FORMAT_PEM  = "pem"
+	FORMAT_JSON = "json"
+)
+
+// Contribution is the signature of a commission member, in the format of the
+// signers and sig_witnesses fields of the transactions
+type Contribution struct {
+	Signer     []byte `json:"signer"`
+	SigWitness []byte `json:"sig_witness"`
+}
+
+// BallotSignature is the ring signature of a ballot, in the format of the
+// signature and pub_keys fields of the ballot input
+type BallotSignature struct {
+	Signature []byte   `json:"signature"`
+	PubKeys   [][]byte `json:"pub_keys"`
+}
+
+// unlockWallet asks for the passphrase of the wallet and decrypts it
+func unlockWallet(wallets *wallet.Wallets, userId string) wallet.WalletGroup {
+	passphrase, err := prompt.Passphrase(fmt.Sprintf("Passphrase of wallet %s: ", userId))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	w, err := wallets.Unlock(userId, passphrase)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return w
+}
+
+// decodeHex decodes a hex command argument
+func decodeHex(name, value string) []byte {
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		logger.Fatalf("Invalid %s: %s", name, err)
+	}
+	return data
+}
+
+// printJSON writes the value to the standard output
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Println(string(data))
+}
+
 func NewCommands() *cobra.Command {
 	var walletCommand = &cobra.Command{
 		Use:   "wallet",
@@ -14,6 +78,7 @@ func NewCommands() *cobra.Command {
 	}
 
 	var userId string
+	var withMnemonic bool
 	var createCommand = &cobra.Command{
 		Use:   "create",
 		Short: "Create a new wallet locally",
@@ -29,11 +94,43 @@ func NewCommands() *cobra.Command {
 
 			logger.Infof("WALLET ID: %s", userId)
 
+			if err = wallet.ValidateWalletID(userId); err != nil {
+				logger.Fatal(err)
+			}
+			passphrase, err := prompt.NewPassphrase("New wallet passphrase: ")
+			if err != nil {
+				logger.Fatal(err)
+			}
+
 			// Initialize system identity wallet
-			wallets, _ := wallet.InitializeWallets()
-			// Add new identity to the wallet with the User ID
-			wallets.AddWallet(userId)
-			wallets.Save()
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if withMnemonic {
+				mnemonic, err := wallet.NewMnemonic()
+				if err != nil {
+					logger.Panic(err)
+				}
+				seed, err := wallet.SeedFromMnemonic(mnemonic, "")
+				if err != nil {
+					logger.Panic(err)
+				}
+				w, err := wallet.MakeHDWalletGroup(seed)
+				if err != nil {
+					logger.Panic(err)
+				}
+				if err = wallets.Import(userId, w); err != nil {
+					logger.Fatal(err)
+				}
+				fmt.Printf("Write down the recovery phrase of the wallet and keep it safe:\n\n%s\n\n", mnemonic)
+			} else {
+				// Add new identity to the wallet with the User ID
+				wallets.AddWallet(userId)
+			}
+			if err = wallets.Save(userId, passphrase); err != nil {
+				logger.Fatal(err)
+			}
 			w, err := wallets.GetWallet(userId)
 			if err != nil {
 				logger.Panic(err)
@@ -42,10 +139,433 @@ func NewCommands() *cobra.Command {
 		},
 	}
 
+	var recoverCommand = &cobra.Command{
+		Use:   "recover",
+		Short: "Recover a wallet from its recovery phrase",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := wallet.ValidateWalletID(userId); err != nil {
+				logger.Fatal(err)
+			}
+			mnemonic, err := prompt.Secret("Recovery phrase: ")
+			if err != nil {
+				logger.Fatal(err)
+			}
+			seed, err := wallet.SeedFromMnemonic(strings.Join(strings.Fields(string(mnemonic)), " "), "")
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w, err := wallet.MakeHDWalletGroup(seed)
+			if err != nil {
+				logger.Panic(err)
+			}
+			passphrase, err := prompt.NewPassphrase("New wallet passphrase: ")
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if err = wallets.Import(userId, w); err != nil {
+				logger.Fatal(err)
+			}
+			if err = wallets.Save(userId, passphrase); err != nil {
+				logger.Fatal(err)
+			}
+			logger.Infof("WALLET ID: %s", userId)
+			logger.Info(w.String())
+		},
+	}
+
+	var election string
+	var derivedId string
+	var deriveCommand = &cobra.Command{
+		Use:   "derive",
+		Short: "Derive the wallet used for an election from a recoverable wallet",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			electionPubKey := []byte(election)
+			if derivedId == "" {
+				h := sha256.Sum256(electionPubKey)
+				derivedId = fmt.Sprintf("%s-%x", userId, h[:4])
+			}
+			if err := wallet.ValidateWalletID(derivedId); err != nil {
+				logger.Fatal(err)
+			}
+
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			passphrase, err := prompt.Passphrase(fmt.Sprintf("Passphrase of wallet %s: ", userId))
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w, err := wallets.Unlock(userId, passphrase)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			derived, err := w.ElectionWallet(electionPubKey)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			// The derived wallet is protected by the passphrase of the wallet
+			// it is derived from
+			if err = wallets.Import(derivedId, derived); err != nil {
+				logger.Fatal(err)
+			}
+			if err = wallets.Save(derivedId, passphrase); err != nil {
+				logger.Fatal(err)
+			}
+			logger.Infof("WALLET ID: %s", derivedId)
+			logger.Info(derived.String())
+		},
+	}
+
+	var listCommand = &cobra.Command{
+		Use:   "list",
+		Short: "List the saved wallets",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			for _, id := range wallets.IDs() {
+				ks, err := wallets.Keystore(id)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				fmt.Printf("%s\t%x\n", id, ks.MainPublicKey)
+			}
+		},
+	}
+
+	var showCommand = &cobra.Command{
+		Use:   "show",
+		Short: "Show the public keys of a wallet",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			// The public keys are readable without unlocking the keystore
+			ks, err := wallets.Keystore(userId)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			fmt.Printf("WALLET ID: %s\n", ks.ID)
+			fmt.Printf("Public Main Key: %x\n", ks.MainPublicKey)
+			fmt.Printf("Public View Key:\n%s", ks.ViewPublicKey)
+		},
+	}
+
+	var deleteCommand = &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a wallet, its passphrase is required",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			unlockWallet(wallets, userId)
+			if err = wallets.Delete(userId); err != nil {
+				logger.Fatal(err)
+			}
+			logger.Infof("WALLET ID: %s deleted", userId)
+		},
+	}
+
+	var signCommand = &cobra.Command{
+		Use:   "sign <data>",
+		Short: "Sign hex encoded transaction data as a commission member",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			data := decodeHex("data", args[0])
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w := unlockWallet(wallets, userId)
+
+			mu := multisig.NewMultisig(1)
+			mu.AddSignature(data, w.Main.PublicKey, w.Main.PrivateKey)
+			printJSON(Contribution{Signer: mu.PubKeys[0], SigWitness: mu.Sigs[0]})
+		},
+	}
+
+	var node string
+	var ringSignCommand = &cobra.Command{
+		Use:   "ring-sign <ballot>",
+		Short: "Sign hex encoded ballot data with the ballot ring of the voter",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			data := decodeHex("ballot", args[0])
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w := unlockWallet(wallets, userId)
+
+			ring, err := rpc.NewRemoteSource(node).GetRing([]byte(election), w.Main.PublicKey)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			keyring, err := ringsig.ParsePublicKeyRing(ring)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			signature, err := ringsig.SignLinkable(&w.Main.PrivateKey, keyring, data, []byte(election))
+			if err != nil {
+				logger.Fatal(err)
+			}
+			printJSON(BallotSignature{signature.ToByte(), ring})
+		},
+	}
+
+	var decryptCommand = &cobra.Command{
+		Use:   "decrypt <ciphertext>",
+		Short: "Decrypt a hex encoded secret message with the view key",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ciphertext := decodeHex("ciphertext", args[0])
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w := unlockWallet(wallets, userId)
+
+			message, err := w.View.Decrypt(ciphertext)
+			if err != nil {
+				logger.Fatalf("Secret message is not for this wallet: %s", err)
+			}
+			fmt.Println(string(message))
+		},
+	}
+
+	var remoteScan bool
+	var scanCommand = &cobra.Command{
+		Use:   "scan",
+		Short: "Find the ballots of an election addressed to the view key of the wallet",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w := unlockWallet(wallets, userId)
+			source := rpc.NewRemoteSource(node)
+
+			var scanned []blockchain.ScannedBallot
+			if remoteScan {
+				// The node learns which ballots belong to the wallet
+				viewKey, err := w.View.MarshalPEM()
+				if err != nil {
+					logger.Fatal(err)
+				}
+				if scanned, err = source.ScanBallotOutputs([]byte(election), viewKey); err != nil {
+					logger.Fatal(err)
+				}
+			} else {
+				outputs, err := source.QueryUnUsedBallotTxs([]byte(election))
+				if err != nil {
+					logger.Fatal(err)
+				}
+				scanned = blockchain.ScanBallots(outputs, w.View)
+			}
+			printJSON(scanned)
+		},
+	}
+
+	var migrateCommand = &cobra.Command{
+		Use:   "migrate",
+		Short: "Encrypt the wallets saved unencrypted by earlier versions",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			passphrase, err := prompt.NewPassphrase("New passphrase for the migrated wallets: ")
+			if err != nil {
+				logger.Fatal(err)
+			}
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			migrated, err := wallets.MigrateFile(passphrase)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			for _, id := range migrated {
+				logger.Infof("WALLET ID: %s encrypted", id)
+			}
+		},
+	}
+
+	var format string
+	var file string
+	var exportCommand = &cobra.Command{
+		Use:   "export",
+		Short: "Export a wallet as PEM keys or as a JSON keystore",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			var content []byte
+			switch format {
+			case FORMAT_PEM:
+				passphrase, err := prompt.Passphrase(fmt.Sprintf("Passphrase of wallet %s: ", userId))
+				if err != nil {
+					logger.Fatal(err)
+				}
+				w, err := wallets.Unlock(userId, passphrase)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				if content, err = w.MarshalPEM(); err != nil {
+					logger.Fatal(err)
+				}
+				logger.Warn("The exported private keys are not encrypted")
+			case FORMAT_JSON:
+				ks, err := wallets.Keystore(userId)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				if content, err = json.MarshalIndent(ks, "", "  "); err != nil {
+					logger.Fatal(err)
+				}
+			default:
+				logger.Fatalf("Unknown format %q, expected %s or %s", format, FORMAT_PEM, FORMAT_JSON)
+			}
+
+			if err = ioutil.WriteFile(file, content, filesystem.OwnerReadWrite); err != nil {
+				logger.Fatal(err)
+			}
+			logger.Infof("WALLET ID: %s exported to %s", userId, file)
+		},
+	}
+
+	var importCommand = &cobra.Command{
+		Use:   "import",
+		Short: "Import a wallet from PEM keys or from a JSON keystore",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			var w *wallet.WalletGroup
+			var passphrase []byte
+			switch format {
+			case FORMAT_PEM:
+				if w, err = wallet.ParsePEM(content); err != nil {
+					logger.Fatal(err)
+				}
+				if passphrase, err = prompt.NewPassphrase("New wallet passphrase: "); err != nil {
+					logger.Fatal(err)
+				}
+			case FORMAT_JSON:
+				ks, err := wallet.ParseKeystore(content)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				if userId == "" {
+					userId = ks.ID
+				}
+				if passphrase, err = prompt.Passphrase(fmt.Sprintf("Passphrase of keystore %s: ", ks.ID)); err != nil {
+					logger.Fatal(err)
+				}
+				if w, err = ks.Decrypt(passphrase); err != nil {
+					logger.Fatal(err)
+				}
+				if err = w.VerifyCertificate(); err != nil {
+					logger.Fatal(err)
+				}
+			default:
+				logger.Fatalf("Unknown format %q, expected %s or %s", format, FORMAT_PEM, FORMAT_JSON)
+			}
+
+			if userId == "" {
+				id, err := uuid.NewUUID()
+				if err != nil {
+					logger.Panic(err)
+				}
+				userId = id.String()
+			}
+			wallets, err := wallet.InitializeWallets()
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if err = wallets.Import(userId, w); err != nil {
+				logger.Fatal(err)
+			}
+			if err = wallets.Save(userId, passphrase); err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Infof("WALLET ID: %s", userId)
+			logger.Info(w.String())
+		},
+	}
+
 	createCommand.Flags().StringVar(&userId, "user", "", "Unique ID of user")
+	createCommand.Flags().BoolVar(&withMnemonic, "mnemonic", false, "Derive the wallet from a recovery phrase")
+
+	recoverCommand.Flags().StringVar(&userId, "user", "", "ID of the recovered wallet")
+	recoverCommand.MarkFlagRequired("user")
+
+	deriveCommand.Flags().StringVar(&userId, "user", "", "ID of the wallet to derive from")
+	deriveCommand.Flags().StringVar(&election, "election", "", "Election public key as the plain string recorded on-chain, e.g. 5_election_12345678")
+	deriveCommand.Flags().StringVar(&derivedId, "as", "", "ID of the derived wallet, the user ID followed by the election hash by default")
+	deriveCommand.MarkFlagRequired("user")
+	deriveCommand.MarkFlagRequired("election")
+
+	exportCommand.Flags().StringVar(&userId, "user", "", "ID of the wallet to export")
+	exportCommand.Flags().StringVar(&format, "format", FORMAT_PEM, "Export format, pem or json")
+	exportCommand.Flags().StringVar(&file, "out", "", "File to export the wallet to")
+	exportCommand.MarkFlagRequired("user")
+	exportCommand.MarkFlagRequired("out")
+
+	importCommand.Flags().StringVar(&userId, "user", "", "ID of the imported wallet, the keystore ID by default")
+	importCommand.Flags().StringVar(&format, "format", FORMAT_PEM, "Import format, pem or json")
+	importCommand.Flags().StringVar(&file, "file", "", "File to import the wallet from")
+	importCommand.MarkFlagRequired("file")
+
+	for _, c := range []*cobra.Command{showCommand, deleteCommand, signCommand, ringSignCommand, decryptCommand} {
+		c.Flags().StringVar(&userId, "user", "", "ID of the wallet")
+		c.MarkFlagRequired("user")
+	}
+	ringSignCommand.Flags().StringVar(&election, "election", "", "Election public key as the plain string recorded on-chain, e.g. 5_election_12345678")
+	ringSignCommand.Flags().StringVar(&node, "node", "http://localhost:4000/json-rpc", "JSON-RPC URL of the node to fetch the ballot ring from")
+	ringSignCommand.MarkFlagRequired("election")
+
+	scanCommand.Flags().StringVar(&userId, "user", "", "ID of the wallet")
+	scanCommand.Flags().StringVar(&election, "election", "", "Election public key as the plain string recorded on-chain, e.g. 5_election_12345678")
+	scanCommand.Flags().StringVar(&node, "node", "http://localhost:4000/json-rpc", "JSON-RPC URL of the node to fetch the ballots from")
+	scanCommand.Flags().BoolVar(&remoteScan, "remote", false, "Send the private view keys to the node to scan the ballots")
+	scanCommand.MarkFlagRequired("user")
+	scanCommand.MarkFlagRequired("election")
 
 	walletCommand.AddCommand(
 		createCommand,
+		listCommand,
+		showCommand,
+		deleteCommand,
+		signCommand,
+		ringSignCommand,
+		decryptCommand,
+		scanCommand,
+		recoverCommand,
+		deriveCommand,
+		migrateCommand,
+		exportCommand,
+		importCommand,
 	)
 
 	return walletCommand
@@ -1,7 +1,6 @@
 package blockchain
 
 import (
-	"crypto/sha256"
 	"fmt"
 	"reflect"
 	"strings"
@@ -18,17 +17,24 @@ type TxAcOutput struct {
 	SigWitnesses   [][]byte `json:"sig_witnesses"`
 	ElectionPubKey []byte   `json:"election_pubkey"`
 	Timestamp      int64    `json:"timestamp"`
+	VoterRoot      []byte   `json:"voter_root"`      // Merkle root of the eligible voter public keys
+	RingCommitment []byte   `json:"ring_commitment"` // Hash of the secret seeding the ballot rings
 }
 
 // End Vote Accreditation TxInput
 type TxAcInput struct {
-	TxID            []byte   `json:"tx_id"`
-	Signers         [][]byte `json:"signers"`
-	SigWitnesses    [][]byte `json:"sig_witnesses"`
-	TxOut           []byte   `json:"tx_out"`
-	ElectionPubKey  []byte   `json:"election_pubkey"`
-	AccreditedCount int64    `json:"accreditation_count"`
-	Timestamp       int64    `json:"timestamp"`
+	TxID               []byte   `json:"tx_id"`
+	Signers            [][]byte `json:"signers"`
+	SigWitnesses       [][]byte `json:"sig_witnesses"`
+	TxOut              []byte   `json:"tx_out"`
+	ElectionPubKey     []byte   `json:"election_pubkey"`
+	AccreditedCount    int64    `json:"accreditation_count"`
+	Timestamp          int64    `json:"timestamp"`
+	Voters             [][]byte `json:"voters"`              // Public keys of the accredited voters, ballot rings are drawn from them
+	RingSize           int64    `json:"ring_size"`           // Number of voter keys in each ballot ring
+	CertificateSerials [][]byte `json:"certificate_serials"` // Serials of the voter certificates in the same order, the certificates stay off-chain
+	RingSecret         []byte   `json:"ring_secret"`         // Secret committed when accreditation started, seeds the ballot rings
+	CertificateHashes  [][]byte `json:"certificate_hashes"`  // Hashes of the voter certificates in the same order, see identity.Fingerprint
 }
 
 // NewTxAccreditationInput Stops Accreditation  Phase
@@ -75,18 +81,30 @@ func (tx *TxAcOutput) TrimmedCopy() TxAcOutput {
 		nil,
 		tx.ElectionPubKey,
 		tx.Timestamp,
+		tx.VoterRoot,
+		tx.RingCommitment,
 	}
 	return txCopy
 }
 
 // Convert Election output to Byte for verification and signing purposes
 func (tx *TxAcOutput) ToByte() []byte {
-	var hash [32]byte
-
-	txCopy := tx.TrimmedCopy()
+	return signingHash(
+		txAcOutputV1{"", tx.TxID, nil, nil, tx.ElectionPubKey, tx.Timestamp},
+		payloadField{"voter_root", tx.VoterRoot},
+		payloadField{"ring_commitment", tx.RingCommitment},
+	)
+}
 
-	hash = sha256.Sum256([]byte(fmt.Sprintf("%x", txCopy)))
-	return hash[:]
+// txAcOutputV1 is the layout of the first version of accreditation outputs,
+// the signing payload starts with it
+type txAcOutputV1 struct {
+	ID             string
+	TxID           []byte
+	Signers        [][]byte
+	SigWitnesses   [][]byte
+	ElectionPubKey []byte
+	Timestamp      int64
 }
 
 // Trim election input data
@@ -99,18 +117,37 @@ func (tx *TxAcInput) TrimmedCopy() TxAcInput {
 		tx.ElectionPubKey,
 		tx.AccreditedCount,
 		tx.Timestamp,
+		tx.Voters,
+		tx.RingSize,
+		tx.CertificateSerials,
+		tx.RingSecret,
+		tx.CertificateHashes,
 	}
 	return txCopy
 }
 
 // Convert Election output to Byte for verification and signing purposes
 func (tx *TxAcInput) ToByte() []byte {
-	var hash [32]byte
-
-	txCopy := tx.TrimmedCopy()
+	return signingHash(
+		txAcInputV1{tx.TxID, nil, nil, tx.TxOut, tx.ElectionPubKey, tx.AccreditedCount, tx.Timestamp},
+		payloadField{"voters", tx.Voters},
+		payloadField{"ring_size", tx.RingSize},
+		payloadField{"certificate_serials", tx.CertificateSerials},
+		payloadField{"ring_secret", tx.RingSecret},
+		payloadField{"certificate_hashes", tx.CertificateHashes},
+	)
+}
 
-	hash = sha256.Sum256([]byte(fmt.Sprintf("%x", txCopy)))
-	return hash[:]
+// txAcInputV1 is the layout of the first version of accreditation inputs, the
+// signing payload starts with it
+type txAcInputV1 struct {
+	TxID            []byte
+	Signers         [][]byte
+	SigWitnesses    [][]byte
+	TxOut           []byte
+	ElectionPubKey  []byte
+	AccreditedCount int64
+	Timestamp       int64
 }
 
 func (tx *TxAcInput) IsSet() bool {
@@ -125,6 +162,12 @@ func (tx *TxAcInput) String() string {
 	if tx.IsSet() {
 		lines = append(lines, fmt.Sprintf("Accreditation Count: %d", tx.AccreditedCount))
 		lines = append(lines, fmt.Sprintf("Timestamp: %d", tx.Timestamp))
+		if tx.RingSize > 0 {
+			lines = append(lines, fmt.Sprintf("Ring Size: %d", tx.RingSize))
+		}
+		for i := 0; i < len(tx.Voters); i++ {
+			lines = append(lines, fmt.Sprintf("(Voters) \n --(%d): %x", i, tx.Voters[i]))
+		}
 		for i := 0; i < len(tx.Signers); i++ {
 			lines = append(lines, fmt.Sprintf("(Signers) \n --(%d): %x", i, tx.Signers[i]))
 		}
@@ -151,6 +194,10 @@ func (tx *TxAcOutput) String() string {
 		for i := 0; i < len(tx.SigWitnesses); i++ {
 			lines = append(lines, fmt.Sprintf("(Signature Witness): \n --(%d): %x", i, tx.SigWitnesses[i]))
 		}
+		lines = append(lines, fmt.Sprintf("Voter Root: %x", tx.VoterRoot))
+		if len(tx.RingCommitment) > 0 {
+			lines = append(lines, fmt.Sprintf("Ring Commitment: %x", tx.RingCommitment))
+		}
 		lines = append(lines, fmt.Sprintf("Election Keyhash: %x", tx.ElectionPubKey))
 	}
 	return strings.Join(lines, "\n")
@@ -0,0 +1,347 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/musig"
+)
+
+// Kinds of discrepancies reported by an election audit
+const AUDIT_MISSING_ELECTION = "missing_election"
+const AUDIT_MISSING_PREV_TX = "missing_prev_tx"
+const AUDIT_INVALID_SIGNATURE = "invalid_signature"
+const AUDIT_INVALID_RING_SIGNATURE = "invalid_ring_signature"
+const AUDIT_UNKNOWN_SIGNER = "unknown_signer"
+const AUDIT_DOUBLE_SPEND = "double_spend"
+const AUDIT_OUT_OF_PHASE = "out_of_phase"
+const AUDIT_INVALID_CHOICES = "invalid_choices"
+const AUDIT_RESULT_MISMATCH = "result_mismatch"
+const AUDIT_INVALID_CERTIFICATE = "invalid_certificate"
+
+// AuditDiscrepancy is a single problem found while replaying an election
+type AuditDiscrepancy struct {
+	TxID   string `json:"tx_id,omitempty"`
+	TxType string `json:"tx_type,omitempty"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// AuditReport is the machine readable outcome of an independent recount
+type AuditReport struct {
+	ElectionPubKey  []byte                `json:"election_pubkey"`
+	Transactions    int                   `json:"transactions"`
+	ValidBallots    int                   `json:"valid_ballots"`
+	InvalidBallots  int                   `json:"invalid_ballots"`
+	Tally           map[string]RaceResult `json:"tally"`
+	CertifiedResult map[string]RaceResult `json:"certified_result"`
+	Certificates    int                   `json:"certificates,omitempty"`
+	Discrepancies   []AuditDiscrepancy    `json:"discrepancies"`
+}
+
+// Passed reports whether the audit completed without any discrepancy
+func (r *AuditReport) Passed() bool {
+	return len(r.Discrepancies) == 0
+}
+
+func (r *AuditReport) addDiscrepancy(tx *Transaction, kind, detail string) {
+	d := AuditDiscrepancy{Kind: kind, Detail: detail}
+	if tx != nil {
+		d.TxID = hex.EncodeToString(tx.ID)
+		d.TxType = tx.Type
+	}
+	r.Discrepancies = append(r.Discrepancies, d)
+}
+
+// Audit replays every transaction of an election, re-verifies all commission
+// multisigs and ballot ring signatures from scratch and recomputes the tally
+// from the ballots that passed the audit. The recount is compared against the
+// certified result when one is given.
+func (bc *Blockchain) Audit(pubKey []byte, certified map[string]RaceResult) (*AuditReport, error) {
+	report := &AuditReport{
+		ElectionPubKey: pubKey,
+		Discrepancies:  []AuditDiscrepancy{},
+	}
+
+	txs, err := bc.GetTransactionsByPubkey(pubKey)
+	if err != nil {
+		return report, err
+	}
+	// Transactions are collected from the last block, replay them in the
+	// order they were added to the blockchain
+	for i, j := 0, len(txs)-1; i < j; i, j = i+1, j-1 {
+		txs[i], txs[j] = txs[j], txs[i]
+	}
+	report.Transactions = len(txs)
+
+	var election TxElectionOutput
+	var ballots []TxBallotInput
+	var accreditationOpen, accreditationClosed bool
+	var votingOpen, votingClosed bool
+	spent := make(map[string]string)
+	keyImages := make(map[string]string)
+
+	for i := range txs {
+		tx := &txs[i]
+
+		if tx.Type == ELECTION_TX_TYPE && tx.Output.ElectionTx.IsSet() {
+			election = tx.Output.ElectionTx
+		}
+		if election.IsSet() == false {
+			report.addDiscrepancy(tx, AUDIT_MISSING_ELECTION, "transaction precedes the election output")
+			continue
+		}
+
+		var prevTx Transaction
+		if tx.inputSet() {
+			prevTx, err = bc.GetPrevTransactionByInput(tx)
+		} else if tx.Output.ElectionTx.IsSet() == false {
+			prevTx, err = bc.GetPrevTransactionByOutput(tx)
+		}
+		if err != nil {
+			report.addDiscrepancy(tx, AUDIT_MISSING_PREV_TX, err.Error())
+			if tx.Input.BallotTx.IsSet() {
+				report.InvalidBallots++
+			}
+			continue
+		}
+
+		if tx.inputSet() {
+			txOut := hex.EncodeToString(tx.inputTxOut())
+			if by, ok := spent[txOut]; ok {
+				report.addDiscrepancy(tx, AUDIT_DOUBLE_SPEND, fmt.Sprintf("output %s already spent by %s", txOut, by))
+				if tx.Input.BallotTx.IsSet() {
+					report.InvalidBallots++
+				}
+				continue
+			}
+			spent[txOut] = hex.EncodeToString(tx.ID)
+		}
+
+		switch {
+		case tx.Output.AccreditationTx.IsSet():
+			accreditationOpen = true
+		case tx.Input.AccreditationTx.IsSet():
+			accreditationClosed = true
+		case tx.Output.VotingTx.IsSet():
+			if accreditationClosed == false {
+				report.addDiscrepancy(tx, AUDIT_OUT_OF_PHASE, "voting started before accreditation stopped")
+			}
+			votingOpen = true
+		case tx.Input.VotingTx.IsSet():
+			votingClosed = true
+		case tx.Output.BallotTx.IsSet():
+			if accreditationOpen == false || votingClosed {
+				report.addDiscrepancy(tx, AUDIT_OUT_OF_PHASE, "ballot issued outside of the accreditation and voting phases")
+			}
+		}
+
+		if tx.Input.BallotTx.IsSet() {
+			ballot := tx.Input.BallotTx
+			valid := true
+			if tx.Verify(prevTx) == false {
+				if ballot.IsBlind() {
+					report.addDiscrepancy(tx, AUDIT_INVALID_RING_SIGNATURE, "token signature does not verify")
+				} else {
+					report.addDiscrepancy(tx, AUDIT_INVALID_RING_SIGNATURE, "ring signature does not verify")
+				}
+				valid = false
+			}
+			if ballot.IsBlind() == false {
+				if image, err := ballotKeyImage(ballot); err == nil {
+					if by, ok := keyImages[string(image)]; ok {
+						report.addDiscrepancy(tx, AUDIT_DOUBLE_SPEND, fmt.Sprintf("key image %x already used by %s", image, by))
+						valid = false
+					} else {
+						keyImages[string(image)] = hex.EncodeToString(tx.ID)
+					}
+				}
+			}
+			if votingOpen == false || votingClosed {
+				report.addDiscrepancy(tx, AUDIT_OUT_OF_PHASE, "ballot cast outside of the voting phase")
+				valid = false
+			}
+			if err := election.ValidateBallot(ballot); err != nil {
+				report.addDiscrepancy(tx, AUDIT_INVALID_CHOICES, err.Error())
+				valid = false
+			}
+			if valid {
+				ballots = append(ballots, ballot)
+				report.ValidBallots++
+			} else {
+				report.InvalidBallots++
+			}
+			continue
+		}
+
+		pubKeys, sigs, data := tx.commissionSignatures(prevTx)
+		if len(pubKeys) > 0 && len(sigs) == 1 && musig.IsAggregate(sigs[0]) {
+			for j := range pubKeys {
+				if isSigner(election, pubKeys[j]) == false {
+					report.addDiscrepancy(tx, AUDIT_UNKNOWN_SIGNER, fmt.Sprintf("signer %x is not part of the commission", pubKeys[j]))
+				}
+			}
+			if verified, _ := musig.Verify(pubKeys, data, sigs[0]); verified == false {
+				report.addDiscrepancy(tx, AUDIT_INVALID_SIGNATURE, fmt.Sprintf("aggregate signature of %d signers does not verify", len(pubKeys)))
+			}
+			continue
+		}
+		if len(pubKeys) == 0 || len(pubKeys) != len(sigs) {
+			report.addDiscrepancy(tx, AUDIT_INVALID_SIGNATURE,
+				fmt.Sprintf("%d signers for %d signature witnesses", len(pubKeys), len(sigs)))
+			continue
+		}
+		for j := range pubKeys {
+			if isSigner(election, pubKeys[j]) == false {
+				report.addDiscrepancy(tx, AUDIT_UNKNOWN_SIGNER, fmt.Sprintf("signer %x is not part of the commission", pubKeys[j]))
+			}
+			ms := multisig.MultiSig{
+				PubKeys: [][]byte{pubKeys[j]},
+				Sigs:    [][]byte{sigs[j]},
+			}
+			if verified, _ := ms.Verify(data); verified == false {
+				report.addDiscrepancy(tx, AUDIT_INVALID_SIGNATURE, fmt.Sprintf("signature of signer %x does not verify", pubKeys[j]))
+			}
+		}
+	}
+
+	if election.IsSet() == false {
+		report.addDiscrepancy(nil, AUDIT_MISSING_ELECTION, "no election output found")
+		return report, nil
+	}
+
+	report.Tally = tallyBallots(election, ballots)
+	if certified == nil {
+		return report, nil
+	}
+	report.CertifiedResult = certified
+
+	for raceID, race := range report.Tally {
+		certifiedRace, ok := certified[raceID]
+		if !ok {
+			report.addDiscrepancy(nil, AUDIT_RESULT_MISMATCH, fmt.Sprintf("race %s: missing from certified result", raceID))
+			continue
+		}
+		if race.Outcome != certifiedRace.Outcome {
+			report.addDiscrepancy(nil, AUDIT_RESULT_MISMATCH,
+				fmt.Sprintf("race %s: recount %s, certified %s", raceID, race.Outcome, certifiedRace.Outcome))
+		}
+		for candidate, count := range race.Tally {
+			if certifiedRace.Tally[candidate] != count {
+				report.addDiscrepancy(nil, AUDIT_RESULT_MISMATCH,
+					fmt.Sprintf("race %s candidate %s: recount %d, certified %d", raceID, candidate, count, certifiedRace.Tally[candidate]))
+			}
+		}
+		for candidate, count := range certifiedRace.Tally {
+			if _, ok := race.Tally[candidate]; !ok {
+				report.addDiscrepancy(nil, AUDIT_RESULT_MISMATCH,
+					fmt.Sprintf("race %s candidate %s: not in race, certified %d", raceID, candidate, count))
+			}
+		}
+	}
+	for raceID := range certified {
+		if _, ok := report.Tally[raceID]; !ok {
+			report.addDiscrepancy(nil, AUDIT_RESULT_MISMATCH, fmt.Sprintf("race %s: not in election", raceID))
+		}
+	}
+
+	return report, nil
+}
+
+// AuditCertificates checks the voter certificates published by the commission,
+// in the order of the accredited voters, against the serials and hashes the
+// stopped accreditation committed on-chain. Certificates revoked after the
+// accreditation do not count against it. Problems are added to the report.
+func (bc *Blockchain) AuditCertificates(report *AuditReport, certificates [][]byte) error {
+	txs, err := bc.GetTransactionsByPubkey(report.ElectionPubKey)
+	if err != nil {
+		return err
+	}
+	for i, j := 0, len(txs)-1; i < j; i, j = i+1, j-1 {
+		txs[i], txs[j] = txs[j], txs[i]
+	}
+
+	var election TxElectionOutput
+	revoked := make(map[string]bool)
+	for i := range txs {
+		tx := &txs[i]
+		if tx.Output.ElectionTx.IsSet() {
+			election = tx.Output.ElectionTx
+		}
+		for _, serial := range tx.Output.RevocationTx.Serials {
+			revoked[fmt.Sprintf("%x", serial)] = true
+		}
+		acIn := tx.Input.AccreditationTx
+		if acIn.IsSet() == false {
+			continue
+		}
+
+		report.Certificates = len(certificates)
+		if err := verifyVoterCertificates(election, acIn, certificates, revoked); err != nil {
+			report.addDiscrepancy(tx, AUDIT_INVALID_CERTIFICATE, err.Error())
+		}
+		return nil
+	}
+	report.addDiscrepancy(nil, AUDIT_INVALID_CERTIFICATE, ErrNoVoterRings.Error())
+	return nil
+}
+
+// inputTxOut returns the ID of the transaction output spent by the input, or
+// the token spent by a blind ballot
+func (tx *Transaction) inputTxOut() []byte {
+	switch tx.Type {
+	case ELECTION_TX_TYPE:
+		return tx.Input.ElectionTx.TxOut
+	case ACCREDITATION_TX_TYPE:
+		return tx.Input.AccreditationTx.TxOut
+	case VOTING_TX_TYPE:
+		return tx.Input.VotingTx.TxOut
+	case BALLOT_TX_TYPE:
+		if tx.Input.BallotTx.IsBlind() {
+			return tx.Input.BallotTx.Token
+		}
+		return tx.Input.BallotTx.TxOut
+	}
+	return nil
+}
+
+// commissionSignatures returns the signers, signature witnesses and signed
+// data of a transaction signed by the election commission
+func (tx *Transaction) commissionSignatures(prevTx Transaction) (pubKeys, sigs [][]byte, data []byte) {
+	switch {
+	case tx.Output.ElectionTx.IsSet():
+		out := tx.Output.ElectionTx
+		return out.Signers, out.SigWitnesses, out.ToByte()
+	case tx.Input.ElectionTx.IsSet():
+		in := tx.Input.ElectionTx
+		return prevTx.Output.ElectionTx.Signers, in.SigWitnesses, in.ToByte()
+	case tx.Output.AccreditationTx.IsSet():
+		out := tx.Output.AccreditationTx
+		return out.Signers, out.SigWitnesses, out.ToByte()
+	case tx.Input.AccreditationTx.IsSet():
+		txCopy := tx.Input.AccreditationTx.TrimmedCopy()
+		txCopy.ElectionPubKey = prevTx.ElectionPubkey
+		return prevTx.Output.AccreditationTx.Signers, tx.Input.AccreditationTx.SigWitnesses, txCopy.ToByte()
+	case tx.Output.VotingTx.IsSet():
+		out := tx.Output.VotingTx
+		return out.Signers, out.SigWitnesses, out.ToByte()
+	case tx.Input.VotingTx.IsSet():
+		txCopy := tx.Input.VotingTx.TrimmedCopy()
+		txCopy.ElectionPubKey = prevTx.Output.VotingTx.ElectionPubKey
+		return prevTx.Output.VotingTx.Signers, tx.Input.VotingTx.SigWitnesses, txCopy.ToByte()
+	case tx.Output.BallotTx.IsSet():
+		out := tx.Output.BallotTx
+		return out.Signers, out.SigWitnesses, out.ToByte()
+	case tx.Output.RevocationTx.IsSet():
+		out := tx.Output.RevocationTx
+		return out.Signers, out.SigWitnesses, out.ToByte()
+	}
+	return
+}
+
+// isSigner checks if the public key belongs to the commission that signed
+// the election output
+func isSigner(election TxElectionOutput, pubKey []byte) bool {
+	return isValidator(election.Signers, pubKey)
+}
@@ -0,0 +1,338 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func countKinds(report *AuditReport) map[string]int {
+	kinds := make(map[string]int)
+	for _, d := range report.Discrepancies {
+		kinds[d.Kind]++
+	}
+	return kinds
+}
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(e *testElection)
+		valid   int
+		invalid int
+		tally   []int // Votes of each candidate
+		kinds   map[string]int
+	}{
+		{
+			name: "clean election",
+			run: func(e *testElection) {
+				e.startAccreditation()
+				e.stopAccreditation()
+				e.startVoting()
+				e.vote(0, e.candidates[0])
+				e.vote(1, e.candidates[1])
+				e.vote(2, e.candidates[0])
+				e.stopVoting()
+			},
+			valid: 3,
+			tally: []int{2, 1, 0},
+			kinds: map[string]int{},
+		},
+		{
+			name: "ballot cast after voting stopped",
+			run: func(e *testElection) {
+				e.startAccreditation()
+				e.stopAccreditation()
+				e.startVoting()
+				e.vote(0, e.candidates[0])
+				out := e.ballotOutputTx(e.ring(1))
+				e.mustAdd(out)
+				e.stopVoting()
+				e.forceAdd(e.ballotInputTx(1, out, func(in *TxBallotInput) {
+					in.Candidate = e.candidates[1]
+				}))
+			},
+			valid:   1,
+			invalid: 1,
+			tally:   []int{1, 0, 0},
+			kinds:   map[string]int{AUDIT_OUT_OF_PHASE: 1},
+		},
+		{
+			name: "voting started before accreditation stopped",
+			run: func(e *testElection) {
+				e.startAccreditation()
+				e.startVoting()
+				e.forceAdd(e.stopAccreditationTx(nil))
+				e.vote(0, e.candidates[2])
+			},
+			valid: 1,
+			tally: []int{0, 0, 1},
+			kinds: map[string]int{AUDIT_OUT_OF_PHASE: 1},
+		},
+		{
+			name: "ballot issued before accreditation started",
+			run: func(e *testElection) {
+				e.forceAdd(e.ballotOutputTx(e.voterKeys))
+				e.startAccreditation()
+				e.stopAccreditation()
+			},
+			tally: []int{0, 0, 0},
+			kinds: map[string]int{AUDIT_OUT_OF_PHASE: 1},
+		},
+		{
+			name: "voter casting two ballots of the ring",
+			run: func(e *testElection) {
+				e.startAccreditation()
+				e.stopAccreditation()
+				e.startVoting()
+				e.vote(0, e.candidates[0])
+				out := e.ballotOutputTx(e.ring(0))
+				e.mustAdd(out)
+				e.forceAdd(e.ballotInputTx(0, out, nil))
+			},
+			valid:   1,
+			invalid: 1,
+			tally:   []int{1, 0, 0},
+			kinds:   map[string]int{AUDIT_DOUBLE_SPEND: 1},
+		},
+		{
+			name: "invalid ring signature",
+			run: func(e *testElection) {
+				e.startAccreditation()
+				e.stopAccreditation()
+				e.startVoting()
+				out := e.ballotOutputTx(e.ring(0))
+				e.mustAdd(out)
+				in := e.ballotInputTx(0, out, nil)
+				in.Input.BallotTx.Candidate = e.candidates[1]
+				e.forceAdd(in)
+			},
+			invalid: 1,
+			tally:   []int{0, 0, 0},
+			kinds:   map[string]int{AUDIT_INVALID_RING_SIGNATURE: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestElection(t, 3, nil)
+			test.run(e)
+
+			report, err := e.bc.Audit(e.pubKey, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.ValidBallots != test.valid || report.InvalidBallots != test.invalid {
+				t.Fatalf("expected %d valid and %d invalid ballots, got %d and %d",
+					test.valid, test.invalid, report.ValidBallots, report.InvalidBallots)
+			}
+			kinds := countKinds(report)
+			if len(kinds) != len(test.kinds) {
+				t.Fatalf("expected discrepancies %v, got %+v", test.kinds, report.Discrepancies)
+			}
+			for kind, count := range test.kinds {
+				if kinds[kind] != count {
+					t.Fatalf("expected discrepancies %v, got %+v", test.kinds, report.Discrepancies)
+				}
+			}
+			// The recount only holds the ballots that passed the audit
+			tally := report.Tally[DEFAULT_RACE_ID].Tally
+			for i, votes := range test.tally {
+				if got := tally[hex.EncodeToString(e.candidates[i])]; got != votes {
+					t.Fatalf("candidate %d: expected %d votes, got %d", i, votes, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditCertifiedResult(t *testing.T) {
+	e := newTestElection(t, 3, nil)
+	e.startAccreditation()
+	e.stopAccreditation()
+	e.startVoting()
+	e.vote(0, e.candidates[0])
+	e.vote(1, e.candidates[1])
+
+	certified, err := e.bc.QueryResult(e.pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := e.bc.Audit(e.pubKey, certified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed() == false {
+		t.Fatalf("unexpected discrepancies: %+v", report.Discrepancies)
+	}
+
+	race := certified[DEFAULT_RACE_ID]
+	race.Tally[hex.EncodeToString(e.candidates[1])]++
+	report, err = e.bc.Audit(e.pubKey, certified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kinds := countKinds(report); kinds[AUDIT_RESULT_MISMATCH] != 1 || len(kinds) != 1 {
+		t.Fatalf("expected a result mismatch, got %+v", report.Discrepancies)
+	}
+}
+
+func TestAuditCertificates(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(e *certifiedElection, in *TxAcInput)
+		run   func(e *certifiedElection) [][]byte
+		found int
+	}{
+		{"published certificates", nil, nil, 0},
+		{
+			name: "certificate revoked after accreditation",
+			run: func(e *certifiedElection) [][]byte {
+				e.revoke(e.serials[0])
+				return e.certificates
+			},
+		},
+		{
+			name: "hash of a certificate that was not published",
+			setup: func(e *certifiedElection, in *TxAcInput) {
+				in.CertificateHashes = [][]byte{e.hashes[0], e.hash(e.issue(&e.voters[1].PublicKey, "V-0001")), e.hashes[2]}
+			},
+			found: 1,
+		},
+		{
+			name:  "missing certificate",
+			run:   func(e *certifiedElection) [][]byte { return e.certificates[:2] },
+			found: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newCertifiedElection(t, 3)
+			e.mustAdd(e.stopAccreditationTx(func(in *TxAcInput) {
+				e.certify(in)
+				if test.setup != nil {
+					test.setup(e, in)
+				}
+			}))
+			certificates := e.certificates
+			if test.run != nil {
+				certificates = test.run(e)
+			}
+
+			report, err := e.bc.Audit(e.pubKey, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = e.bc.AuditCertificates(report, certificates); err != nil {
+				t.Fatal(err)
+			}
+			if report.Certificates != len(certificates) {
+				t.Fatalf("expected %d certificates checked, got %d", len(certificates), report.Certificates)
+			}
+			if kinds := countKinds(report); kinds[AUDIT_INVALID_CERTIFICATE] != test.found || len(report.Discrepancies) != test.found {
+				t.Fatalf("expected %d invalid certificates, got %+v", test.found, report.Discrepancies)
+			}
+		})
+	}
+}
+
+func TestAuditMissingElection(t *testing.T) {
+	bc := newTestChain(t)
+	report, err := bc.Audit([]byte("unknown"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kinds := countKinds(report); kinds[AUDIT_MISSING_ELECTION] != 1 {
+		t.Fatalf("expected a missing election, got %+v", report.Discrepancies)
+	}
+}
+
+func TestAuditBlindBallots(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(e *blindElection)
+		valid   int
+		invalid int
+		tally   []int // Votes of each candidate
+		kinds   map[string]int
+	}{
+		{
+			name: "clean election",
+			run: func(e *blindElection) {
+				e.mustAdd(e.castTx(e.issue(0), e.candidates[0]))
+				e.mustAdd(e.castTx(e.issue(1), e.candidates[1]))
+				e.stopVoting()
+			},
+			valid: 2,
+			tally: []int{1, 1, 0},
+			kinds: map[string]int{},
+		},
+		{
+			name: "token not signed by the commission",
+			run: func(e *blindElection) {
+				e.issue(0)
+				token := e.newToken()
+				token.signature = e.newToken().signature
+				e.forceAdd(e.castTx(token, e.candidates[0]))
+			},
+			invalid: 1,
+			tally:   []int{0, 0, 0},
+			kinds:   map[string]int{AUDIT_INVALID_RING_SIGNATURE: 1},
+		},
+		{
+			name: "token used twice",
+			run: func(e *blindElection) {
+				token := e.issue(0)
+				e.issue(1)
+				e.mustAdd(e.castTx(token, e.candidates[0]))
+				e.forceAdd(e.castTx(token, e.candidates[1]))
+			},
+			valid:   1,
+			invalid: 1,
+			tally:   []int{1, 0, 0},
+			kinds:   map[string]int{AUDIT_DOUBLE_SPEND: 1},
+		},
+		{
+			name: "ballot cast after voting stopped",
+			run: func(e *blindElection) {
+				token := e.issue(0)
+				e.stopVoting()
+				e.forceAdd(e.castTx(token, e.candidates[2]))
+			},
+			invalid: 1,
+			tally:   []int{0, 0, 0},
+			kinds:   map[string]int{AUDIT_OUT_OF_PHASE: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newBlindElection(t, 3)
+			e.open()
+			test.run(e)
+
+			report, err := e.bc.Audit(e.pubKey, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.ValidBallots != test.valid || report.InvalidBallots != test.invalid {
+				t.Fatalf("expected %d valid and %d invalid ballots, got %d and %d",
+					test.valid, test.invalid, report.ValidBallots, report.InvalidBallots)
+			}
+			kinds := countKinds(report)
+			if len(kinds) != len(test.kinds) {
+				t.Fatalf("expected discrepancies %v, got %+v", test.kinds, report.Discrepancies)
+			}
+			for kind, count := range test.kinds {
+				if kinds[kind] != count {
+					t.Fatalf("expected discrepancies %v, got %+v", test.kinds, report.Discrepancies)
+				}
+			}
+			tally := report.Tally[DEFAULT_RACE_ID].Tally
+			for i, votes := range test.tally {
+				if got := tally[hex.EncodeToString(e.candidates[i])]; got != votes {
+					t.Fatalf("candidate %d: expected %d votes, got %d", i, votes, got)
+				}
+			}
+		})
+	}
+}
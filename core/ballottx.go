@@ -2,7 +2,6 @@ package blockchain
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"fmt"
 	"reflect"
 	"strings"
@@ -14,25 +13,32 @@ import (
 
 // Vote TxTxOutput
 type TxBallotOutput struct {
-	ID             string   `json:"id"`
-	TxID           []byte   `json:"tx_id"`
-	Signers        [][]byte `json:"signers"` // SIGNATURE BY CONSENSUS GROUP
-	SigWitnesses   [][]byte `json:"sig_witnesses"`
-	SecretMessage  []byte   `json:"secret_message"` // Signed with Public view key (Decrypted with private view key) 🔑
-	PubKeys        [][]byte `json:"pub_keys"`
-	ElectionPubKey []byte   `json:"election_pubKey"`
-	Timestamp      int64    `json:"timestamp"`
+	ID             string        `json:"id"`
+	TxID           []byte        `json:"tx_id"`
+	Signers        [][]byte      `json:"signers"` // SIGNATURE BY CONSENSUS GROUP
+	SigWitnesses   [][]byte      `json:"sig_witnesses"`
+	SecretMessage  []byte        `json:"secret_message"` // Encrypted with Public view key (Decrypted with private view key) 🔑
+	PubKeys        [][]byte      `json:"pub_keys"`
+	ElectionPubKey []byte        `json:"election_pubKey"`
+	Timestamp      int64         `json:"timestamp"`
+	VoterProofs    []MerkleProof `json:"voter_proofs"`    // Voter roll membership proof of each key in PubKeys
+	BlindedToken   []byte        `json:"blinded_token"`   // Token of the voter blinded with the election blind key
+	BlindSignature []byte        `json:"blind_signature"` // Signature of the blinded token by the commission
 }
 
 // Vote TxInput
 type TxBallotInput struct {
-	TxID           []byte   `json:"tx_id"`
-	Signature      []byte   `json:"signature"`
-	PubKeys        [][]byte `json:"pub_keys"`
-	TxOut          []byte   `json:"tx_out"`
-	Candidate      []byte   `json:"candidate"`
-	ElectionPubKey []byte   `json:"election_pubkey"`
-	Timestamp      int64    `json:"timestamp"`
+	TxID           []byte          `json:"tx_id"`
+	Signature      []byte          `json:"signature"`
+	PubKeys        [][]byte        `json:"pub_keys"`
+	TxOut          []byte          `json:"tx_out"`
+	Candidate      []byte          `json:"candidate"`
+	ElectionPubKey []byte          `json:"election_pubkey"`
+	Timestamp      int64           `json:"timestamp"`
+	Choices        [][]byte        `json:"choices"`         // Selected candidates, in order of preference for ranked ballots
+	Races          []RaceSelection `json:"races"`           // Choices for each race of a multi-race election
+	Token          []byte          `json:"token"`           // One-time public key of a blind ballot, signs the ballot
+	TokenSignature []byte          `json:"token_signature"` // Unblinded commission signature of the token
 }
 
 // NewTxBallotInput CASTS Vote using secret ballot
@@ -73,6 +79,12 @@ func (TxOut *TxBallotOutput) IsLockWithKey(ElectionPubKey []byte) bool {
 	return bytes.Compare(TxOut.ElectionPubKey, ElectionPubKey) == 0
 }
 
+// IsBlind reports whether the output records the blind signature of a token
+// issued to a single voter
+func (tx *TxBallotOutput) IsBlind() bool {
+	return len(tx.BlindedToken) > 0
+}
+
 func (tx *TxBallotOutput) IsSet() bool {
 	return reflect.DeepEqual(tx, &TxBallotOutput{}) == false
 }
@@ -88,18 +100,33 @@ func (tx *TxBallotOutput) TrimmedCopy() TxBallotOutput {
 		nil,
 		tx.ElectionPubKey,
 		tx.Timestamp,
+		nil,
+		tx.BlindedToken,
+		tx.BlindSignature,
 	}
 	return txCopy
 }
 
 // Convert Election output to Byte for verification and signing purposes
 func (tx *TxBallotOutput) ToByte() []byte {
-	var hash [32]byte
-
-	txCopy := tx.TrimmedCopy()
+	return signingHash(
+		txBallotOutputV1{"", tx.TxID, nil, nil, tx.SecretMessage, nil, tx.ElectionPubKey, tx.Timestamp},
+		payloadField{"blinded_token", tx.BlindedToken},
+		payloadField{"blind_signature", tx.BlindSignature},
+	)
+}
 
-	hash = sha256.Sum256([]byte(fmt.Sprintf("%x", txCopy)))
-	return hash[:]
+// txBallotOutputV1 is the layout of the first version of ballot outputs, the
+// signing payload starts with it
+type txBallotOutputV1 struct {
+	ID             string
+	TxID           []byte
+	Signers        [][]byte
+	SigWitnesses   [][]byte
+	SecretMessage  []byte
+	PubKeys        [][]byte
+	ElectionPubKey []byte
+	Timestamp      int64
 }
 
 // Trim election input data
@@ -112,18 +139,53 @@ func (tx *TxBallotInput) TrimmedCopy() TxBallotInput {
 		tx.Candidate,
 		tx.ElectionPubKey,
 		tx.Timestamp,
+		tx.Choices,
+		tx.Races,
+		tx.Token,
+		tx.TokenSignature,
 	}
 	return txCopy
 }
 
 // Convert Election output to Byte for verification and signing purposes
 func (tx *TxBallotInput) ToByte() []byte {
-	var hash [32]byte
+	return signingHash(
+		txBallotInputV1{tx.TxID, nil, nil, tx.TxOut, tx.Candidate, tx.ElectionPubKey, tx.Timestamp},
+		payloadField{"choices", tx.Choices},
+		payloadField{"races", tx.Races},
+		payloadField{"token", tx.Token},
+		payloadField{"token_signature", tx.TokenSignature},
+	)
+}
 
-	txCopy := tx.TrimmedCopy()
+// txBallotInputV1 is the layout of the first version of ballot inputs, the
+// signing payload starts with it
+type txBallotInputV1 struct {
+	TxID           []byte
+	Signature      []byte
+	PubKeys        [][]byte
+	TxOut          []byte
+	Candidate      []byte
+	ElectionPubKey []byte
+	Timestamp      int64
+}
 
-	hash = sha256.Sum256([]byte(fmt.Sprintf("%x", txCopy)))
-	return hash[:]
+// Selections returns the candidates picked on the ballot, a plurality ballot
+// may only set the candidate
+func (tx *TxBallotInput) Selections() [][]byte {
+	if len(tx.Choices) > 0 {
+		return tx.Choices
+	}
+	if len(tx.Candidate) > 0 {
+		return [][]byte{tx.Candidate}
+	}
+	return nil
+}
+
+// IsBlind reports whether the ballot is cast with a blind-signed token instead
+// of spending a ballot output with a ring signature
+func (tx *TxBallotInput) IsBlind() bool {
+	return len(tx.Token) > 0
 }
 
 func (tx *TxBallotInput) IsSet() bool {
@@ -139,7 +201,16 @@ func (tx *TxBallotInput) String() string {
 	if tx.IsSet() {
 		lines = append(lines, fmt.Sprintf("Timestamp: %d", tx.Timestamp))
 		lines = append(lines, fmt.Sprintf("Candidate: %x", tx.Candidate))
+		for i := 0; i < len(tx.Choices); i++ {
+			lines = append(lines, fmt.Sprintf("(Choice) \n --(%d): %x", i, tx.Choices[i]))
+		}
+		for i := 0; i < len(tx.Races); i++ {
+			lines = append(lines, fmt.Sprintf("(Race %s) \n --%x", tx.Races[i].RaceID, tx.Races[i].Choices))
+		}
 		lines = append(lines, fmt.Sprintf("Signature: %x", tx.Signature))
+		if tx.IsBlind() {
+			lines = append(lines, fmt.Sprintf("(Token): %x", tx.Token))
+		}
 		lines = append(lines, fmt.Sprintf("(Election pubKey): %x", tx.ElectionPubKey))
 	}
 	return strings.Join(lines, "\n")
@@ -160,6 +231,9 @@ func (tx *TxBallotOutput) String() string {
 			lines = append(lines, fmt.Sprintf("(Signature Witness): \n --(%d): %x", i, tx.SigWitnesses[i]))
 		}
 		lines = append(lines, fmt.Sprintf("(Secret Message): %x", tx.SecretMessage))
+		if tx.IsBlind() {
+			lines = append(lines, fmt.Sprintf("(Blinded Token): %x", tx.BlindedToken))
+		}
 		lines = append(lines, fmt.Sprintf("(Election pubKey): %s", tx.ElectionPubKey))
 	}
 	return strings.Join(lines, "\n")
@@ -0,0 +1,171 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/blindsig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+)
+
+var (
+	ErrNoBlindKey         = errors.New("Election has no blind signature key")
+	ErrBlindTokenRequired = errors.New("Ballots of the election require a blind-signed token")
+	ErrInvalidBlindToken  = errors.New("Invalid blind-signed token")
+	ErrTokenIssued        = errors.New("A token was already issued to the voter")
+	ErrTokenUsed          = errors.New("Token was already used to cast a ballot")
+)
+
+// verifyBlindBallot checks that the token of the ballot is signed with the
+// election blind key and that the ballot is signed with the token
+func verifyBlindBallot(ballotIn TxBallotInput, election TxElectionOutput) bool {
+	if len(election.BlindKey) == 0 {
+		return false
+	}
+	pub, err := blindsig.ParsePublicKey(election.BlindKey)
+	if err != nil {
+		logger.Error(err)
+		return false
+	}
+	if blindsig.Verify(pub, ballotIn.Token, ballotIn.TokenSignature) == false {
+		logger.Errorf("%s: token %x is not signed by the commission", ErrInvalidBlindToken, ballotIn.Token)
+		return false
+	}
+
+	ms := multisig.MultiSig{
+		PubKeys: [][]byte{ballotIn.Token},
+		Sigs:    [][]byte{ballotIn.Signature},
+	}
+	txCopy := ballotIn.TrimmedCopy()
+	txCopy.ElectionPubKey = election.ElectionPubKey
+	verified, err := ms.Verify(txCopy.ToByte())
+	if err != nil {
+		logger.Error(err)
+	}
+	return verified
+}
+
+// validateBlindTokens checks the ballots of elections with a blind key. Every
+// ballot output records the blind signature of the token of one voter
+// published by the stopped accreditation, issued once per voter, and every
+// ballot is cast with a token used once instead of spending a ballot output.
+// Elections without a blind key must not carry tokens.
+func (bc *Blockchain) validateBlindTokens(tx *Transaction) error {
+	ballotOut := tx.Output.BallotTx
+	ballotIn := tx.Input.BallotTx
+	if ballotOut.IsSet() == false && ballotIn.IsSet() == false {
+		return nil
+	}
+
+	txElection, err := bc.FindTxWithElectionOutByPubkey(tx.ElectionPubkey)
+	if err != nil {
+		return err
+	}
+	election := txElection.Output.ElectionTx
+	if election.IsSet() == false || bytes.Compare(election.ElectionPubKey, tx.ElectionPubkey) != 0 {
+		return ErrNoBlindKey
+	}
+	if len(election.BlindKey) == 0 {
+		if ballotOut.IsBlind() || ballotIn.IsBlind() {
+			return ErrNoBlindKey
+		}
+		return nil
+	}
+	if (ballotOut.IsSet() && ballotOut.IsBlind() == false) || (ballotIn.IsSet() && ballotIn.IsBlind() == false) {
+		return ErrBlindTokenRequired
+	}
+
+	txs, err := bc.GetTransactionsByPubkey(tx.ElectionPubkey)
+	if err != nil {
+		return err
+	}
+
+	if ballotIn.IsSet() {
+		// Spending an issued output would link the ballot to its voter
+		if len(ballotIn.TxOut) > 0 {
+			return fmt.Errorf("%w: blind ballots do not spend a ballot output", ErrInvalidBlindToken)
+		}
+		if _, err := keys.Parse(ballotIn.Token); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidBlindToken, err)
+		}
+		for _, prev := range txs {
+			if prev.Input.BallotTx.IsBlind() && bytes.Compare(prev.Input.BallotTx.Token, ballotIn.Token) == 0 {
+				return fmt.Errorf("%w: %x", ErrTokenUsed, ballotIn.Token)
+			}
+		}
+
+		stats, err := bc.GetElectionStats(tx.ElectionPubkey)
+		if err != nil {
+			return err
+		}
+		if stats.CastBallots+1 > stats.IssuedBallots {
+			return fmt.Errorf("%w: %d ballots cast for %d tokens issued", ErrTooManyBallots, stats.CastBallots, stats.IssuedBallots)
+		}
+		return nil
+	}
+
+	if len(ballotOut.PubKeys) != 1 {
+		return fmt.Errorf("%w: token issued to %d voters", ErrInvalidBlindToken, len(ballotOut.PubKeys))
+	}
+	voter := ballotOut.PubKeys[0]
+	pub, err := blindsig.ParsePublicKey(election.BlindKey)
+	if err != nil {
+		return err
+	}
+	if blindsig.VerifyBlinded(pub, ballotOut.BlindedToken, ballotOut.BlindSignature) == false {
+		return fmt.Errorf("%w: blind signature does not verify", ErrInvalidBlindToken)
+	}
+
+	// Tokens are issued to the voters published by the accreditation
+	var accredited [][]byte
+	for _, prev := range txs {
+		if acIn := prev.Input.AccreditationTx; acIn.IsSet() && len(acIn.Voters) > 0 {
+			accredited = acIn.Voters
+		}
+		prevOut := prev.Output.BallotTx
+		if prevOut.IsBlind() && len(prevOut.PubKeys) == 1 && keys.Equal(prevOut.PubKeys[0], voter) {
+			return fmt.Errorf("%w: %x", ErrTokenIssued, voter)
+		}
+	}
+	if accredited == nil {
+		return fmt.Errorf("%w: tokens are issued once accreditation is stopped", ErrNoVoterRings)
+	}
+	if isValidator(accredited, voter) == false {
+		return fmt.Errorf("%w: voter %x is not accredited", ErrInvalidBlindToken, voter)
+	}
+	return nil
+}
+
+// validateBlockTokens checks the blind ballots of a block against each other,
+// a token is used once and a voter is issued one token across the block. The
+// ballots already on the chain are checked by validateBlindTokens.
+func validateBlockTokens(txs []*Transaction) error {
+	type electionKey struct {
+		election string
+		key      string
+	}
+	used := make(map[electionKey]bool)
+	issued := make(map[electionKey]bool)
+	for _, tx := range txs {
+		if ballotIn := tx.Input.BallotTx; ballotIn.IsBlind() {
+			token := electionKey{string(tx.ElectionPubkey), string(ballotIn.Token)}
+			if used[token] {
+				return fmt.Errorf("transaction %x: %w: %x", tx.ID, ErrTokenUsed, ballotIn.Token)
+			}
+			used[token] = true
+		}
+		if ballotOut := tx.Output.BallotTx; ballotOut.IsBlind() {
+			for _, voter := range ballotOut.PubKeys {
+				id := electionKey{string(tx.ElectionPubkey), keyID(voter)}
+				if issued[id] {
+					return fmt.Errorf("transaction %x: %w: %x", tx.ID, ErrTokenIssued, voter)
+				}
+				issued[id] = true
+			}
+		}
+	}
+	return nil
+}
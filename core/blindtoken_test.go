@@ -0,0 +1,373 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/blindsig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+var (
+	blindKeyOnce sync.Once
+	blindKey     *rsa.PrivateKey
+	blindKeyErr  error
+)
+
+// testBlindKey returns the blind signature key of the commission, the RSA key
+// is generated once for every test
+func testBlindKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	blindKeyOnce.Do(func() {
+		blindKey, blindKeyErr = blindsig.GenerateKey()
+	})
+	if blindKeyErr != nil {
+		t.Fatal(blindKeyErr)
+	}
+	return blindKey
+}
+
+// blindElection is a test election whose ballots are blind-signed tokens
+type blindElection struct {
+	*testElection
+	blindKey *rsa.PrivateKey
+}
+
+func newBlindElection(t *testing.T, voters int) *blindElection {
+	t.Helper()
+	priv := testBlindKey(t)
+	pub, err := blindsig.MarshalPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &blindElection{
+		testElection: newTestElection(t, voters, func(out *TxElectionOutput) {
+			out.BlindKey = pub
+		}),
+		blindKey: priv,
+	}
+}
+
+// blindToken is the one-time key of a voter and the commission signatures of
+// its token
+type blindToken struct {
+	priv           *ecdsa.PrivateKey
+	token          []byte
+	blinded        []byte
+	blindSignature []byte
+	signature      []byte
+}
+
+// newToken creates a token signed blindly by the commission
+func (e *blindElection) newToken() *blindToken {
+	e.t.Helper()
+	priv, token := wallet.NewKeyPair()
+	blinded, unblinder, err := blindsig.Blind(&e.blindKey.PublicKey, token)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	blindSignature, err := blindsig.Sign(e.blindKey, blinded)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	signature, err := blindsig.Unblind(&e.blindKey.PublicKey, token, blindSignature, unblinder)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return &blindToken{priv, token, blinded, blindSignature, signature}
+}
+
+// issueTx records the blind signature of the token issued to the voter key
+func (e *blindElection) issueTx(voterKey []byte, token *blindToken) *Transaction {
+	out := NewBallotTxOutput(e.pubKey, nil, e.election.ID, [][]byte{voterKey}, nil, nil, time.Now().Unix())
+	out.BallotTx.BlindedToken = token.blinded
+	out.BallotTx.BlindSignature = token.blindSignature
+	out.BallotTx.Signers, out.BallotTx.SigWitnesses = e.sign(out.BallotTx.ToByte())
+	return e.tx(BALLOT_TX_TYPE, TxInput{}, *out)
+}
+
+// issue issues a new token to the voter
+func (e *blindElection) issue(voter int) *blindToken {
+	e.t.Helper()
+	token := e.newToken()
+	e.mustAdd(e.issueTx(e.voterKeys[voter], token))
+	return token
+}
+
+// castTx casts a ballot for the candidate signed with the token
+func (e *blindElection) castTx(token *blindToken, candidate []byte) *Transaction {
+	in := NewBallotTxInput(e.pubKey, candidate, e.election.ID, nil, nil, nil, time.Now().Unix())
+	in.BallotTx.Token = token.token
+	in.BallotTx.TokenSignature = token.signature
+	mu := multisig.NewMultisig(1)
+	mu.AddSignature(in.BallotTx.ToByte(), token.token, *token.priv)
+	in.BallotTx.Signature = mu.Sigs[0]
+	return e.tx(BALLOT_TX_TYPE, *in, TxOutput{})
+}
+
+// open runs the election up to the voting phase
+func (e *blindElection) open() {
+	e.startAccreditation()
+	e.stopAccreditation()
+	e.startVoting()
+}
+
+func TestValidateBlindTokenIssue(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(e *blindElection) *Transaction
+		err  error
+	}{
+		{
+			name: "accredited voter",
+			run: func(e *blindElection) *Transaction {
+				e.open()
+				return e.issueTx(e.voterKeys[0], e.newToken())
+			},
+		},
+		{
+			name: "accredited voter with another key encoding",
+			run: func(e *blindElection) *Transaction {
+				e.open()
+				return e.issueTx(uncompressed(e.t, e.voterKeys[:1])[0], e.newToken())
+			},
+		},
+		{
+			name: "voter issued twice",
+			run: func(e *blindElection) *Transaction {
+				e.open()
+				e.issue(0)
+				return e.issueTx(uncompressed(e.t, e.voterKeys[:1])[0], e.newToken())
+			},
+			err: ErrTokenIssued,
+		},
+		{
+			name: "voter not accredited",
+			run: func(e *blindElection) *Transaction {
+				e.open()
+				_, outsider := wallet.NewKeyPair()
+				return e.issueTx(outsider, e.newToken())
+			},
+			err: ErrInvalidBlindToken,
+		},
+		{
+			name: "accreditation not stopped",
+			run: func(e *blindElection) *Transaction {
+				e.startAccreditation()
+				return e.issueTx(e.voterKeys[0], e.newToken())
+			},
+			err: ErrNoVoterRings,
+		},
+		{
+			name: "blind signature of another key",
+			run: func(e *blindElection) *Transaction {
+				e.open()
+				token := e.newToken()
+				token.blindSignature = e.newToken().blindSignature
+				return e.issueTx(e.voterKeys[0], token)
+			},
+			err: ErrInvalidBlindToken,
+		},
+		{
+			name: "token issued to two voters",
+			run: func(e *blindElection) *Transaction {
+				e.open()
+				tx := e.issueTx(e.voterKeys[0], e.newToken())
+				tx.Output.BallotTx.PubKeys = e.voterKeys[:2]
+				return tx
+			},
+			err: ErrInvalidBlindToken,
+		},
+		{
+			name: "ring ballot",
+			run: func(e *blindElection) *Transaction {
+				e.open()
+				return e.ballotOutputTx(e.ring(0))
+			},
+			err: ErrBlindTokenRequired,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newBlindElection(t, 3)
+			err := e.bc.validateBlindTokens(test.run(e))
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateBlindTokenCast(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(e *blindElection) *Transaction
+		err  error
+	}{
+		{
+			name: "issued token",
+			run: func(e *blindElection) *Transaction {
+				return e.castTx(e.issue(0), e.candidates[0])
+			},
+		},
+		{
+			name: "token used twice",
+			run: func(e *blindElection) *Transaction {
+				token := e.issue(0)
+				e.mustAdd(e.castTx(token, e.candidates[0]))
+				return e.castTx(token, e.candidates[1])
+			},
+			err: ErrTokenUsed,
+		},
+		{
+			name: "more ballots than tokens issued",
+			run: func(e *blindElection) *Transaction {
+				e.mustAdd(e.castTx(e.issue(0), e.candidates[0]))
+				return e.castTx(e.newToken(), e.candidates[1])
+			},
+			err: ErrTooManyBallots,
+		},
+		{
+			name: "ballot spending an output",
+			run: func(e *blindElection) *Transaction {
+				tx := e.castTx(e.issue(0), e.candidates[0])
+				tx.Input.BallotTx.TxOut = e.election.ID
+				return tx
+			},
+			err: ErrInvalidBlindToken,
+		},
+		{
+			name: "ballot without token",
+			run: func(e *blindElection) *Transaction {
+				tx := e.castTx(e.issue(0), e.candidates[0])
+				tx.Input.BallotTx.Token = nil
+				return tx
+			},
+			err: ErrBlindTokenRequired,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newBlindElection(t, 3)
+			e.open()
+			err := e.bc.validateBlindTokens(test.run(e))
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateBlindTokenWithoutBlindKey(t *testing.T) {
+	e := &blindElection{newTestElection(t, 1, nil), testBlindKey(t)}
+	e.open()
+
+	for _, tx := range []*Transaction{e.issueTx(e.voterKeys[0], e.newToken()), e.castTx(e.newToken(), e.candidates[0])} {
+		if err := e.bc.validateBlindTokens(tx); errors.Is(err, ErrNoBlindKey) == false {
+			t.Errorf("expected %v, got %v", ErrNoBlindKey, err)
+		}
+	}
+}
+
+func TestVerifyBlindBallot(t *testing.T) {
+	e := newBlindElection(t, 1)
+	e.open()
+	token := e.issue(0)
+	election := e.election.Output.ElectionTx
+
+	tests := []struct {
+		name  string
+		setup func(in *TxBallotInput)
+		valid bool
+	}{
+		{"signed token", func(in *TxBallotInput) {}, true},
+		{"token not signed by the commission", func(in *TxBallotInput) { in.TokenSignature = e.newToken().signature }, false},
+		{"ballot changed after signing", func(in *TxBallotInput) { in.Candidate = e.candidates[1] }, false},
+		{"ballot of another token", func(in *TxBallotInput) {
+			other := e.newToken()
+			in.Token, in.TokenSignature = other.token, other.signature
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := e.castTx(token, e.candidates[0]).Input.BallotTx
+			test.setup(&in)
+			if verifyBlindBallot(in, election) != test.valid {
+				t.Fatalf("expected valid to be %v", test.valid)
+			}
+		})
+	}
+
+	if verifyBlindBallot(e.castTx(token, e.candidates[0]).Input.BallotTx, TxElectionOutput{}) {
+		t.Error("expected ballots of an election without blind key to be rejected")
+	}
+}
+
+func TestValidateBlockTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		txs  func(e *blindElection) []*Transaction
+		err  error
+	}{
+		{
+			name: "tokens of two voters",
+			txs: func(e *blindElection) []*Transaction {
+				return []*Transaction{e.issueTx(e.voterKeys[0], e.newToken()), e.issueTx(e.voterKeys[1], e.newToken())}
+			},
+		},
+		{
+			name: "voter issued twice",
+			txs: func(e *blindElection) []*Transaction {
+				return []*Transaction{e.issueTx(e.voterKeys[0], e.newToken()), e.issueTx(e.voterKeys[0], e.newToken())}
+			},
+			err: ErrTokenIssued,
+		},
+		{
+			name: "voter issued twice with another key encoding",
+			txs: func(e *blindElection) []*Transaction {
+				voter := uncompressed(e.t, e.voterKeys[:1])[0]
+				return []*Transaction{e.issueTx(e.voterKeys[0], e.newToken()), e.issueTx(voter, e.newToken())}
+			},
+			err: ErrTokenIssued,
+		},
+		{
+			name: "ballots of two tokens",
+			txs: func(e *blindElection) []*Transaction {
+				return []*Transaction{e.castTx(e.issue(0), e.candidates[0]), e.castTx(e.issue(1), e.candidates[1])}
+			},
+		},
+		{
+			name: "token used twice",
+			txs: func(e *blindElection) []*Transaction {
+				e.issue(1)
+				token := e.issue(0)
+				return []*Transaction{e.castTx(token, e.candidates[0]), e.castTx(token, e.candidates[1])}
+			},
+			err: ErrTokenUsed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newBlindElection(t, 3)
+			e.open()
+			_, err := e.add(test.txs(e)...)
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
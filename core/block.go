@@ -2,11 +2,17 @@ package blockchain
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/gob"
+	"errors"
+	"fmt"
 	"time"
 
 	logger "github.com/sirupsen/logrus"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
 )
 
 // Block represent the Block entity of the blockchain
@@ -19,10 +25,42 @@ type Block struct {
 	Height       int            `json:"height"`
 	MerkleRoot   []byte         `json:"merkle_root"`
 	TxCount      int            `json:"tx_count"`
+	Signers      [][]byte       `json:"signers"` // SIGNATURE BY CONSENSUS GROUP
+	SigWitnesses [][]byte       `json:"sig_witnesses"`
 }
 
+// BlockHeader holds the block fields needed to check a merkle proof without
+// downloading the block transactions
+type BlockHeader struct {
+	Timestamp    int64    `json:"timestamp"`
+	Version      int      `json:"version"`
+	Hash         []byte   `json:"hash"`
+	PrevHash     []byte   `json:"prev_hash"`
+	Height       int      `json:"height"`
+	MerkleRoot   []byte   `json:"merkle_root"`
+	TxCount      int      `json:"tx_count"`
+	Signers      [][]byte `json:"signers"`
+	SigWitnesses [][]byte `json:"sig_witnesses"`
+}
+
+// TxProof holds what a client needs to check a transaction is part of a
+// block: the serialized transaction is the merkle leaf, hashed together with
+// the proof siblings it must give back the header merkle root
+type TxProof struct {
+	Header BlockHeader `json:"header"`
+	Tx     Transaction `json:"tx"`
+	Leaf   []byte      `json:"leaf"`
+	Proof  MerkleProof `json:"proof"`
+}
+
+// First block version hashing its merkle tree with leaf and node prefixes
+const MERKLE_PREFIX_VERSION = 2
+
 var (
-	Version = 1
+	Version = 2
+
+	ErrTxNotInBlock       = errors.New("Transaction is not part of the block")
+	ErrInvalidBlockHeader = errors.New("Invalid block header")
 )
 
 func NewBlock(txs []*Transaction, version int, prevHash []byte, height int) *Block {
@@ -35,6 +73,8 @@ func NewBlock(txs []*Transaction, version int, prevHash []byte, height int) *Blo
 		height,
 		[]byte{},
 		len(txs),
+		nil,
+		nil,
 	}
 	block.MerkleRoot = block.HashTransactions()
 	block.Hash = block.GetHashData()
@@ -42,13 +82,23 @@ func NewBlock(txs []*Transaction, version int, prevHash []byte, height int) *Blo
 	return block
 }
 
-// GetHashData  returns the hash of the block
+// GetHashData  returns the hash of the block. From MERKLE_PREFIX_VERSION on
+// the hash also commits to the block version, height and timestamp, so the
+// header of a signed block cannot claim the legacy merkle hashing or another
+// height than the one it was signed at.
 func (block *Block) GetHashData() []byte {
-	info := bytes.Join(
-		[][]byte{
-			block.MerkleRoot,
-			block.PrevHash,
-		}, []byte{})
+	data := [][]byte{
+		block.MerkleRoot,
+		block.PrevHash,
+	}
+	if block.Version >= MERKLE_PREFIX_VERSION {
+		for _, value := range []uint64{uint64(block.Version), uint64(block.Height), uint64(block.Timestamp)} {
+			field := make([]byte, 8)
+			binary.BigEndian.PutUint64(field, value)
+			data = append(data, field)
+		}
+	}
+	info := bytes.Join(data, []byte{})
 
 	hash := sha256.Sum256(info)
 	return hash[:]
@@ -56,14 +106,150 @@ func (block *Block) GetHashData() []byte {
 
 // HashTransactions Uses Merkle Tree to hash the Transactions
 func (block *Block) HashTransactions() []byte {
+	return block.transactionsTree().RootNode.Data
+}
+
+// transactionsTree builds the merkle tree of the serialized block transactions
+func (block *Block) transactionsTree() *MerkleTree {
 	var txHashes [][]byte
 
 	for _, tx := range block.Transactions {
 		txHashes = append(txHashes, tx.Serialize())
 	}
 
-	tree := NewMerkleTree(txHashes)
-	return tree.RootNode.Data
+	return newMerkleTree(txHashes, block.Version < MERKLE_PREFIX_VERSION)
+}
+
+// Header returns the header of the block
+func (block *Block) Header() BlockHeader {
+	return BlockHeader{
+		block.Timestamp,
+		block.Version,
+		block.Hash,
+		block.PrevHash,
+		block.Height,
+		block.MerkleRoot,
+		block.TxCount,
+		block.Signers,
+		block.SigWitnesses,
+	}
+}
+
+// Sign adds the signature of a member of the consensus group to the block
+func (block *Block) Sign(privKey ecdsa.PrivateKey, pubKey []byte) {
+	ms := multisig.NewMultisig(1)
+	ms.AddSignature(block.Hash, pubKey, privKey)
+
+	block.AddSignature(pubKey, ms.Sigs[0])
+}
+
+// AddSignature adds a signature of the block hash made by a signer outside of
+// the node
+func (block *Block) AddSignature(pubKey, sig []byte) {
+	block.Signers = append(block.Signers, pubKey)
+	block.SigWitnesses = append(block.SigWitnesses, sig)
+}
+
+// VerifyHash checks that the header hash commits to its merkle root, previous
+// block hash and, from MERKLE_PREFIX_VERSION on, its version, height and
+// timestamp
+func (header *BlockHeader) VerifyHash() bool {
+	block := Block{
+		Timestamp:  header.Timestamp,
+		Version:    header.Version,
+		PrevHash:   header.PrevHash,
+		Height:     header.Height,
+		MerkleRoot: header.MerkleRoot,
+	}
+	return bytes.Compare(block.GetHashData(), header.Hash) == 0
+}
+
+// VerifyTxProof checks that the serialized transaction is part of the block
+// with the merkle hashing of the block version
+func (header *BlockHeader) VerifyTxProof(leaf []byte, proof MerkleProof) bool {
+	return verifyProof(header.MerkleRoot, leaf, proof, header.Version < MERKLE_PREFIX_VERSION)
+}
+
+// VerifySignatures checks that the header is signed by the consensus group.
+// Every signature must verify and come from one of the validators, and more
+// than half of the validators must have signed.
+func (header *BlockHeader) VerifySignatures(validators [][]byte) error {
+	if len(header.Signers) == 0 || len(header.Signers) != len(header.SigWitnesses) {
+		return fmt.Errorf("%w: %d signers for %d signature witnesses", ErrInvalidBlockHeader, len(header.Signers), len(header.SigWitnesses))
+	}
+	if quorum := len(validators)/2 + 1; len(header.Signers) < quorum {
+		return fmt.Errorf("%w: %d signers, %d validators must sign", ErrInvalidBlockHeader, len(header.Signers), quorum)
+	}
+	for i, signer := range header.Signers {
+		if isValidator(validators, signer) == false {
+			return fmt.Errorf("%w: signer %x is not a validator", ErrInvalidBlockHeader, signer)
+		}
+		if isValidator(header.Signers[:i], signer) {
+			return fmt.Errorf("%w: validator %x signed twice", ErrInvalidBlockHeader, signer)
+		}
+		ms := multisig.MultiSig{
+			PubKeys: [][]byte{signer},
+			Sigs:    [][]byte{header.SigWitnesses[i]},
+		}
+		if verified, _ := ms.Verify(header.Hash); verified == false {
+			return fmt.Errorf("%w: signature of %x does not verify", ErrInvalidBlockHeader, signer)
+		}
+	}
+	return nil
+}
+
+// Serialize function for serializing block headers
+func (header *BlockHeader) Serialize() []byte {
+	var res bytes.Buffer
+	encoder := gob.NewEncoder(&res)
+
+	err := encoder.Encode(header)
+	if err != nil {
+		logger.Panic(err)
+	}
+	return res.Bytes()
+}
+
+// DeSerializeHeader function for De-serializing block headers
+func DeSerializeHeader(data []byte) *BlockHeader {
+	var header BlockHeader
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+
+	err := decoder.Decode(&header)
+	if err != nil {
+		logger.Panic(err)
+	}
+	return &header
+}
+
+// isValidator checks if the given key is part of the validators
+func isValidator(validators [][]byte, pubKey []byte) bool {
+	for _, v := range validators {
+		if keys.Equal(v, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyID returns the map key of an encoded public key, keys with another
+// encoding of the same point share it. Values that are not keys are used as is.
+func keyID(key []byte) string {
+	if pub, err := keys.ParseAny(key); err == nil {
+		return string(pub.Bytes())
+	}
+	return string(key)
+}
+
+// TxProof returns the merkle proof of the transaction with the given ID
+// against the block merkle root
+func (block *Block) TxProof(txId []byte) (MerkleProof, error) {
+	for i, tx := range block.Transactions {
+		if bytes.Compare(tx.ID, txId) == 0 {
+			return block.transactionsTree().Proof(i)
+		}
+	}
+	return MerkleProof{}, ErrTxNotInBlock
 }
 
 //Serialize function for serializing blockchain data
@@ -2,6 +2,7 @@ package blockchain
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
 	"strconv"
@@ -18,6 +19,10 @@ type Blockchain struct {
 	config   config.Config
 	lashHash []byte
 	crud     *Crud
+	verifier *BlockVerifier
+
+	// Signer of the blocks the node adds
+	signer NodeSigner
 }
 
 var (
@@ -26,10 +31,23 @@ var (
 
 func NewBlockchain(s database.Store, cfg config.Config) *Blockchain {
 	return &Blockchain{
-		config: cfg,
-		crud:   NewCrud(s),
+		config:   cfg,
+		crud:     NewCrud(s),
+		verifier: NewBlockVerifier(cfg.VerifierWorkers),
 	}
 }
+
+// SetBlockSigner sets the key the node signs the blocks it adds with
+func (bc *Blockchain) SetBlockSigner(privKey ecdsa.PrivateKey, pubKey []byte) {
+	bc.signer = NewLocalSigner(privKey, pubKey)
+}
+
+// SetSigner sets the signer of the blocks the node adds, the key may be held
+// by a remote signer
+func (bc *Blockchain) SetSigner(signer NodeSigner) {
+	bc.signer = signer
+}
+
 func (bc *Blockchain) ResetBlockchain(name string) error {
 	return database.RemoveDatabase(name)
 }
@@ -70,21 +88,39 @@ func (bc *Blockchain) ReInit() *Blockchain {
 	return bc
 }
 
+// validateBlock checks the transactions of a block against each other, every
+// transaction was verified alone against the blockchain
+func (bc *Blockchain) validateBlock(transactions []*Transaction) error {
+	if err := validateBlockTokens(transactions); err != nil {
+		return err
+	}
+	if err := validateBlockKeyImages(transactions); err != nil {
+		return err
+	}
+	return bc.validateBlockCounts(transactions)
+}
+
 func (bc *Blockchain) AddBlock(transactions []*Transaction) (*Block, error) {
 	mutex.Lock()
+	defer mutex.Unlock()
 	utxos := NewUnusedXTOSet(bc)
 
-	for _, tx := range transactions {
-
+	err := bc.verifier.Verify(transactions, func(tx *Transaction) error {
 		if tx.Valid(*utxos) != true {
-			logger.Error("Invalid Transaction")
-			return &Block{}, ErrInvalidTransaction
+			return ErrInvalidTransaction
 		}
-
 		if bc.VerifyTx(tx) != true {
-			logger.Error("Invalid Transaction Veriiiiift=y")
-			return &Block{}, ErrInvalidTransaction
+			return ErrInvalidTransaction
 		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Invalid Transaction: ", err)
+		return &Block{}, err
+	}
+	if err = bc.validateBlock(transactions); err != nil {
+		logger.Error("Invalid Block: ", err)
+		return &Block{}, err
 	}
 	// get block from lasthash
 	lastBlock, err := bc.crud.GetBlock(bc.lashHash)
@@ -98,6 +134,13 @@ func (bc *Blockchain) AddBlock(transactions []*Transaction) (*Block, error) {
 		bc.lashHash,
 		lastBlock.Height+1,
 	)
+	if bc.signer != nil {
+		sig, err := bc.signer.SignBlock(block.Header())
+		if err != nil {
+			return &Block{}, err
+		}
+		block.AddSignature(bc.signer.PublicKey(), sig)
+	}
 	// Store block
 	block, err = bc.crud.StoreBlock(block)
 	if err != nil {
@@ -111,7 +154,6 @@ func (bc *Blockchain) AddBlock(transactions []*Transaction) (*Block, error) {
 	// Compute
 	bc.ComputeUnUsedTXOs()
 
-	mutex.Unlock()
 	return block, nil
 }
 
@@ -179,6 +221,73 @@ func (bc *Blockchain) GetTransaction(txId []byte) (tx Transaction, err error) {
 	return tx, nil
 }
 
+// GetHeaders returns the headers of the blocks from the given height up to
+// the last block, in ascending order
+func (bc *Blockchain) GetHeaders(height int) ([]BlockHeader, error) {
+	var headers []BlockHeader
+
+	iter, err := bc.crud.Iterator()
+	if err != nil {
+		return headers, err
+	}
+	for {
+		block := iter.Next()
+		if block.Height < height {
+			break
+		}
+		headers = append(headers, block.Header())
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+
+	reverseHeaders(headers)
+	return headers, nil
+}
+
+// GetTxProof returns the merkle inclusion proof of the transaction with the
+// given ID
+func (bc *Blockchain) GetTxProof(txId []byte) (TxProof, error) {
+	block, err := bc.FindTxBlock(txId)
+	if err != nil {
+		return TxProof{}, err
+	}
+	proof, err := block.TxProof(txId)
+	if err != nil {
+		return TxProof{}, err
+	}
+	tx := block.Transactions[proof.Index]
+
+	return TxProof{
+		Header: block.Header(),
+		Tx:     *tx,
+		Leaf:   tx.Serialize(),
+		Proof:  proof,
+	}, nil
+}
+
+// FindTxBlock returns the block holding the transaction with the given ID
+func (bc *Blockchain) FindTxBlock(txId []byte) (Block, error) {
+	iter, err := bc.crud.Iterator()
+	if err != nil {
+		return Block{}, err
+	}
+	for {
+		block := iter.Next()
+		for _, tx := range block.Transactions {
+			if bytes.Compare(tx.ID, txId) == 0 {
+				return *block, nil
+			}
+		}
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+
+	return Block{}, ErrTxNotInBlock
+}
+
 func (bc *Blockchain) GetTransactions() (txs []*Transaction, err error) {
 	iter, err := bc.crud.Iterator()
 	if err != nil {
@@ -328,37 +437,21 @@ func (bc *Blockchain) GetUnUsedBallotTxOutputs(pubKey []byte) (tx []map[string]T
 	return
 }
 
-func (bc *Blockchain) QueryResult(pubKey []byte) (map[string]int, error) {
-	var candidate string
-	var results = make(map[string]int)
+// QueryResult tallies the ballots of each race of the election
+func (bc *Blockchain) QueryResult(pubKey []byte) (map[string]RaceResult, error) {
+	var ballots []TxBallotInput
 	txElection, _ := bc.FindTxWithElectionOutByPubkey(pubKey)
-	for _, v := range txElection.Output.ElectionTx.Candidates {
-		candidate := hex.EncodeToString(v)
-		results[candidate] = 0
-	}
-	fmt.Println(results)
 
-	iter, err := bc.crud.Iterator()
+	txs, err := bc.GetTransactionsByPubkey(pubKey)
 	if err != nil {
-		return results, err
+		return tallyBallots(txElection.Output.ElectionTx, ballots), err
 	}
-	for {
-		block := iter.Next()
-		for i := 0; i < len(block.Transactions); i++ {
-			tx := *block.Transactions[i]
-			txBallotIn := tx.Input.BallotTx
-			if bytes.Compare(tx.ElectionPubkey, pubKey) == 0 {
-				candidate = hex.EncodeToString(txBallotIn.Candidate)
-				if _, ok := results[candidate]; ok {
-					results[candidate] += 1
-				}
-			}
-		}
-		if len(block.PrevHash) == 0 {
-			break
+	for _, tx := range txs {
+		if tx.Input.BallotTx.IsSet() {
+			ballots = append(ballots, tx.Input.BallotTx)
 		}
 	}
-	return results, nil
+	return tallyBallots(txElection.Output.ElectionTx, ballots), nil
 }
 
 func (bc *Blockchain) GetPrevTransactionByInput(transaction *Transaction) (Transaction, error) {
@@ -373,6 +466,11 @@ func (bc *Blockchain) GetPrevTransactionByInput(transaction *Transaction) (Trans
 	case VOTING_TX_TYPE:
 		txId = transaction.Input.VotingTx.TxOut
 	case BALLOT_TX_TYPE:
+		// Blind ballots do not spend an output, their token is checked
+		// against the election
+		if transaction.Input.BallotTx.IsBlind() {
+			return bc.FindTxWithElectionOutByPubkey(transaction.ElectionPubkey)
+		}
 		txId = transaction.Input.BallotTx.TxOut
 	}
 
@@ -396,6 +494,8 @@ func (bc *Blockchain) GetPrevTransactionByOutput(transaction *Transaction) (Tran
 		txId = transaction.Output.VotingTx.TxID
 	case BALLOT_TX_TYPE:
 		txId = transaction.Output.BallotTx.TxID
+	case REVOCATION_TX_TYPE:
+		txId = transaction.Output.RevocationTx.TxID
 	}
 
 	tx, err := bc.crud.FindTransaction(txId)
@@ -431,6 +531,48 @@ func (bc *Blockchain) VerifyTx(tx *Transaction) bool {
 		return false
 	}
 
+	if err = bc.validateCounts(tx); err != nil {
+		logger.Error(err)
+		return false
+	}
+
+	if err = bc.validateVoterRoll(tx); err != nil {
+		logger.Error(err)
+		return false
+	}
+
+	if err = bc.validateRing(tx); err != nil {
+		logger.Error(err)
+		return false
+	}
+
+	if err = bc.validateCertificates(tx); err != nil {
+		logger.Error(err)
+		return false
+	}
+
+	if err = bc.validateBlindTokens(tx); err != nil {
+		logger.Error(err)
+		return false
+	}
+
+	if err = bc.validateKeyImage(tx); err != nil {
+		logger.Error(err)
+		return false
+	}
+
+	if tx.Input.BallotTx.IsSet() {
+		txElection, _ := bc.FindTxWithElectionOutByPubkey(tx.ElectionPubkey)
+		if txElection.Output.ElectionTx.IsSet() == false {
+			logger.Error("Election does not exist")
+			return false
+		}
+		if err = txElection.Output.ElectionTx.ValidateBallot(tx.Input.BallotTx); err != nil {
+			logger.Error(err)
+			return false
+		}
+	}
+
 	return tx.Verify(prevTx)
 }
 
@@ -0,0 +1,171 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/identity"
+)
+
+var (
+	ErrNoCertificateAuthority = errors.New("Election has no certificate authority")
+	ErrMissingCertificate     = errors.New("Missing voter certificate")
+	ErrRevokedCertificate     = errors.New("Voter certificate is revoked")
+)
+
+// GetRevokedSerials returns the serial numbers of the voter certificates
+// revoked by the commission of the election, hex encoded
+func (bc *Blockchain) GetRevokedSerials(pubKey []byte) (map[string]bool, error) {
+	txs, err := bc.GetTransactionsByPubkey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	revoked := make(map[string]bool)
+	for _, tx := range txs {
+		for _, serial := range tx.Output.RevocationTx.Serials {
+			revoked[fmt.Sprintf("%x", serial)] = true
+		}
+	}
+	return revoked, nil
+}
+
+// validateCertificates checks that revocations target an election with a
+// certificate authority, and that every voter published when accreditation
+// stops comes with the serial of its certificate, which is not revoked, and
+// the hash of the certificate. The certificates themselves hold the identity
+// of the voters and stay off-chain, the hashes let auditors check them once
+// published, see VerifyVoterCertificates. Elections without a certificate
+// authority are not checked.
+func (bc *Blockchain) validateCertificates(tx *Transaction) error {
+	revocationOut := tx.Output.RevocationTx
+	acIn := tx.Input.AccreditationTx
+	if revocationOut.IsSet() == false && acIn.IsSet() == false {
+		return nil
+	}
+
+	txElection, err := bc.FindTxWithElectionOutByPubkey(tx.ElectionPubkey)
+	if err != nil {
+		return err
+	}
+	election := txElection.Output.ElectionTx
+	if election.IsSet() == false || bytes.Compare(election.ElectionPubKey, tx.ElectionPubkey) != 0 {
+		return ErrNoCertificateAuthority
+	}
+
+	if revocationOut.IsSet() {
+		if len(election.CACertificate) == 0 {
+			return ErrNoCertificateAuthority
+		}
+		if bytes.Compare(revocationOut.TxID, txElection.ID) != 0 {
+			return fmt.Errorf("%w: revocation does not reference the election output", ErrInvalidTransaction)
+		}
+		if len(revocationOut.Serials) == 0 {
+			return fmt.Errorf("%w: no certificate revoked", ErrInvalidTransaction)
+		}
+		return nil
+	}
+
+	if len(election.CACertificate) == 0 {
+		return nil
+	}
+	if len(acIn.Voters) == 0 {
+		return fmt.Errorf("%w: accredited voters are not published", ErrMissingCertificate)
+	}
+	if len(acIn.CertificateSerials) != len(acIn.Voters) {
+		return fmt.Errorf("%w: %d certificate serials for %d voters", ErrMissingCertificate, len(acIn.CertificateSerials), len(acIn.Voters))
+	}
+	if len(acIn.CertificateHashes) != len(acIn.Voters) {
+		return fmt.Errorf("%w: %d certificate hashes for %d voters", ErrMissingCertificate, len(acIn.CertificateHashes), len(acIn.Voters))
+	}
+
+	revoked, err := bc.GetRevokedSerials(tx.ElectionPubkey)
+	if err != nil {
+		return err
+	}
+	serials := make(map[string]bool)
+	for i, serial := range acIn.CertificateSerials {
+		if len(serial) == 0 {
+			return fmt.Errorf("%w: voter %x", ErrMissingCertificate, acIn.Voters[i])
+		}
+		if revoked[fmt.Sprintf("%x", serial)] {
+			return fmt.Errorf("%w: voter %x", ErrRevokedCertificate, acIn.Voters[i])
+		}
+		if serials[string(serial)] {
+			return fmt.Errorf("%w: certificate %x is used twice", ErrInvalidAcVoters, serial)
+		}
+		serials[string(serial)] = true
+	}
+	hashes := make(map[string]bool)
+	for i, hash := range acIn.CertificateHashes {
+		if len(hash) != sha256.Size {
+			return fmt.Errorf("%w: voter %x has no certificate hash", ErrMissingCertificate, acIn.Voters[i])
+		}
+		if hashes[string(hash)] {
+			return fmt.Errorf("%w: certificate hash %x is used twice", ErrInvalidAcVoters, hash)
+		}
+		hashes[string(hash)] = true
+	}
+	return nil
+}
+
+// VerifyVoterCertificates checks the certificates of the voters accredited by
+// the accreditation input, in the same order as its voters. Each certificate
+// must be issued by the election authority for its voter, valid at the
+// accreditation time, match the serial and hash published on-chain and not be
+// revoked. A voter ID can only be accredited once. The certificates are
+// checked off-chain by the commission before it signs the accreditation.
+func (bc *Blockchain) VerifyVoterCertificates(pubKey []byte, acIn TxAcInput, certificates [][]byte) error {
+	txElection, err := bc.FindTxWithElectionOutByPubkey(pubKey)
+	if err != nil {
+		return err
+	}
+	election := txElection.Output.ElectionTx
+	if election.IsSet() == false || bytes.Compare(election.ElectionPubKey, pubKey) != 0 {
+		return ErrNoCertificateAuthority
+	}
+	revoked, err := bc.GetRevokedSerials(pubKey)
+	if err != nil {
+		return err
+	}
+	return verifyVoterCertificates(election, acIn, certificates, revoked)
+}
+
+// verifyVoterCertificates checks the certificates of the accredited voters
+// against the serials revoked so far, hex encoded
+func verifyVoterCertificates(election TxElectionOutput, acIn TxAcInput, certificates [][]byte, revoked map[string]bool) error {
+	if len(election.CACertificate) == 0 {
+		return ErrNoCertificateAuthority
+	}
+	if len(certificates) != len(acIn.Voters) || len(acIn.CertificateSerials) != len(acIn.Voters) || len(acIn.CertificateHashes) != len(acIn.Voters) {
+		return fmt.Errorf("%w: %d certificates, %d serials and %d hashes for %d voters", ErrMissingCertificate,
+			len(certificates), len(acIn.CertificateSerials), len(acIn.CertificateHashes), len(acIn.Voters))
+	}
+
+	at := time.Unix(acIn.Timestamp, 0)
+	voterIDs := make(map[string]bool)
+	for i, voter := range acIn.Voters {
+		cert, err := identity.Verify(election.CACertificate, certificates[i], voter, at)
+		if err != nil {
+			return fmt.Errorf("voter %x: %w", voter, err)
+		}
+		serial := identity.Serial(cert)
+		if bytes.Compare(serial, acIn.CertificateSerials[i]) != 0 {
+			return fmt.Errorf("%w: certificate of voter %x does not match its serial", ErrMissingCertificate, voter)
+		}
+		if bytes.Compare(identity.Fingerprint(cert), acIn.CertificateHashes[i]) != 0 {
+			return fmt.Errorf("%w: certificate of voter %x does not match its hash", ErrMissingCertificate, voter)
+		}
+		if revoked[fmt.Sprintf("%x", serial)] {
+			return fmt.Errorf("%w: voter %x", ErrRevokedCertificate, voter)
+		}
+		voterID := identity.IdentityOf(cert).VoterID
+		if voterIDs[voterID] {
+			return fmt.Errorf("%w: voter ID %s is accredited twice", ErrInvalidAcVoters, voterID)
+		}
+		voterIDs[voterID] = true
+	}
+	return nil
+}
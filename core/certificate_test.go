@@ -0,0 +1,294 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/identity"
+)
+
+// certifiedElection is a test election whose voters are certified by the
+// election authority
+type certifiedElection struct {
+	*testElection
+	caKey        *ecdsa.PrivateKey
+	caCert       []byte
+	certificates [][]byte
+	serials      [][]byte
+	hashes       [][]byte
+}
+
+func newCertifiedElection(t *testing.T, voters int) *certifiedElection {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := identity.NewCA("Electoral Commission", caKey, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &certifiedElection{
+		testElection: newTestElection(t, voters, func(out *TxElectionOutput) {
+			out.CACertificate = caCert
+		}),
+		caKey:  caKey,
+		caCert: caCert,
+	}
+	for i, voter := range e.voters {
+		cert := e.issue(&voter.PublicKey, fmt.Sprintf("V-%04d", i))
+		e.certificates = append(e.certificates, cert)
+		e.serials = append(e.serials, e.serial(cert))
+		e.hashes = append(e.hashes, e.hash(cert))
+	}
+	e.startAccreditation()
+	return e
+}
+
+func (e *certifiedElection) issue(voter *ecdsa.PublicKey, voterID string) []byte {
+	e.t.Helper()
+	cert, err := identity.Issue(e.caCert, e.caKey, voter, identity.Identity{VoterID: voterID}, time.Hour)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return cert
+}
+
+func (e *certifiedElection) serial(cert []byte) []byte {
+	e.t.Helper()
+	leaf, err := identity.Parse(cert)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return identity.Serial(leaf)
+}
+
+func (e *certifiedElection) hash(cert []byte) []byte {
+	e.t.Helper()
+	leaf, err := identity.Parse(cert)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return identity.Fingerprint(leaf)
+}
+
+// certify publishes the serials and hashes of the voter certificates
+func (e *certifiedElection) certify(in *TxAcInput) {
+	in.CertificateSerials = e.serials
+	in.CertificateHashes = e.hashes
+}
+
+func (e *certifiedElection) revoke(serials ...[]byte) {
+	e.t.Helper()
+	out := NewRevocationTxOutput(e.pubKey, e.election.ID, serials, nil, nil, time.Now().Unix())
+	out.RevocationTx.Signers, out.RevocationTx.SigWitnesses = e.sign(out.RevocationTx.ToByte())
+	e.mustAdd(e.tx(REVOCATION_TX_TYPE, TxInput{}, *out))
+}
+
+func TestValidateCertificates(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(e *certifiedElection, in *TxAcInput)
+		err   error
+	}{
+		{"certified voters", nil, nil},
+		{
+			name: "voters not published",
+			setup: func(e *certifiedElection, in *TxAcInput) {
+				in.Voters, in.CertificateSerials, in.CertificateHashes = nil, nil, nil
+			},
+			err: ErrMissingCertificate,
+		},
+		{
+			name:  "missing serial",
+			setup: func(e *certifiedElection, in *TxAcInput) { in.CertificateSerials = e.serials[:2] },
+			err:   ErrMissingCertificate,
+		},
+		{
+			name: "empty serial",
+			setup: func(e *certifiedElection, in *TxAcInput) {
+				in.CertificateSerials = [][]byte{e.serials[0], nil, e.serials[2]}
+			},
+			err: ErrMissingCertificate,
+		},
+		{
+			name: "serial used twice",
+			setup: func(e *certifiedElection, in *TxAcInput) {
+				in.CertificateSerials = [][]byte{e.serials[0], e.serials[0], e.serials[2]}
+			},
+			err: ErrInvalidAcVoters,
+		},
+		{
+			name:  "missing hash",
+			setup: func(e *certifiedElection, in *TxAcInput) { in.CertificateHashes = e.hashes[:2] },
+			err:   ErrMissingCertificate,
+		},
+		{
+			name: "malformed hash",
+			setup: func(e *certifiedElection, in *TxAcInput) {
+				in.CertificateHashes = [][]byte{e.hashes[0], e.hashes[1][:16], e.hashes[2]}
+			},
+			err: ErrMissingCertificate,
+		},
+		{
+			name: "hash used twice",
+			setup: func(e *certifiedElection, in *TxAcInput) {
+				in.CertificateHashes = [][]byte{e.hashes[0], e.hashes[0], e.hashes[2]}
+			},
+			err: ErrInvalidAcVoters,
+		},
+		{
+			name:  "revoked certificate",
+			setup: func(e *certifiedElection, in *TxAcInput) { e.revoke(e.serials[1]) },
+			err:   ErrRevokedCertificate,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newCertifiedElection(t, 3)
+			tx := e.stopAccreditationTx(func(in *TxAcInput) {
+				e.certify(in)
+				if test.setup != nil {
+					test.setup(e, in)
+				}
+			})
+
+			err := e.bc.validateCertificates(tx)
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateRevocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		certified bool
+		serials   [][]byte
+		err       error
+	}{
+		{"revoked serial", true, [][]byte{{0x01}}, nil},
+		{"no serial", true, nil, ErrInvalidTransaction},
+		{"no certificate authority", false, [][]byte{{0x01}}, ErrNoCertificateAuthority},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var e *testElection
+			if test.certified {
+				e = newCertifiedElection(t, 1).testElection
+			} else {
+				e = newTestElection(t, 1, nil)
+			}
+			out := NewRevocationTxOutput(e.pubKey, e.election.ID, test.serials, nil, nil, time.Now().Unix())
+			out.RevocationTx.Signers, out.RevocationTx.SigWitnesses = e.sign(out.RevocationTx.ToByte())
+
+			err := e.bc.validateCertificates(e.tx(REVOCATION_TX_TYPE, TxInput{}, *out))
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyVoterCertificates(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(e *certifiedElection, in *TxAcInput, certificates [][]byte) [][]byte
+		err   error
+	}{
+		{"certified voters", nil, nil},
+		{
+			name: "missing certificate",
+			setup: func(e *certifiedElection, in *TxAcInput, certificates [][]byte) [][]byte {
+				return certificates[:2]
+			},
+			err: ErrMissingCertificate,
+		},
+		{
+			name: "certificate of another voter",
+			setup: func(e *certifiedElection, in *TxAcInput, certificates [][]byte) [][]byte {
+				return [][]byte{certificates[1], certificates[0], certificates[2]}
+			},
+			err: identity.ErrKeyMismatch,
+		},
+		{
+			name: "serial of another certificate",
+			setup: func(e *certifiedElection, in *TxAcInput, certificates [][]byte) [][]byte {
+				in.CertificateSerials = [][]byte{e.serials[0], e.serials[2], e.serials[1]}
+				return certificates
+			},
+			err: ErrMissingCertificate,
+		},
+		{
+			name: "hash of another certificate",
+			setup: func(e *certifiedElection, in *TxAcInput, certificates [][]byte) [][]byte {
+				in.CertificateHashes = [][]byte{e.hashes[0], e.hash(e.issue(&e.voters[1].PublicKey, "V-0001")), e.hashes[2]}
+				return certificates
+			},
+			err: ErrMissingCertificate,
+		},
+		{
+			name: "revoked certificate",
+			setup: func(e *certifiedElection, in *TxAcInput, certificates [][]byte) [][]byte {
+				e.revoke(e.serials[2])
+				return certificates
+			},
+			err: ErrRevokedCertificate,
+		},
+		{
+			name: "voter ID accredited twice",
+			setup: func(e *certifiedElection, in *TxAcInput, certificates [][]byte) [][]byte {
+				cert := e.issue(&e.voters[1].PublicKey, "V-0000")
+				in.CertificateSerials = [][]byte{e.serials[0], e.serial(cert), e.serials[2]}
+				in.CertificateHashes = [][]byte{e.hashes[0], e.hash(cert), e.hashes[2]}
+				return [][]byte{certificates[0], cert, certificates[2]}
+			},
+			err: ErrInvalidAcVoters,
+		},
+		{
+			name: "expired at accreditation",
+			setup: func(e *certifiedElection, in *TxAcInput, certificates [][]byte) [][]byte {
+				in.Timestamp = time.Now().Add(2 * time.Hour).Unix()
+				return certificates
+			},
+			err: identity.ErrNotIssuedByCA,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newCertifiedElection(t, 3)
+			certificates := e.certificates
+			tx := e.stopAccreditationTx(func(in *TxAcInput) {
+				e.certify(in)
+				if test.setup != nil {
+					certificates = test.setup(e, in, e.certificates)
+				}
+			})
+
+			err := e.bc.VerifyVoterCertificates(e.pubKey, tx.Input.AccreditationTx, certificates)
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+
+	e := newTestElection(t, 1, nil)
+	if err := e.bc.VerifyVoterCertificates(e.pubKey, TxAcInput{}, nil); errors.Is(err, ErrNoCertificateAuthority) == false {
+		t.Errorf("expected %v, got %v", ErrNoCertificateAuthority, err)
+	}
+}
@@ -20,6 +20,11 @@ type TxElectionOutput struct {
 	Description    string   `json:"description "`
 	TotalPeople    int64    `json:"total_people"`
 	Candidates     [][]byte `json:"candidates"`
+	BallotFormat   string   `json:"ballot_format"`
+	MaxChoices     int64    `json:"max_choices"`    // Number of candidates a voter may pick in pick-k elections
+	Races          []Race   `json:"races"`          // Contests of the election, replaces the candidates when set
+	CACertificate  []byte   `json:"ca_certificate"` // Commission authority certifying the voters, PEM encoded
+	BlindKey       []byte   `json:"blind_key"`      // Commission RSA key blind signing the ballot tokens, PEM encoded
 }
 
 // End Election TxInput
@@ -72,24 +77,67 @@ func (tx TxElectionOutput) TrimmedCopy() *TxElectionOutput {
 		tx.Description,
 		tx.TotalPeople,
 		tx.Candidates,
+		tx.BallotFormat,
+		tx.MaxChoices,
+		tx.Races,
+		tx.CACertificate,
+		tx.BlindKey,
 	}
 	return txCopy
 }
 
 // Convert Election output to Byte for verification and signing purposes
 func (tx *TxElectionOutput) ToByte() []byte {
-	var hash [32]byte
-
-	txCopy := tx.TrimmedCopy()
-
-	hash = sha256.Sum256([]byte(fmt.Sprintf("%x", txCopy)))
-	return hash[:]
+	return signingHash(
+		&txElectionOutputV1{"", nil, nil, tx.ElectionPubKey, tx.Title, tx.Description, tx.TotalPeople, tx.Candidates},
+		payloadField{"ballot_format", tx.BallotFormat},
+		payloadField{"max_choices", tx.MaxChoices},
+		payloadField{"races", tx.Races},
+		payloadField{"ca_certificate", tx.CACertificate},
+		payloadField{"blind_key", tx.BlindKey},
+	)
 }
 
 func (tx *TxElectionOutput) IsSet() bool {
 	return reflect.DeepEqual(tx, &TxElectionOutput{}) == false
 }
 
+// txElectionOutputV1 is the layout of the first version of election outputs,
+// the signing payload starts with it
+type txElectionOutputV1 struct {
+	ID             string
+	Signers        [][]byte
+	SigWitnesses   [][]byte
+	ElectionPubKey []byte
+	Title          string
+	Description    string
+	TotalPeople    int64
+	Candidates     [][]byte
+}
+
+// String formats the first version of election outputs as its String did,
+// which is what the first version signed
+func (tx *txElectionOutputV1) String() string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("--TX_OUTPUT \n"))
+	if reflect.DeepEqual(tx, &txElectionOutputV1{}) == false {
+		lines = append(lines, fmt.Sprintf("	ID: %s", tx.ID))
+		lines = append(lines, fmt.Sprintf("	Title: %s", tx.Title))
+		lines = append(lines, fmt.Sprintf("	Signers"))
+		for i := 0; i < len(tx.Signers); i++ {
+			lines = append(lines, fmt.Sprintf("		--(%d): %x", i, tx.Signers[i]))
+		}
+		lines = append(lines, fmt.Sprintf("	Signature Witnesses:"))
+		for i := 0; i < len(tx.SigWitnesses); i++ {
+			lines = append(lines, fmt.Sprintf("		--(%d): %x", i, tx.SigWitnesses[i]))
+		}
+		lines = append(lines, fmt.Sprintf("	Description: %s", tx.Description))
+		lines = append(lines, fmt.Sprintf("	People: %d", tx.TotalPeople))
+		lines = append(lines, fmt.Sprintf("	Election Keyhash: %s", tx.ElectionPubKey))
+	}
+	return strings.Join(lines, "\n")
+}
+
 // Trim election input data
 func (tx *TxElectionInput) TrimmedCopy() *TxElectionInput {
 	txCopy := &TxElectionInput{
@@ -151,6 +199,10 @@ func (tx *TxElectionOutput) String() string {
 		}
 		lines = append(lines, fmt.Sprintf("	Description: %s", tx.Description))
 		lines = append(lines, fmt.Sprintf("	People: %d", tx.TotalPeople))
+		lines = append(lines, fmt.Sprintf("	Ballot Format: %s", tx.GetBallotFormat()))
+		for i := 0; i < len(tx.Races); i++ {
+			lines = append(lines, fmt.Sprintf("		--(%s): %s [%s] %d candidates", tx.Races[i].ID, tx.Races[i].Title, tx.Races[i].GetBallotFormat(), len(tx.Races[i].Candidates)))
+		}
 		lines = append(lines, fmt.Sprintf("	Election Keyhash: %s", tx.ElectionPubKey))
 	}
 	return strings.Join(lines, "\n")
@@ -0,0 +1,287 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/database"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/config"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/ringsig"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+// memStore keeps the blockchain of a test in memory
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string][]byte)}
+}
+
+func (s *memStore) Get(k []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[string(k)]
+	if !ok {
+		return nil, database.ErrKeyNotFound
+	}
+	return append([]byte{}, v...), nil
+}
+
+func (s *memStore) Put(k, v []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[string(k)] = append([]byte{}, v...)
+	return nil
+}
+
+func (s *memStore) Delete(k []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, string(k))
+	return nil
+}
+
+func (s *memStore) Seek(k []byte, f func(k, v []byte)) {
+	s.mu.Lock()
+	var found []string
+	for key := range s.data {
+		if strings.HasPrefix(key, string(k)) {
+			found = append(found, key)
+		}
+	}
+	sort.Strings(found)
+	values := make([][]byte, len(found))
+	for i, key := range found {
+		values[i] = s.data[key]
+	}
+	s.mu.Unlock()
+
+	for i, key := range found {
+		f([]byte(key), values[i])
+	}
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
+
+func newTestChain(t *testing.T) *Blockchain {
+	t.Helper()
+	return NewBlockchain(newMemStore(), config.Config{}).Init()
+}
+
+// testElection runs an election with a commission of three members on an
+// in-memory blockchain
+type testElection struct {
+	t          *testing.T
+	bc         *Blockchain
+	pubKey     []byte
+	commission []*ecdsa.PrivateKey
+	candidates [][]byte
+	voters     []*ecdsa.PrivateKey
+	voterKeys  [][]byte
+	election   *Transaction
+	acOut      *Transaction
+	votingOut  *Transaction
+}
+
+// newTestElection adds an election with three candidates and the given number
+// of voters, setup can change the election output before it is signed
+func newTestElection(t *testing.T, voters int, setup func(out *TxElectionOutput)) *testElection {
+	t.Helper()
+	return newChainElection(t, newTestChain(t), voters, setup)
+}
+
+// newChainElection adds the election to the given blockchain
+func newChainElection(t *testing.T, bc *Blockchain, voters int, setup func(out *TxElectionOutput)) *testElection {
+	t.Helper()
+	e := &testElection{
+		t:      t,
+		bc:     bc,
+		pubKey: []byte("test_election_" + t.Name()),
+	}
+	for i := 0; i < 3; i++ {
+		priv, _ := wallet.NewKeyPair()
+		e.commission = append(e.commission, priv)
+		_, candidate := wallet.NewKeyPair()
+		e.candidates = append(e.candidates, candidate)
+	}
+	for i := 0; i < voters; i++ {
+		priv, pub := wallet.NewKeyPair()
+		e.voters = append(e.voters, priv)
+		e.voterKeys = append(e.voterKeys, pub)
+	}
+
+	out := NewElectionTxOutput("Test", "Test election", e.pubKey, nil, nil, e.candidates, int64(voters))
+	if setup != nil {
+		setup(&out.ElectionTx)
+	}
+	out.ElectionTx.Signers, out.ElectionTx.SigWitnesses = e.sign(out.ElectionTx.ToByte())
+	e.election = e.tx(ELECTION_TX_TYPE, TxInput{}, *out)
+	e.mustAdd(e.election)
+	return e
+}
+
+// sign signs the data with every member of the commission
+func (e *testElection) sign(data []byte) (signers, sigs [][]byte) {
+	mu := multisig.NewMultisig(len(e.commission))
+	for _, priv := range e.commission {
+		mu.AddSignature(data, keys.FromECDSA(&priv.PublicKey).Bytes(), *priv)
+	}
+	return mu.PubKeys, mu.Sigs
+}
+
+func (e *testElection) tx(txType string, in TxInput, out TxOutput) *Transaction {
+	tx, err := NewTransaction(txType, e.pubKey, in, out)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return tx
+}
+
+func (e *testElection) add(txs ...*Transaction) (*Block, error) {
+	return e.bc.AddBlock(txs)
+}
+
+func (e *testElection) mustAdd(txs ...*Transaction) *Block {
+	e.t.Helper()
+	block, err := e.add(txs...)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return block
+}
+
+func (e *testElection) startAccreditation() *Transaction {
+	e.t.Helper()
+	out := NewAccreditationTxOutput(e.pubKey, e.election.ID, nil, nil, time.Now().Unix())
+	out.AccreditationTx.RingCommitment = RingCommitment(e.ringSecret())
+	out.AccreditationTx.Signers, out.AccreditationTx.SigWitnesses = e.sign(out.AccreditationTx.ToByte())
+	e.acOut = e.tx(ACCREDITATION_TX_TYPE, TxInput{}, *out)
+	e.mustAdd(e.acOut)
+	return e.acOut
+}
+
+// ringSecret returns the secret of the commission seeding the ballot rings
+func (e *testElection) ringSecret() []byte {
+	return RingSecret(*e.commission[0], e.pubKey)
+}
+
+// stopAccreditationTx stops accreditation publishing every voter, setup can
+// change the input before it is signed
+func (e *testElection) stopAccreditationTx(setup func(in *TxAcInput)) *Transaction {
+	in := NewAccreditationTxInput(e.pubKey, e.election.ID, e.acOut.ID, nil, nil, int64(len(e.voterKeys)), time.Now().Unix())
+	in.AccreditationTx.Voters = e.voterKeys
+	in.AccreditationTx.RingSize = int64(len(e.voterKeys))
+	in.AccreditationTx.RingSecret = e.ringSecret()
+	if setup != nil {
+		setup(&in.AccreditationTx)
+	}
+	in.AccreditationTx.Signers, in.AccreditationTx.SigWitnesses = e.sign(in.AccreditationTx.ToByte())
+	return e.tx(ACCREDITATION_TX_TYPE, *in, TxOutput{})
+}
+
+func (e *testElection) stopAccreditation() *Transaction {
+	e.t.Helper()
+	tx := e.stopAccreditationTx(nil)
+	e.mustAdd(tx)
+	return tx
+}
+
+func (e *testElection) startVoting() *Transaction {
+	e.t.Helper()
+	out := NewVotingTxOutput(e.pubKey, e.election.ID, nil, nil, time.Now().Unix())
+	out.VotingTx.Signers, out.VotingTx.SigWitnesses = e.sign(out.VotingTx.ToByte())
+	e.votingOut = e.tx(VOTING_TX_TYPE, TxInput{}, *out)
+	e.mustAdd(e.votingOut)
+	return e.votingOut
+}
+
+func (e *testElection) stopVoting() *Transaction {
+	e.t.Helper()
+	in := NewVotingTxInput(e.pubKey, e.election.ID, e.votingOut.ID, nil, nil, time.Now().Unix())
+	in.VotingTx.Signers, in.VotingTx.SigWitnesses = e.sign(in.VotingTx.ToByte())
+	tx := e.tx(VOTING_TX_TYPE, *in, TxOutput{})
+	e.mustAdd(tx)
+	return tx
+}
+
+// ring returns the ballot ring of the voter
+func (e *testElection) ring(voter int) [][]byte {
+	e.t.Helper()
+	ring, err := e.bc.GetRing(e.pubKey, e.voterKeys[voter])
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return ring
+}
+
+// ballotOutputTx issues a ballot to the ring
+func (e *testElection) ballotOutputTx(ring [][]byte) *Transaction {
+	out := NewBallotTxOutput(e.pubKey, []byte("ballot"), e.election.ID, ring, nil, nil, time.Now().Unix())
+	out.BallotTx.Signers, out.BallotTx.SigWitnesses = e.sign(out.BallotTx.ToByte())
+	return e.tx(BALLOT_TX_TYPE, TxInput{}, *out)
+}
+
+// ballotInputTx casts the ballot output for the candidate, signed by the voter
+// with the ring of the output
+func (e *testElection) ballotInputTx(voter int, ballotOut *Transaction, setup func(in *TxBallotInput)) *Transaction {
+	e.t.Helper()
+	ring := ballotOut.Output.BallotTx.PubKeys
+	in := NewBallotTxInput(e.pubKey, e.candidates[0], e.election.ID, ballotOut.ID, nil, ring, time.Now().Unix())
+	if setup != nil {
+		setup(&in.BallotTx)
+	}
+	pubKeys, err := ringsig.ParsePublicKeyRing(ring)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	sig, err := ringsig.SignLinkable(e.voters[voter], pubKeys, in.BallotTx.ToByte(), e.pubKey)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	in.BallotTx.Signature = sig.ToByte()
+	return e.tx(BALLOT_TX_TYPE, *in, TxOutput{})
+}
+
+// vote issues a ballot to the ring of the voter and casts it for the
+// candidate
+func (e *testElection) vote(voter int, candidate []byte) *Transaction {
+	e.t.Helper()
+	out := e.ballotOutputTx(e.ring(voter))
+	e.mustAdd(out)
+	in := e.ballotInputTx(voter, out, func(in *TxBallotInput) {
+		in.Candidate = candidate
+	})
+	e.mustAdd(in)
+	return in
+}
+
+// forceAdd appends the transactions to the blockchain without verifying
+// them, as a faulty node would
+func (e *testElection) forceAdd(txs ...*Transaction) *Block {
+	e.t.Helper()
+	last, err := e.bc.crud.GetBlock(e.bc.lashHash)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	block := NewBlock(txs, Version, e.bc.lashHash, last.Height+1)
+	if _, err = e.bc.crud.StoreBlock(block); err != nil {
+		e.t.Fatal(err)
+	}
+	if err = e.bc.crud.Save(lastHashKey, block.Hash); err != nil {
+		e.t.Fatal(err)
+	}
+	e.bc.lashHash = block.Hash
+	e.bc.ComputeUnUsedTXOs()
+	return block
+}
@@ -0,0 +1,213 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/thedhejavu/ev-blockchain-protocol/database"
+)
+
+var (
+	ErrInvalidTxProof  = errors.New("Invalid transaction proof")
+	ErrSingleValidator = errors.New("Light chains follow a single validator")
+
+	lastHeaderKey = []byte("lhh")
+	headerPrefix  = []byte("header-")
+)
+
+// ChainSource is a full node a light chain follows
+type ChainSource interface {
+	// GetHeaders returns the block headers from the given height
+	GetHeaders(height int) ([]BlockHeader, error)
+
+	// GetTxProof returns a transaction with its merkle inclusion proof
+	GetTxProof(txId []byte) (TxProof, error)
+}
+
+// LightChain follows the chain of a full node by only storing block headers.
+// Headers are accepted once they link to the last header and carry the
+// signature of the validator, transactions are fetched on demand and checked
+// against the stored headers with their merkle proof.
+//
+// Blocks only carry the signature of the node adding them, validators do not
+// collect each other's signatures yet. A light chain therefore follows a
+// single validator, with several of them no header would reach the quorum.
+type LightChain struct {
+	crud        *Crud
+	source      ChainSource
+	validators  [][]byte
+	genesisHash []byte
+	mutex       sync.Mutex
+}
+
+// NewLightChain creates a light chain following the source and the blocks
+// signed by the validator. The genesis hash is trusted as is, when empty the
+// first header received is trusted.
+func NewLightChain(s database.Store, source ChainSource, validators [][]byte, genesisHash []byte) (*LightChain, error) {
+	if len(validators) != 1 {
+		return nil, fmt.Errorf("%w: %d validators", ErrSingleValidator, len(validators))
+	}
+	return &LightChain{
+		crud:        NewCrud(s),
+		source:      source,
+		validators:  validators,
+		genesisHash: genesisHash,
+	}, nil
+}
+
+// LastHeader returns the header of the last synced block
+func (lc *LightChain) LastHeader() (BlockHeader, error) {
+	lastHash, err := lc.crud.ps.Get(lastHeaderKey)
+	if err != nil {
+		return BlockHeader{}, err
+	}
+	return lc.GetHeader(lastHash)
+}
+
+// GetHeader returns the stored header of the block with the given hash
+func (lc *LightChain) GetHeader(hash []byte) (BlockHeader, error) {
+	data, err := lc.crud.ps.Get(append(append([]byte{}, headerPrefix...), hash...))
+	if err != nil {
+		return BlockHeader{}, err
+	}
+	return *DeSerializeHeader(data), nil
+}
+
+// GetHeaders returns the stored headers from the given height up to the last
+// synced block, in ascending order
+func (lc *LightChain) GetHeaders(height int) ([]BlockHeader, error) {
+	var headers []BlockHeader
+
+	header, err := lc.LastHeader()
+	if err != nil {
+		return headers, err
+	}
+	for header.Height >= height {
+		headers = append(headers, header)
+		if len(header.PrevHash) == 0 {
+			break
+		}
+		header, err = lc.GetHeader(header.PrevHash)
+		if err != nil {
+			return headers, err
+		}
+	}
+
+	reverseHeaders(headers)
+	return headers, nil
+}
+
+// AddHeader verifies the header extends the last synced block and stores it
+func (lc *LightChain) AddHeader(header BlockHeader) error {
+	if header.VerifyHash() == false {
+		return fmt.Errorf("%w: hash of block %d does not match its content", ErrInvalidBlockHeader, header.Height)
+	}
+
+	last, err := lc.LastHeader()
+	if err != nil {
+		// First header of the chain
+		if len(header.PrevHash) != 0 {
+			return fmt.Errorf("%w: block %d is not a genesis block", ErrInvalidBlockHeader, header.Height)
+		}
+		if len(lc.genesisHash) != 0 && bytes.Compare(lc.genesisHash, header.Hash) != 0 {
+			return fmt.Errorf("%w: unexpected genesis block %x", ErrInvalidBlockHeader, header.Hash)
+		}
+	} else {
+		if bytes.Compare(header.PrevHash, last.Hash) != 0 || header.Height != last.Height+1 {
+			return fmt.Errorf("%w: block %d does not link to block %d", ErrInvalidBlockHeader, header.Height, last.Height)
+		}
+		if header.Version < last.Version {
+			return fmt.Errorf("%w: block %d downgrades version %d to %d", ErrInvalidBlockHeader, header.Height, last.Version, header.Version)
+		}
+		if err := header.VerifySignatures(lc.validators); err != nil {
+			return err
+		}
+	}
+
+	err = lc.crud.Save(append(append([]byte{}, headerPrefix...), header.Hash...), header.Serialize())
+	if err != nil {
+		return err
+	}
+	return lc.crud.Save(lastHeaderKey, header.Hash)
+}
+
+// Sync fetches and verifies the headers added to the source since the last
+// synced block. It returns the number of headers added.
+func (lc *LightChain) Sync() (int, error) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	height := 0
+	if last, err := lc.LastHeader(); err == nil {
+		height = last.Height + 1
+	}
+	headers, err := lc.source.GetHeaders(height)
+	if err != nil {
+		return 0, err
+	}
+	for i, header := range headers {
+		if err := lc.AddHeader(header); err != nil {
+			return i, err
+		}
+	}
+	if len(headers) > 0 {
+		logger.Infof("Synced %d block headers", len(headers))
+	}
+	return len(headers), nil
+}
+
+// GetTxProof fetches a transaction from the source and checks its merkle
+// proof against the synced header of its block
+func (lc *LightChain) GetTxProof(txId []byte) (TxProof, error) {
+	txProof, err := lc.source.GetTxProof(txId)
+	if err != nil {
+		return TxProof{}, err
+	}
+
+	header, err := lc.GetHeader(txProof.Header.Hash)
+	if err != nil {
+		// The block may have been added since the last sync
+		if _, err = lc.Sync(); err != nil {
+			return TxProof{}, err
+		}
+		if header, err = lc.GetHeader(txProof.Header.Hash); err != nil {
+			return TxProof{}, fmt.Errorf("%w: block %x is not part of the chain", ErrInvalidTxProof, txProof.Header.Hash)
+		}
+	}
+	if header.VerifyTxProof(txProof.Leaf, txProof.Proof) == false {
+		return TxProof{}, fmt.Errorf("%w: merkle proof does not verify", ErrInvalidTxProof)
+	}
+
+	// Only trust the transaction committed by the merkle root
+	tx := DeserializeTransaction(txProof.Leaf)
+	if bytes.Compare(tx.ID, txId) != 0 {
+		return TxProof{}, fmt.Errorf("%w: proof is for transaction %x", ErrInvalidTxProof, tx.ID)
+	}
+
+	return TxProof{
+		Header: header,
+		Tx:     tx,
+		Leaf:   txProof.Leaf,
+		Proof:  txProof.Proof,
+	}, nil
+}
+
+// GetTransaction fetches a transaction from the source and checks it is part
+// of the chain
+func (lc *LightChain) GetTransaction(txId []byte) (Transaction, error) {
+	txProof, err := lc.GetTxProof(txId)
+	if err != nil {
+		return Transaction{}, err
+	}
+	return txProof.Tx, nil
+}
+
+// reverseHeaders reverses the headers collected from the last block
+func reverseHeaders(headers []BlockHeader) {
+	for i, j := 0, len(headers)-1; i < j; i, j = i+1, j-1 {
+		headers[i], headers[j] = headers[j], headers[i]
+	}
+}
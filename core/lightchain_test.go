@@ -0,0 +1,240 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+// tamperedSource hands out the proof of another transaction
+type tamperedSource struct {
+	*Blockchain
+	txId []byte
+}
+
+func (s tamperedSource) GetTxProof(txId []byte) (TxProof, error) {
+	return s.Blockchain.GetTxProof(s.txId)
+}
+
+// newSignedElection runs an election on a chain whose blocks are signed with
+// the node key
+func newSignedElection(t *testing.T) (*testElection, []byte) {
+	t.Helper()
+	bc := newTestChain(t)
+	signer, err := bc.NodeKeySigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.SetSigner(signer)
+
+	e := newChainElection(t, bc, 1, nil)
+	e.startAccreditation()
+	return e, signer.PublicKey()
+}
+
+func newLightChain(t *testing.T, source ChainSource, validator []byte, genesisHash []byte) *LightChain {
+	t.Helper()
+	lc, err := NewLightChain(newMemStore(), source, [][]byte{validator}, genesisHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lc
+}
+
+func TestNewLightChain(t *testing.T) {
+	e, nodeKey := newSignedElection(t)
+	_, otherKey := wallet.NewKeyPair()
+
+	for _, validators := range [][][]byte{nil, {nodeKey, otherKey}} {
+		if _, err := NewLightChain(newMemStore(), e.bc, validators, nil); errors.Is(err, ErrSingleValidator) == false {
+			t.Errorf("%d validators: expected %v, got %v", len(validators), ErrSingleValidator, err)
+		}
+	}
+}
+
+func TestLightChainSync(t *testing.T) {
+	e, nodeKey := newSignedElection(t)
+	_, otherKey := wallet.NewKeyPair()
+
+	headers, err := e.bc.GetHeaders(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := headers[0]
+
+	tests := []struct {
+		name        string
+		validator   []byte
+		genesisHash []byte
+		synced      int
+	}{
+		{"signed by the validator", nodeKey, genesis.Hash, len(headers)},
+		{"trust the first header", nodeKey, nil, len(headers)},
+		{"unexpected genesis", nodeKey, headers[1].Hash, 0},
+		{"signed by another node", otherKey, genesis.Hash, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lc := newLightChain(t, e.bc, test.validator, test.genesisHash)
+			synced, err := lc.Sync()
+			if synced != test.synced {
+				t.Fatalf("expected %d headers synced, got %d: %v", test.synced, synced, err)
+			}
+			if synced == len(headers) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := lc.GetTransaction(e.acOut.ID); err != nil {
+					t.Fatal(err)
+				}
+				stored, err := lc.GetHeaders(0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range stored {
+					if stored[i].Height != headers[i].Height {
+						t.Fatalf("expected header %d at position %d, got %d", headers[i].Height, i, stored[i].Height)
+					}
+				}
+			} else if errors.Is(err, ErrInvalidBlockHeader) == false {
+				t.Fatalf("expected %v, got %v", ErrInvalidBlockHeader, err)
+			}
+		})
+	}
+}
+
+func TestLightChainUnsignedHeaders(t *testing.T) {
+	e := newTestElection(t, 1, nil)
+	_, nodeKey := wallet.NewKeyPair()
+
+	lc := newLightChain(t, e.bc, nodeKey, nil)
+	synced, err := lc.Sync()
+	if synced != 1 || errors.Is(err, ErrInvalidBlockHeader) == false {
+		t.Fatalf("expected unsigned blocks to be rejected after the genesis block, synced %d: %v", synced, err)
+	}
+}
+
+func TestLightChainTamperedTxProof(t *testing.T) {
+	e, nodeKey := newSignedElection(t)
+
+	source := tamperedSource{e.bc, e.election.ID}
+	lc := newLightChain(t, source, nodeKey, nil)
+	if _, err := lc.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lc.GetTransaction(e.acOut.ID); errors.Is(err, ErrInvalidTxProof) == false {
+		t.Fatalf("expected %v, got %v", ErrInvalidTxProof, err)
+	}
+}
+
+// A signed header claiming the first version would switch light nodes to the
+// legacy merkle hashing
+func TestLightChainVersionDowngrade(t *testing.T) {
+	e, nodeKey := newSignedElection(t)
+	headers, err := e.bc.GetHeaders(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc := newLightChain(t, e.bc, nodeKey, nil)
+	last := len(headers) - 1
+	for _, header := range headers[:last] {
+		if err := lc.AddHeader(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	downgraded := headers[last]
+	downgraded.Version = 1
+	if downgraded.VerifyHash() {
+		t.Fatal("expected the block hash to commit to the version")
+	}
+	if err := lc.AddHeader(downgraded); errors.Is(err, ErrInvalidBlockHeader) == false {
+		t.Fatalf("expected %v, got %v", ErrInvalidBlockHeader, err)
+	}
+	if err := lc.AddHeader(headers[last]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A signed header moved to another height would get a second block signed on
+// the same parent past the signer watermark
+func TestVerifyHashCommitsHeader(t *testing.T) {
+	header := NewBlock([]*Transaction{{ID: []byte("tx")}}, Version, []byte("prev"), 2).Header()
+	if header.VerifyHash() == false {
+		t.Fatal("expected the hash to match the header")
+	}
+
+	tests := []struct {
+		name   string
+		change func(header *BlockHeader)
+	}{
+		{"height", func(header *BlockHeader) { header.Height++ }},
+		{"timestamp", func(header *BlockHeader) { header.Timestamp++ }},
+		{"version", func(header *BlockHeader) { header.Version++ }},
+		{"merkle root", func(header *BlockHeader) { header.MerkleRoot = []byte("root") }},
+		{"previous block", func(header *BlockHeader) { header.PrevHash = []byte("other") }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := header
+			test.change(&changed)
+			if changed.VerifyHash() {
+				t.Fatalf("expected the hash to commit to the %s", test.name)
+			}
+		})
+	}
+}
+
+func TestVerifySignatures(t *testing.T) {
+	block := NewBlock([]*Transaction{{ID: []byte("tx")}}, Version, []byte("prev"), 2)
+	var privKeys []*ecdsa.PrivateKey
+	var validators [][]byte
+	for i := 0; i < 3; i++ {
+		priv, pub := wallet.NewKeyPair()
+		privKeys = append(privKeys, priv)
+		validators = append(validators, pub)
+	}
+
+	sign := func(signers ...int) BlockHeader {
+		signed := *block
+		for _, i := range signers {
+			signed.Sign(*privKeys[i], validators[i])
+		}
+		return signed.Header()
+	}
+
+	tests := []struct {
+		name   string
+		header BlockHeader
+		valid  bool
+	}{
+		{"quorum", sign(0, 1), true},
+		{"every validator", sign(0, 1, 2), true},
+		{"unsigned", sign(), false},
+		{"no quorum", sign(2), false},
+		{"validator signing twice", sign(1, 1), false},
+		{"forged signature", func() BlockHeader {
+			header := sign(0, 1)
+			header.SigWitnesses[1] = header.SigWitnesses[0]
+			return header
+		}(), false},
+		{"missing signature witness", func() BlockHeader {
+			header := sign(0, 1)
+			header.SigWitnesses = header.SigWitnesses[:1]
+			return header
+		}(), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.header.VerifySignatures(validators)
+			if test.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !test.valid && errors.Is(err, ErrInvalidBlockHeader) == false {
+				t.Fatalf("expected %v, got %v", ErrInvalidBlockHeader, err)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package blockchain
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"errors"
 
 	logger "github.com/sirupsen/logrus"
 )
@@ -9,6 +11,7 @@ import (
 type (
 	MerkleTree struct {
 		RootNode *MerkleNode
+		Leaves   int
 	}
 
 	MerkleNode struct {
@@ -16,19 +19,39 @@ type (
 		Left  *MerkleNode
 		Data  []byte
 	}
+
+	// MerkleProof is the path proving a leaf is part of a merkle tree. Index is
+	// the position of the leaf and Siblings the hashes needed to rebuild the
+	// root, starting from the leaf.
+	MerkleProof struct {
+		Index    int      `json:"index"`
+		Siblings [][]byte `json:"siblings"`
+	}
+)
+
+// Leaves and interior nodes are hashed with different prefixes, an interior
+// node can't be passed off as a leaf
+const MERKLE_LEAF_PREFIX = 0x00
+const MERKLE_NODE_PREFIX = 0x01
+
+var (
+	ErrInvalidProofIndex = errors.New("Merkle proof index out of range")
 )
 
 // NewMerkleNode Instantiate a new merklee tree node
 func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
+	return newMerkleNode(left, right, data, false)
+}
+
+// newMerkleNode hashes a leaf or an interior node. Legacy nodes, used by the
+// blocks of the first version, are hashed without prefix.
+func newMerkleNode(left, right *MerkleNode, data []byte, legacy bool) *MerkleNode {
 	node := MerkleNode{}
 
 	if left == nil && right == nil {
-		hash := sha256.Sum256(data)
-		node.Data = hash[:]
+		node.Data = hashLeaf(data, legacy)
 	} else {
-		prevHashes := append(left.Data, right.Data...)
-		hash := sha256.Sum256(prevHashes)
-		node.Data = hash[:]
+		node.Data = hashNode(left.Data, right.Data, legacy)
 	}
 
 	node.Left = left
@@ -36,13 +59,36 @@ func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
 	return &node
 }
 
+func hashLeaf(data []byte, legacy bool) []byte {
+	if legacy == false {
+		data = append([]byte{MERKLE_LEAF_PREFIX}, data...)
+	}
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+func hashNode(left, right []byte, legacy bool) []byte {
+	var data []byte
+	if legacy == false {
+		data = append(data, MERKLE_NODE_PREFIX)
+	}
+	data = append(append(data, left...), right...)
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
 // NewMerkleTree Creates a Binary Hash Tree
 func NewMerkleTree(data [][]byte) *MerkleTree {
+	return newMerkleTree(data, false)
+}
+
+func newMerkleTree(data [][]byte, legacy bool) *MerkleTree {
 
 	var nodes []MerkleNode
+	leaves := len(data)
 
 	for _, d := range data {
-		node := NewMerkleNode(nil, nil, d)
+		node := newMerkleNode(nil, nil, d, legacy)
 		nodes = append(nodes, *node)
 	}
 
@@ -60,14 +106,65 @@ func NewMerkleTree(data [][]byte) *MerkleTree {
 
 		var level []MerkleNode
 		for i := 0; i < len(nodes); i += 2 {
-			node := NewMerkleNode(&nodes[i], &nodes[i+1], nil)
+			node := newMerkleNode(&nodes[i], &nodes[i+1], nil, legacy)
 			level = append(level, *node)
 		}
 
 		nodes = level
 	}
 
-	tree := MerkleTree{&nodes[0]}
+	tree := MerkleTree{&nodes[0], leaves}
 
 	return &tree
 }
+
+// Proof returns the merkle path of the leaf at the given index
+func (tree *MerkleTree) Proof(index int) (MerkleProof, error) {
+	if index < 0 || index >= tree.Leaves {
+		return MerkleProof{}, ErrInvalidProofIndex
+	}
+
+	// All leaves of the tree sit at the same depth, walk down from the root
+	// following the bits of the index and collect the sibling of each node
+	depth := 0
+	for node := tree.RootNode; node.Left != nil; node = node.Left {
+		depth++
+	}
+	siblings := make([][]byte, depth)
+	node := tree.RootNode
+	for level := depth - 1; level >= 0; level-- {
+		if (index>>uint(level))&1 == 0 {
+			siblings[level] = node.Right.Data
+			node = node.Left
+		} else {
+			siblings[level] = node.Left.Data
+			node = node.Right
+		}
+	}
+
+	return MerkleProof{index, siblings}, nil
+}
+
+// VerifyProof checks that the leaf data is part of the merkle tree with the
+// given root
+func VerifyProof(root, leaf []byte, proof MerkleProof) bool {
+	return verifyProof(root, leaf, proof, false)
+}
+
+func verifyProof(root, leaf []byte, proof MerkleProof, legacy bool) bool {
+	if proof.Index < 0 {
+		return false
+	}
+	current := hashLeaf(leaf, legacy)
+	index := proof.Index
+
+	for _, sibling := range proof.Siblings {
+		if index&1 == 0 {
+			current = hashNode(current, sibling, legacy)
+		} else {
+			current = hashNode(sibling, current, legacy)
+		}
+		index >>= 1
+	}
+	return index == 0 && bytes.Compare(current, root) == 0
+}
@@ -0,0 +1,129 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func merkleLeaves(n int) [][]byte {
+	var data [][]byte
+	for i := 0; i < n; i++ {
+		data = append(data, []byte(fmt.Sprintf("leaf %d", i)))
+	}
+	return data
+}
+
+func TestMerkleProof(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		for n := 1; n <= 9; n++ {
+			data := merkleLeaves(n)
+			tree := newMerkleTree(data, legacy)
+			for i, leaf := range data {
+				proof, err := tree.Proof(i)
+				if err != nil {
+					t.Fatalf("legacy %v, %d leaves: proof of leaf %d: %v", legacy, n, i, err)
+				}
+				if verifyProof(tree.RootNode.Data, leaf, proof, legacy) == false {
+					t.Errorf("legacy %v, %d leaves: proof of leaf %d does not verify", legacy, n, i)
+				}
+				if verifyProof(tree.RootNode.Data, leaf, proof, !legacy) {
+					t.Errorf("legacy %v, %d leaves: proof of leaf %d verifies with the other hashing", legacy, n, i)
+				}
+			}
+			if _, err := tree.Proof(n); err != ErrInvalidProofIndex {
+				t.Errorf("%d leaves: expected %v for an index out of range, got %v", n, ErrInvalidProofIndex, err)
+			}
+		}
+	}
+}
+
+func TestMerkleProofInvalid(t *testing.T) {
+	data := merkleLeaves(5)
+	tree := NewMerkleTree(data)
+	root := tree.RootNode.Data
+	valid, err := tree.Proof(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := MerkleProof{valid.Index, append([][]byte{}, valid.Siblings...)}
+	tampered.Siblings[1] = bytes.Repeat([]byte{0xff}, sha256.Size)
+
+	tests := []struct {
+		name  string
+		leaf  []byte
+		proof MerkleProof
+	}{
+		{"wrong leaf", data[3], valid},
+		{"wrong index", data[2], MerkleProof{3, valid.Siblings}},
+		{"negative index", data[2], MerkleProof{-1, valid.Siblings}},
+		{"index past the proof depth", data[2], MerkleProof{valid.Index + 8, valid.Siblings}},
+		{"tampered sibling", data[2], tampered},
+		{"missing sibling", data[2], MerkleProof{valid.Index, valid.Siblings[:2]}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if VerifyProof(root, test.leaf, test.proof) {
+				t.Error("invalid proof verifies")
+			}
+		})
+	}
+}
+
+// An interior node is made of two child hashes, passing their concatenation
+// off as a leaf must not give back the root
+func TestMerkleProofInteriorNodeAsLeaf(t *testing.T) {
+	data := merkleLeaves(4)
+
+	tests := []struct {
+		legacy   bool
+		verifies bool
+	}{
+		{legacy: true, verifies: true},
+		{legacy: false, verifies: false},
+	}
+	for _, test := range tests {
+		tree := newMerkleTree(data, test.legacy)
+		left := tree.RootNode.Left
+		leaf := append(append([]byte{}, left.Left.Data...), left.Right.Data...)
+		proof := MerkleProof{0, [][]byte{tree.RootNode.Right.Data}}
+
+		if verifyProof(tree.RootNode.Data, leaf, proof, test.legacy) != test.verifies {
+			t.Errorf("legacy %v: expected the forged leaf to verify %v", test.legacy, test.verifies)
+		}
+	}
+}
+
+func TestBlockTxProof(t *testing.T) {
+	e := newTestElection(t, 1, nil)
+	e.startAccreditation()
+
+	tests := []struct {
+		name    string
+		version int
+	}{
+		{"first version", 1},
+		{"prefixed merkle tree", MERKLE_PREFIX_VERSION},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block := NewBlock([]*Transaction{e.election, e.acOut}, test.version, []byte("prev"), 2)
+			proof, err := block.TxProof(e.acOut.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := block.Header()
+			if header.VerifyTxProof(e.acOut.Serialize(), proof) == false {
+				t.Error("transaction proof does not verify")
+			}
+			if header.VerifyTxProof(e.election.Serialize(), proof) {
+				t.Error("proof verifies for another transaction")
+			}
+			if _, err := block.TxProof([]byte("missing")); err != ErrTxNotInBlock {
+				t.Errorf("expected %v, got %v", ErrTxNotInBlock, err)
+			}
+		})
+	}
+}
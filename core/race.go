@@ -0,0 +1,302 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/blindsig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/identity"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+// Ballot formats supported by a race
+const PLURALITY_BALLOT = "plurality"
+const APPROVAL_BALLOT = "approval"
+const PICK_K_BALLOT = "pick_k"
+const RANKED_BALLOT = "ranked"
+
+// DEFAULT_RACE_ID identifies the single race of elections created without races
+const DEFAULT_RACE_ID = "default"
+
+// Types of race, candidate races elect candidates while question races put a
+// referendum question to the voters
+const CANDIDATE_RACE = "candidate"
+const QUESTION_RACE = "question"
+
+// Thresholds a referendum question must reach to pass
+const SIMPLE_MAJORITY = "simple_majority"
+const TWO_THIRDS_MAJORITY = "two_thirds"
+
+var (
+	BallotFormats = []string{
+		PLURALITY_BALLOT,
+		APPROVAL_BALLOT,
+		PICK_K_BALLOT,
+		RANKED_BALLOT,
+	}
+	ErrInvalidBallotFormat = errors.New("Invalid ballot format")
+	ErrInvalidBallot       = errors.New("Invalid ballot choices")
+	ErrInvalidRace         = errors.New("Invalid election race")
+
+	// Fixed options of a referendum question
+	YesOption       = []byte("yes")
+	NoOption        = []byte("no")
+	AbstainOption   = []byte("abstain")
+	QuestionOptions = [][]byte{YesOption, NoOption, AbstainOption}
+)
+
+// Race is a single contest of an election with its own candidates and rules
+type Race struct {
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	Candidates   [][]byte `json:"candidates"`
+	BallotFormat string   `json:"ballot_format"`
+	MaxChoices   int64    `json:"max_choices"` // Number of candidates a voter may pick in pick-k races
+	Type         string   `json:"type"`
+	Threshold    string   `json:"threshold"`   // Majority a question needs to pass
+	MinTurnout   int64    `json:"min_turnout"` // Percentage of the election TotalPeople that must vote on a question
+}
+
+// RaceSelection holds the choices of a ballot for one race, in order of
+// preference for ranked races
+type RaceSelection struct {
+	RaceID  string   `json:"race_id"`
+	Choices [][]byte `json:"choices"`
+}
+
+// GetRaces returns the races of the election. Elections created without races
+// hold a single race made of the election candidates.
+func (tx *TxElectionOutput) GetRaces() []Race {
+	if len(tx.Races) > 0 {
+		races := make([]Race, len(tx.Races))
+		for i, race := range tx.Races {
+			// Question races always offer the fixed referendum options
+			if race.GetType() == QUESTION_RACE && len(race.Candidates) == 0 {
+				race.Candidates = QuestionOptions
+			}
+			races[i] = race
+		}
+		return races
+	}
+	return []Race{
+		{
+			ID:           DEFAULT_RACE_ID,
+			Title:        tx.Title,
+			Candidates:   tx.Candidates,
+			BallotFormat: tx.BallotFormat,
+			MaxChoices:   tx.MaxChoices,
+		},
+	}
+}
+
+// GetBallotFormat returns the ballot format of the election, elections
+// created without a format are plurality elections
+func (tx *TxElectionOutput) GetBallotFormat() string {
+	if tx.BallotFormat == "" {
+		return PLURALITY_BALLOT
+	}
+	return tx.BallotFormat
+}
+
+// ValidateFormat checks the races of the election and their ballot formats
+func (tx *TxElectionOutput) ValidateFormat() error {
+	seen := make(map[string]bool)
+	for _, race := range tx.GetRaces() {
+		if race.ID == "" {
+			return fmt.Errorf("%w: missing race ID", ErrInvalidRace)
+		}
+		if seen[race.ID] {
+			return fmt.Errorf("%w: race %s defined twice", ErrInvalidRace, race.ID)
+		}
+		seen[race.ID] = true
+
+		if err := race.ValidateFormat(); err != nil {
+			return err
+		}
+	}
+	if len(tx.CACertificate) > 0 {
+		if _, err := identity.ParseCA(tx.CACertificate); err != nil {
+			return err
+		}
+	}
+	if len(tx.BlindKey) > 0 {
+		if _, err := blindsig.ParsePublicKey(tx.BlindKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateBallot checks that every race selection on the ballot follows the
+// rules of its race. Races left out of the ballot are treated as abstentions.
+func (tx *TxElectionOutput) ValidateBallot(ballot TxBallotInput) error {
+	selections := ballot.RaceSelections()
+	if len(selections) == 0 {
+		return fmt.Errorf("%w: no candidate selected", ErrInvalidBallot)
+	}
+
+	races := make(map[string]Race)
+	for _, race := range tx.GetRaces() {
+		races[race.ID] = race
+	}
+
+	seen := make(map[string]bool)
+	for _, selection := range selections {
+		race, ok := races[selection.RaceID]
+		if !ok {
+			return fmt.Errorf("%w: unknown race %s", ErrInvalidBallot, selection.RaceID)
+		}
+		if seen[selection.RaceID] {
+			return fmt.Errorf("%w: race %s selected twice", ErrInvalidBallot, selection.RaceID)
+		}
+		seen[selection.RaceID] = true
+
+		if err := race.ValidateChoices(selection.Choices); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBallotFormat returns the ballot format of the race, races created
+// without a format are plurality races
+func (r *Race) GetBallotFormat() string {
+	if r.BallotFormat == "" {
+		return PLURALITY_BALLOT
+	}
+	return r.BallotFormat
+}
+
+// GetType returns the type of the race, races created without a type are
+// candidate races
+func (r *Race) GetType() string {
+	if r.Type == "" {
+		return CANDIDATE_RACE
+	}
+	return r.Type
+}
+
+// GetThreshold returns the majority a question needs to pass, questions
+// created without a threshold pass with a simple majority
+func (r *Race) GetThreshold() string {
+	if r.Threshold == "" {
+		return SIMPLE_MAJORITY
+	}
+	return r.Threshold
+}
+
+// ValidateFormat checks the ballot format of the race and its options
+func (r *Race) ValidateFormat() error {
+	if len(r.Candidates) == 0 {
+		return fmt.Errorf("%w: race %s has no candidates", ErrInvalidRace, r.ID)
+	}
+	seen := make(map[string]bool)
+	for _, candidate := range r.Candidates {
+		if seen[keyID(candidate)] {
+			return fmt.Errorf("%w: candidate %x appears twice in race %s", ErrInvalidRace, candidate, r.ID)
+		}
+		seen[keyID(candidate)] = true
+	}
+
+	switch r.GetType() {
+	case CANDIDATE_RACE:
+	case QUESTION_RACE:
+		if r.GetBallotFormat() != PLURALITY_BALLOT {
+			return fmt.Errorf("%w: question %s only accepts plurality ballots", ErrInvalidRace, r.ID)
+		}
+		if len(r.Candidates) != len(QuestionOptions) {
+			return fmt.Errorf("%w: question %s must offer yes, no and abstain", ErrInvalidRace, r.ID)
+		}
+		for i, option := range QuestionOptions {
+			if bytes.Compare(r.Candidates[i], option) != 0 {
+				return fmt.Errorf("%w: question %s must offer yes, no and abstain", ErrInvalidRace, r.ID)
+			}
+		}
+		if r.GetThreshold() != SIMPLE_MAJORITY && r.GetThreshold() != TWO_THIRDS_MAJORITY {
+			return fmt.Errorf("%w: unknown threshold %s for question %s", ErrInvalidRace, r.Threshold, r.ID)
+		}
+		if r.MinTurnout < 0 || r.MinTurnout > 100 {
+			return fmt.Errorf("%w: minimum turnout of question %s must be a percentage", ErrInvalidRace, r.ID)
+		}
+	default:
+		return fmt.Errorf("%w: unknown race type %s", ErrInvalidRace, r.Type)
+	}
+
+	switch r.GetBallotFormat() {
+	case PLURALITY_BALLOT, APPROVAL_BALLOT, RANKED_BALLOT:
+		return nil
+	case PICK_K_BALLOT:
+		if r.MaxChoices < 1 || r.MaxChoices > int64(len(r.Candidates)) {
+			return fmt.Errorf("%w: pick-k race %s must allow between 1 and %d choices", ErrInvalidBallotFormat, r.ID, len(r.Candidates))
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidBallotFormat, r.BallotFormat)
+}
+
+// ValidateChoices checks that the choices for the race follow its ballot format
+func (r *Race) ValidateChoices(choices [][]byte) error {
+	if len(choices) == 0 {
+		return fmt.Errorf("%w: no candidate selected in race %s", ErrInvalidBallot, r.ID)
+	}
+
+	seen := make(map[string]bool)
+	for _, choice := range choices {
+		candidate := r.candidate(choice)
+		if candidate == nil {
+			return fmt.Errorf("%w: unknown candidate %x in race %s", ErrInvalidBallot, choice, r.ID)
+		}
+		if seen[string(candidate)] {
+			return fmt.Errorf("%w: candidate %x selected twice in race %s", ErrInvalidBallot, choice, r.ID)
+		}
+		seen[string(candidate)] = true
+	}
+
+	switch r.GetBallotFormat() {
+	case PLURALITY_BALLOT:
+		if len(choices) != 1 {
+			return fmt.Errorf("%w: plurality race %s selects exactly one candidate", ErrInvalidBallot, r.ID)
+		}
+	case PICK_K_BALLOT:
+		if int64(len(choices)) > r.MaxChoices {
+			return fmt.Errorf("%w: at most %d candidates may be selected in race %s", ErrInvalidBallot, r.MaxChoices, r.ID)
+		}
+	}
+	return nil
+}
+
+// candidate returns the candidate of the race matching the choice, as it is
+// listed by the election, or nil if the choice is not a candidate
+func (r *Race) candidate(choice []byte) []byte {
+	for _, v := range r.Candidates {
+		if keys.Equal(v, choice) {
+			return v
+		}
+	}
+	return nil
+}
+
+// listedChoices returns the choices with the candidate encoding listed by the
+// election, unknown choices are kept as they are
+func (r *Race) listedChoices(choices [][]byte) [][]byte {
+	listed := make([][]byte, len(choices))
+	for i, choice := range choices {
+		if listed[i] = r.candidate(choice); listed[i] == nil {
+			listed[i] = choice
+		}
+	}
+	return listed
+}
+
+// RaceSelections returns the choices of the ballot for each race. Ballots for
+// elections without races select candidates of the default race.
+func (tx *TxBallotInput) RaceSelections() []RaceSelection {
+	if len(tx.Races) > 0 {
+		return tx.Races
+	}
+	if choices := tx.Selections(); len(choices) > 0 {
+		return []RaceSelection{{RaceID: DEFAULT_RACE_ID, Choices: choices}}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestElectionValidateFormat(t *testing.T) {
+	candidates := [][]byte{candidateA, candidateB, candidateC}
+	tests := []struct {
+		name     string
+		election TxElectionOutput
+		err      error
+	}{
+		{"plurality", TxElectionOutput{Candidates: candidates}, nil},
+		{"ranked", TxElectionOutput{Candidates: candidates, BallotFormat: RANKED_BALLOT}, nil},
+		{"pick-k", TxElectionOutput{Candidates: candidates, BallotFormat: PICK_K_BALLOT, MaxChoices: 2}, nil},
+		{"pick-k without choices", TxElectionOutput{Candidates: candidates, BallotFormat: PICK_K_BALLOT}, ErrInvalidBallotFormat},
+		{"pick-k above candidates", TxElectionOutput{Candidates: candidates, BallotFormat: PICK_K_BALLOT, MaxChoices: 4}, ErrInvalidBallotFormat},
+		{"unknown format", TxElectionOutput{Candidates: candidates, BallotFormat: "borda"}, ErrInvalidBallotFormat},
+		{"no candidates", TxElectionOutput{}, ErrInvalidRace},
+		{"duplicate candidates", TxElectionOutput{Candidates: [][]byte{candidateA, candidateB, candidateA}}, ErrInvalidRace},
+		{
+			name: "races",
+			election: TxElectionOutput{Races: []Race{
+				{ID: "mayor", Candidates: candidates},
+				{ID: "council", Candidates: candidates, BallotFormat: APPROVAL_BALLOT},
+			}},
+		},
+		{
+			name:     "race without candidates",
+			election: TxElectionOutput{Races: []Race{{ID: "mayor", Candidates: candidates}, {ID: "council"}}},
+			err:      ErrInvalidRace,
+		},
+		{
+			name: "race with duplicate candidates",
+			election: TxElectionOutput{Races: []Race{
+				{ID: "mayor", Candidates: [][]byte{candidateA, candidateA}},
+			}},
+			err: ErrInvalidRace,
+		},
+		{
+			name:     "race defined twice",
+			election: TxElectionOutput{Races: []Race{{ID: "mayor", Candidates: candidates}, {ID: "mayor", Candidates: candidates}}},
+			err:      ErrInvalidRace,
+		},
+		{
+			name:     "question",
+			election: TxElectionOutput{Races: []Race{{ID: "q", Type: QUESTION_RACE, Threshold: TWO_THIRDS_MAJORITY, MinTurnout: 40}}},
+		},
+		{
+			name:     "question with candidates",
+			election: TxElectionOutput{Races: []Race{{ID: "q", Type: QUESTION_RACE, Candidates: candidates}}},
+			err:      ErrInvalidRace,
+		},
+		{
+			name:     "question with ranked ballots",
+			election: TxElectionOutput{Races: []Race{{ID: "q", Type: QUESTION_RACE, BallotFormat: RANKED_BALLOT}}},
+			err:      ErrInvalidRace,
+		},
+		{
+			name:     "question with unknown threshold",
+			election: TxElectionOutput{Races: []Race{{ID: "q", Type: QUESTION_RACE, Threshold: "unanimous"}}},
+			err:      ErrInvalidRace,
+		},
+		{
+			name:     "question turnout above 100",
+			election: TxElectionOutput{Races: []Race{{ID: "q", Type: QUESTION_RACE, MinTurnout: 101}}},
+			err:      ErrInvalidRace,
+		},
+		{
+			name:     "unknown race type",
+			election: TxElectionOutput{Races: []Race{{ID: "q", Type: "poll", Candidates: candidates}}},
+			err:      ErrInvalidRace,
+		},
+		{
+			name:     "race without ID",
+			election: TxElectionOutput{Races: []Race{{Candidates: candidates}}},
+			err:      ErrInvalidRace,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.election.ValidateFormat()
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateBallot(t *testing.T) {
+	election := TxElectionOutput{Races: []Race{
+		{ID: "mayor", Candidates: [][]byte{candidateA, candidateB}},
+		{ID: "council", Candidates: [][]byte{candidateA, candidateB, candidateC}, BallotFormat: PICK_K_BALLOT, MaxChoices: 2},
+		{ID: "budget", Candidates: [][]byte{candidateA, candidateB, candidateC}, BallotFormat: RANKED_BALLOT},
+	}}
+	tests := []struct {
+		name  string
+		races []RaceSelection
+		err   error
+	}{
+		{
+			name: "every race",
+			races: []RaceSelection{
+				{"mayor", [][]byte{candidateA}},
+				{"council", [][]byte{candidateB, candidateC}},
+				{"budget", [][]byte{candidateC, candidateA, candidateB}},
+			},
+		},
+		{"abstains from races", []RaceSelection{{"council", [][]byte{candidateA}}}, nil},
+		{"unknown race", []RaceSelection{{"governor", [][]byte{candidateA}}}, ErrInvalidBallot},
+		{"race selected twice", []RaceSelection{{"mayor", [][]byte{candidateA}}, {"mayor", [][]byte{candidateB}}}, ErrInvalidBallot},
+		{"two plurality choices", []RaceSelection{{"mayor", [][]byte{candidateA, candidateB}}}, ErrInvalidBallot},
+		{"too many choices", []RaceSelection{{"council", [][]byte{candidateA, candidateB, candidateC}}}, ErrInvalidBallot},
+		{"candidate of another race", []RaceSelection{{"mayor", [][]byte{candidateC}}}, ErrInvalidBallot},
+		{"ranked twice", []RaceSelection{{"budget", [][]byte{candidateA, candidateA}}}, ErrInvalidBallot},
+		{"empty race", []RaceSelection{{"mayor", nil}}, ErrInvalidBallot},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := election.ValidateBallot(TxBallotInput{Races: test.races})
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestTallyRaces(t *testing.T) {
+	election := TxElectionOutput{Races: []Race{
+		{ID: "mayor", Candidates: [][]byte{candidateA, candidateB}},
+		{ID: "council", Candidates: [][]byte{candidateA, candidateB, candidateC}, BallotFormat: APPROVAL_BALLOT},
+	}}
+	results := tallyBallots(election, []TxBallotInput{
+		{Races: []RaceSelection{{"mayor", [][]byte{candidateA}}, {"council", [][]byte{candidateA, candidateC}}}},
+		{Races: []RaceSelection{{"mayor", [][]byte{candidateB}}}},
+		{Races: []RaceSelection{{"mayor", [][]byte{candidateA}}, {"council", [][]byte{candidateC}}}},
+		{Races: []RaceSelection{{"mayor", [][]byte{candidateC}}}}, // Not a candidate of the race
+	})
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 races, got %d", len(results))
+	}
+	mayor, council := results["mayor"], results["council"]
+	if mayor.Ballots != 3 || votes(mayor.Tally, candidateA) != 2 || votes(mayor.Tally, candidateB) != 1 {
+		t.Fatalf("unexpected mayor result %+v", mayor)
+	}
+	if council.Ballots != 2 || votes(council.Tally, candidateA) != 1 || votes(council.Tally, candidateC) != 2 {
+		t.Fatalf("unexpected council result %+v", council)
+	}
+	if council.BallotFormat != APPROVAL_BALLOT {
+		t.Fatalf("expected an approval race, got %s", council.BallotFormat)
+	}
+}
+
+func TestCandidateKeyEncodings(t *testing.T) {
+	candidates := newTestKeys(t, 2)
+	other := uncompressed(t, candidates)
+	election := TxElectionOutput{Candidates: candidates, BallotFormat: APPROVAL_BALLOT}
+
+	tests := []struct {
+		name    string
+		choices [][]byte
+		err     error
+	}{
+		{"listed encoding", [][]byte{candidates[0]}, nil},
+		{"other encoding", [][]byte{other[0], candidates[1]}, nil},
+		{"selected twice", [][]byte{candidates[0], other[0]}, ErrInvalidBallot},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := election.ValidateBallot(TxBallotInput{Choices: test.choices})
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+
+	duplicate := TxElectionOutput{Candidates: [][]byte{candidates[0], other[0]}}
+	if err := duplicate.ValidateFormat(); errors.Is(err, ErrInvalidRace) == false {
+		t.Errorf("expected %v, got %v", ErrInvalidRace, err)
+	}
+
+	ballots := []TxBallotInput{{Choices: [][]byte{candidates[0]}}, {Choices: [][]byte{other[0]}}}
+	result := tallyBallots(election, ballots)[DEFAULT_RACE_ID]
+	if got := votes(result.Tally, candidates[0]); got != 2 {
+		t.Errorf("expected 2 votes whatever the encoding, got %d", got)
+	}
+}
@@ -0,0 +1,121 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+)
+
+var (
+	ErrInvalidReceipt = errors.New("Invalid ballot receipt")
+)
+
+// Receipt is handed to a voter once their ballot is recorded. It is signed by
+// the node and proves the ballot transaction is part of a block without
+// revealing the choices of the voter.
+type Receipt struct {
+	TxID           []byte      `json:"tx_id"`
+	ElectionPubKey []byte      `json:"election_pubkey"`
+	BlockHash      []byte      `json:"block_hash"`
+	Height         int         `json:"height"`
+	MerkleRoot     []byte      `json:"merkle_root"`
+	Proof          MerkleProof `json:"proof"`
+	NodePubKey     []byte      `json:"node_pubkey"`
+	Signature      []byte      `json:"signature"`
+}
+
+// NewReceipt builds the unsigned receipt of a transaction of the block
+func NewReceipt(block Block, txId []byte) (*Receipt, error) {
+	proof, err := block.TxProof(txId)
+	if err != nil {
+		return nil, err
+	}
+	return &Receipt{
+		TxID:           txId,
+		ElectionPubKey: block.Transactions[proof.Index].ElectionPubkey,
+		BlockHash:      block.Hash,
+		Height:         block.Height,
+		MerkleRoot:     block.MerkleRoot,
+		Proof:          proof,
+	}, nil
+}
+
+// Hash returns the hash of the receipt fields covered by the node signature
+func (r *Receipt) Hash() []byte {
+	receiptCopy := *r
+	receiptCopy.Signature = nil
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%x", receiptCopy)))
+	return hash[:]
+}
+
+// Sign signs the receipt with the node key
+func (r *Receipt) Sign(privKey ecdsa.PrivateKey, pubKey []byte) {
+	r.NodePubKey = pubKey
+
+	ms := multisig.NewMultisig(1)
+	ms.AddSignature(r.Hash(), pubKey, privKey)
+	r.Signature = ms.Sigs[0]
+}
+
+// VerifySignature checks that the receipt is signed by one of the trusted
+// node keys
+func (r *Receipt) VerifySignature(nodeKeys [][]byte) bool {
+	if len(r.NodePubKey) == 0 || len(r.Signature) == 0 {
+		return false
+	}
+	if isValidator(nodeKeys, r.NodePubKey) == false {
+		return false
+	}
+	ms := multisig.MultiSig{
+		PubKeys: [][]byte{r.NodePubKey},
+		Sigs:    [][]byte{r.Signature},
+	}
+	verified, _ := ms.Verify(r.Hash())
+	return verified
+}
+
+// VerifyReceipt checks that the receipt is signed by a trusted node and that
+// the ballot it refers to is recorded as cast in the chain
+func (bc *Blockchain) VerifyReceipt(r Receipt) error {
+	nodeKeys := bc.receiptKeys()
+	if isValidator(nodeKeys, r.NodePubKey) == false {
+		return fmt.Errorf("%w: node %x is not trusted", ErrInvalidReceipt, r.NodePubKey)
+	}
+	if r.VerifySignature(nodeKeys) == false {
+		return fmt.Errorf("%w: signature does not verify", ErrInvalidReceipt)
+	}
+
+	block, err := bc.GetBlock(r.BlockHash)
+	if err != nil {
+		return fmt.Errorf("%w: block %x not found", ErrInvalidReceipt, r.BlockHash)
+	}
+	if block.Height != r.Height || bytes.Compare(block.MerkleRoot, r.MerkleRoot) != 0 {
+		return fmt.Errorf("%w: block %x does not match the receipt", ErrInvalidReceipt, r.BlockHash)
+	}
+	if r.Proof.Index < 0 || r.Proof.Index >= len(block.Transactions) {
+		return fmt.Errorf("%w: %v", ErrInvalidReceipt, ErrInvalidProofIndex)
+	}
+
+	tx := block.Transactions[r.Proof.Index]
+	if bytes.Compare(tx.ID, r.TxID) != 0 || tx.Input.BallotTx.IsSet() == false {
+		return fmt.Errorf("%w: transaction %x is not a ballot of the block", ErrInvalidReceipt, r.TxID)
+	}
+	if header := block.Header(); header.VerifyTxProof(tx.Serialize(), r.Proof) == false {
+		return fmt.Errorf("%w: merkle proof does not verify", ErrInvalidReceipt)
+	}
+	return nil
+}
+
+// receiptKeys returns the keys of the nodes whose receipts are trusted
+func (bc *Blockchain) receiptKeys() [][]byte {
+	nodeKeys := bc.config.ReceiptKeys
+	if bc.signer != nil {
+		nodeKeys = append([][]byte{bc.signer.PublicKey()}, nodeKeys...)
+	}
+	return nodeKeys
+}
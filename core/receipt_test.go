@@ -0,0 +1,88 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+func TestVerifyReceipt(t *testing.T) {
+	e := newTestElection(t, 1, nil)
+	nodeKey, nodePub := wallet.NewKeyPair()
+	e.bc.SetBlockSigner(*nodeKey, nodePub)
+	otherKey, otherPub := wallet.NewKeyPair()
+
+	e.startAccreditation()
+	e.stopAccreditation()
+	e.startVoting()
+	out := e.ballotOutputTx(e.ring(0))
+	e.mustAdd(out)
+	ballot := e.ballotInputTx(0, out, nil)
+	block := e.mustAdd(ballot)
+
+	newReceipt := func(txId []byte) Receipt {
+		receipt, err := NewReceipt(*block, txId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipt.Sign(*nodeKey, nodePub)
+		return *receipt
+	}
+
+	tests := []struct {
+		name        string
+		receiptKeys [][]byte
+		forge       func(r *Receipt)
+		valid       bool
+	}{
+		{"receipt of the node", nil, func(r *Receipt) {}, true},
+		{"receipt of a trusted node", [][]byte{otherPub}, func(r *Receipt) {
+			r.Sign(*otherKey, otherPub)
+		}, true},
+		{"receipt of an untrusted node", nil, func(r *Receipt) {
+			r.Sign(*otherKey, otherPub)
+		}, false},
+		{"signed by another key than the node key", [][]byte{otherPub}, func(r *Receipt) {
+			r.Sign(*otherKey, otherPub)
+			r.NodePubKey = nodePub
+		}, false},
+		{"unsigned", nil, func(r *Receipt) {
+			r.Signature = nil
+		}, false},
+		{"other transaction", nil, func(r *Receipt) {
+			r.TxID = out.ID
+			r.Sign(*nodeKey, nodePub)
+		}, false},
+		{"unknown block", nil, func(r *Receipt) {
+			r.BlockHash = out.ID
+			r.Sign(*nodeKey, nodePub)
+		}, false},
+		{"wrong height", nil, func(r *Receipt) {
+			r.Height++
+			r.Sign(*nodeKey, nodePub)
+		}, false},
+		{"proof index out of range", nil, func(r *Receipt) {
+			r.Proof.Index = 1
+			r.Sign(*nodeKey, nodePub)
+		}, false},
+		{"tampered proof", nil, func(r *Receipt) {
+			r.Proof.Siblings = [][]byte{block.MerkleRoot}
+			r.Sign(*nodeKey, nodePub)
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e.bc.config.ReceiptKeys = test.receiptKeys
+			receipt := newReceipt(ballot.ID)
+			test.forge(&receipt)
+
+			err := e.bc.VerifyReceipt(receipt)
+			if test.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("forged receipt verifies")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// REVOCATION
+// Revoke voter certificates of the election TxOutput
+type TxRevocationOutput struct {
+	ID             string   `json:"id"`
+	TxID           []byte   `json:"tx_id"` // Election output issuing the certificate authority
+	Signers        [][]byte `json:"signers"`
+	SigWitnesses   [][]byte `json:"sig_witnesses"`
+	ElectionPubKey []byte   `json:"election_pubkey"`
+	Serials        [][]byte `json:"serials"` // Serial numbers of the revoked certificates
+	Timestamp      int64    `json:"timestamp"`
+}
+
+// NewRevocationTxOutput records revoked voter certificates
+func NewRevocationTxOutput(keyHash, txId []byte, serials, signers, SigWitnesses [][]byte, timestamp int64) *TxOutput {
+	tx := &TxOutput{
+		RevocationTx: TxRevocationOutput{
+			TxID:           txId,
+			Signers:        signers,
+			SigWitnesses:   SigWitnesses,
+			ElectionPubKey: keyHash,
+			Serials:        serials,
+			Timestamp:      timestamp,
+		},
+	}
+	uuid, _ := uuid.NewUUID()
+	tx.RevocationTx.ID = uuid.String()
+	return tx
+}
+
+func (tx *TxRevocationOutput) IsSet() bool {
+	return reflect.DeepEqual(tx, &TxRevocationOutput{}) == false
+}
+
+// Trim revocation output data
+func (tx *TxRevocationOutput) TrimmedCopy() TxRevocationOutput {
+	txCopy := TxRevocationOutput{
+		"",
+		tx.TxID,
+		nil,
+		nil,
+		tx.ElectionPubKey,
+		tx.Serials,
+		tx.Timestamp,
+	}
+	return txCopy
+}
+
+// Convert Revocation output to Byte for verification and signing purposes
+func (tx *TxRevocationOutput) ToByte() []byte {
+	var hash [32]byte
+
+	txCopy := tx.TrimmedCopy()
+
+	hash = sha256.Sum256([]byte(fmt.Sprintf("%x", txCopy)))
+	return hash[:]
+}
+
+// Helper function for displaying transaction data in the console
+func (tx *TxRevocationOutput) String() string {
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("--TX_OUTPUT: %x", tx.ID))
+	if tx.IsSet() {
+		lines = append(lines, fmt.Sprintf("Timestamp: %d", tx.Timestamp))
+		for i := 0; i < len(tx.Serials); i++ {
+			lines = append(lines, fmt.Sprintf("(Revoked Serials) \n --(%d): %x", i, tx.Serials[i]))
+		}
+		for i := 0; i < len(tx.Signers); i++ {
+			lines = append(lines, fmt.Sprintf("(Signers) \n --(%d): %x", i, tx.Signers[i]))
+		}
+		for i := 0; i < len(tx.SigWitnesses); i++ {
+			lines = append(lines, fmt.Sprintf("(Signature Witness): \n --(%d): %x", i, tx.SigWitnesses[i]))
+		}
+		lines = append(lines, fmt.Sprintf("Election Keyhash: %x", tx.ElectionPubKey))
+	}
+	return strings.Join(lines, "\n")
+}
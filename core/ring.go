@@ -0,0 +1,310 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/ringsig"
+)
+
+var (
+	ErrNoVoterRings    = errors.New("Accreditation has not published the accredited voters")
+	ErrInvalidRing     = errors.New("Ballot ring is not one of the election rings")
+	ErrInvalidAcVoters = errors.New("Invalid accredited voters")
+	ErrKeyImageUsed    = errors.New("Voter key image was already used to cast a ballot")
+)
+
+// RingSecret derives the secret seeding the ballot rings of the election from
+// a commission key. Its commitment is published when accreditation starts,
+// before the accredited voters are known, and the secret is revealed when
+// accreditation stops. The commission knows its secret before it publishes
+// the voters, so the rings are only drawn with RingSeed.
+func RingSecret(privKey ecdsa.PrivateKey, electionPubKey []byte) []byte {
+	mac := hmac.New(sha256.New, privKey.D.Bytes())
+	mac.Write([]byte("ring secret"))
+	mac.Write(electionPubKey)
+	return mac.Sum(nil)
+}
+
+// RingCommitment returns the commitment of the ring secret
+func RingCommitment(secret []byte) []byte {
+	hash := sha256.Sum256(secret)
+	return hash[:]
+}
+
+// RingSeed returns the seed of the ballot rings: the revealed ring secret
+// mixed with the hash of the block holding the stopped accreditation. That
+// block only exists once the voters are on-chain, the commission cannot try
+// voter lists until it gets the rings it wants.
+func RingSeed(secret, electionPubKey, blockHash []byte) []byte {
+	seed := sha256.Sum256(bytes.Join([][]byte{secret, electionPubKey, blockHash}, []byte{}))
+	return seed[:]
+}
+
+// ringSeed is a deterministic stream of random numbers derived from a seed
+type ringSeed struct {
+	seed    []byte
+	counter uint64
+	buffer  []byte
+}
+
+// next returns the next 8 bytes of the stream as an integer
+func (r *ringSeed) next() uint64 {
+	if len(r.buffer) < 8 {
+		counter := make([]byte, 8)
+		binary.BigEndian.PutUint64(counter, r.counter)
+		hash := sha256.Sum256(append(append([]byte{}, r.seed...), counter...))
+		r.buffer = hash[:]
+		r.counter++
+	}
+	value := binary.BigEndian.Uint64(r.buffer[:8])
+	r.buffer = r.buffer[8:]
+	return value
+}
+
+// intn returns a uniform integer in [0, n). Values above the largest multiple
+// of n are rejected so the result is not biased towards small numbers.
+func (r *ringSeed) intn(n int) int {
+	limit := math.MaxUint64 - math.MaxUint64%uint64(n)
+	for {
+		if value := r.next(); value < limit {
+			return int(value % uint64(n))
+		}
+	}
+}
+
+// SelectRings shuffles the voters with the seed and splits them into rings of
+// the given size. Every voter belongs to exactly one ring and all the members
+// of a ring share it, so a ring does not reveal which member it was drawn for.
+// When the voters do not split evenly the remaining voters are spread over the
+// rings, keeping their sizes within one of each other. Keys of a ring are
+// sorted.
+func SelectRings(voters [][]byte, seed []byte, size int) [][][]byte {
+	if len(voters) == 0 || size < 1 {
+		return nil
+	}
+
+	shuffled := make([][]byte, len(voters))
+	copy(shuffled, voters)
+	sort.Slice(shuffled, func(i, j int) bool {
+		return bytes.Compare(shuffled[i], shuffled[j]) < 0
+	})
+	stream := &ringSeed{seed: seed}
+	for i := len(shuffled) - 1; i > 0; i-- {
+		j := stream.intn(i + 1)
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+
+	count := len(shuffled) / size
+	if count == 0 {
+		count = 1
+	}
+	rings := make([][][]byte, count)
+	start := 0
+	for i := 0; i < count; i++ {
+		end := start + len(shuffled)/count
+		if i < len(shuffled)%count {
+			end++
+		}
+		ring := shuffled[start:end]
+		sort.Slice(ring, func(a, b int) bool {
+			return bytes.Compare(ring[a], ring[b]) < 0
+		})
+		rings[i] = ring
+		start = end
+	}
+	return rings
+}
+
+// GetRings returns the ballot rings of an election. They are drawn from the
+// voters published when accreditation stopped, seeded with the ring secret
+// the commission committed to before the voters were known and the hash of
+// the block holding them, see RingSeed.
+func (bc *Blockchain) GetRings(pubKey []byte) ([][][]byte, error) {
+	iter, err := bc.crud.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		block := iter.Next()
+		for _, tx := range block.Transactions {
+			acIn := tx.Input.AccreditationTx
+			if bytes.Compare(tx.ElectionPubkey, pubKey) != 0 || acIn.IsSet() == false {
+				continue
+			}
+			if len(acIn.Voters) == 0 || acIn.RingSize < 1 || len(acIn.RingSecret) == 0 {
+				return nil, ErrNoVoterRings
+			}
+
+			seed := RingSeed(acIn.RingSecret, pubKey, block.Hash)
+			return SelectRings(acIn.Voters, seed, int(acIn.RingSize)), nil
+		}
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+	return nil, ErrNoVoterRings
+}
+
+// GetRing returns the ballot ring of the voter
+func (bc *Blockchain) GetRing(pubKey, voter []byte) ([][]byte, error) {
+	rings, err := bc.GetRings(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, ring := range rings {
+		for _, key := range ring {
+			if keys.Equal(key, voter) {
+				return ring, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %x", ErrNotInVoterRoll, voter)
+}
+
+// validateRing checks the accredited voters published when accreditation
+// stops and that ballots issued afterwards use one of the election rings.
+// Elections that do not publish their accredited voters are not checked.
+func (bc *Blockchain) validateRing(tx *Transaction) error {
+	if acIn := tx.Input.AccreditationTx; acIn.IsSet() {
+		if len(acIn.Voters) == 0 {
+			return nil
+		}
+		if int64(len(acIn.Voters)) != acIn.AccreditedCount {
+			return fmt.Errorf("%w: %d voters for %d accredited", ErrInvalidAcVoters, len(acIn.Voters), acIn.AccreditedCount)
+		}
+		if acIn.RingSize < 1 {
+			return fmt.Errorf("%w: ring size must be positive", ErrInvalidAcVoters)
+		}
+		seen := make(map[string]bool)
+		for _, voter := range acIn.Voters {
+			if seen[keyID(voter)] {
+				return fmt.Errorf("%w: voter %x appears twice", ErrInvalidAcVoters, voter)
+			}
+			seen[keyID(voter)] = true
+		}
+
+		txAc, err := bc.FindTxWithAcOutByPubkey(tx.ElectionPubkey)
+		if err != nil {
+			return err
+		}
+		commitment := txAc.Output.AccreditationTx.RingCommitment
+		if txAc.Output.AccreditationTx.IsSet() == false || len(commitment) == 0 {
+			return fmt.Errorf("%w: accreditation did not commit to a ring secret", ErrInvalidAcVoters)
+		}
+		if bytes.Compare(RingCommitment(acIn.RingSecret), commitment) != 0 {
+			return fmt.Errorf("%w: ring secret does not match its commitment", ErrInvalidAcVoters)
+		}
+		return nil
+	}
+
+	// Blind tokens are issued to a single voter, see validateBlindTokens
+	ballotOut := tx.Output.BallotTx
+	if ballotOut.IsSet() == false || ballotOut.IsBlind() {
+		return nil
+	}
+	rings, err := bc.GetRings(tx.ElectionPubkey)
+	if errors.Is(err, ErrNoVoterRings) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, ring := range rings {
+		if sameKeys(ring, ballotOut.PubKeys) {
+			return nil
+		}
+	}
+	return ErrInvalidRing
+}
+
+// sameKeys checks if both lists hold the same public keys, whatever their
+// order and encoding
+func sameKeys(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[string]int)
+	for _, key := range a {
+		count[keyID(key)]++
+	}
+	for _, key := range b {
+		if count[keyID(key)] == 0 {
+			return false
+		}
+		count[keyID(key)]--
+	}
+	return true
+}
+
+// ballotKeyImage returns the key image of a ring signed ballot. It is bound to
+// the voter key and the election, not to the ballot, so every ballot of a
+// voter in the election shares it.
+func ballotKeyImage(ballotIn TxBallotInput) ([]byte, error) {
+	signature := new(ringsig.RingSign)
+	if err := signature.FromByte(ballotIn.Signature); err != nil {
+		return nil, err
+	}
+	return signature.KeyImage(), nil
+}
+
+// validateKeyImage checks that the voter of a ring signed ballot has not cast
+// a ballot of the election yet. Ballot outputs are issued to a whole ring, any
+// member could otherwise spend the ballots of the others.
+func (bc *Blockchain) validateKeyImage(tx *Transaction) error {
+	ballotIn := tx.Input.BallotTx
+	if ballotIn.IsSet() == false || ballotIn.IsBlind() {
+		return nil
+	}
+	image, err := ballotKeyImage(ballotIn)
+	if err != nil {
+		return err
+	}
+
+	txs, err := bc.GetTransactionsByPubkey(tx.ElectionPubkey)
+	if err != nil {
+		return err
+	}
+	for _, prev := range txs {
+		prevIn := prev.Input.BallotTx
+		if prevIn.IsSet() == false || prevIn.IsBlind() {
+			continue
+		}
+		if prevImage, err := ballotKeyImage(prevIn); err == nil && bytes.Compare(prevImage, image) == 0 {
+			return fmt.Errorf("%w: %x", ErrKeyImageUsed, image)
+		}
+	}
+	return nil
+}
+
+// validateBlockKeyImages checks the ring signed ballots of a block against
+// each other, a key image is used once per election across the block. The
+// ballots already on the chain are checked by validateKeyImage.
+func validateBlockKeyImages(txs []*Transaction) error {
+	used := make(map[string]bool)
+	for _, tx := range txs {
+		ballotIn := tx.Input.BallotTx
+		if ballotIn.IsSet() == false || ballotIn.IsBlind() {
+			continue
+		}
+		image, err := ballotKeyImage(ballotIn)
+		if err != nil {
+			return fmt.Errorf("transaction %x: %w", tx.ID, err)
+		}
+		id := string(tx.ElectionPubkey) + string(image)
+		if used[id] {
+			return fmt.Errorf("transaction %x: %w: %x", tx.ID, ErrKeyImageUsed, image)
+		}
+		used[id] = true
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+// newTestKeys returns public keys of new P-256 keys
+func newTestKeys(t *testing.T, n int) [][]byte {
+	t.Helper()
+	var pubKeys [][]byte
+	for i := 0; i < n; i++ {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubKeys = append(pubKeys, keys.FromECDSA(&privKey.PublicKey).Bytes())
+	}
+	return pubKeys
+}
+
+// uncompressed returns the keys with their SEC1 uncompressed encoding
+func uncompressed(t *testing.T, pubKeys [][]byte) [][]byte {
+	t.Helper()
+	var encoded [][]byte
+	for _, key := range pubKeys {
+		pub, err := keys.Parse(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded = append(encoded, pub.Uncompressed())
+	}
+	return encoded
+}
+
+func ringVoters(n int) [][]byte {
+	var voters [][]byte
+	for i := 0; i < n; i++ {
+		voters = append(voters, []byte(fmt.Sprintf("voter %02d", i)))
+	}
+	return voters
+}
+
+func TestSelectRings(t *testing.T) {
+	tests := []struct {
+		voters int
+		size   int
+		rings  int
+	}{
+		{voters: 1, size: 3, rings: 1},
+		{voters: 3, size: 3, rings: 1},
+		{voters: 10, size: 3, rings: 3},
+		{voters: 12, size: 4, rings: 3},
+		{voters: 7, size: 1, rings: 7},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d voters in rings of %d", test.voters, test.size), func(t *testing.T) {
+			voters := ringVoters(test.voters)
+			rings := SelectRings(voters, []byte("seed"), test.size)
+			if len(rings) != test.rings {
+				t.Fatalf("expected %d rings, got %d", test.rings, len(rings))
+			}
+
+			seen := make(map[string]int)
+			for _, ring := range rings {
+				if len(ring) < test.voters/test.rings || len(ring) > test.voters/test.rings+1 {
+					t.Errorf("ring of %d voters out of %d rings of %d voters", len(ring), test.rings, test.voters)
+				}
+				if sort.SliceIsSorted(ring, func(i, j int) bool { return bytes.Compare(ring[i], ring[j]) < 0 }) == false {
+					t.Error("ring keys are not sorted")
+				}
+				for _, key := range ring {
+					seen[string(key)]++
+				}
+			}
+			for _, voter := range voters {
+				if seen[string(voter)] != 1 {
+					t.Errorf("voter %s is in %d rings", voter, seen[string(voter)])
+				}
+			}
+		})
+	}
+}
+
+func TestSelectRingsDeterminism(t *testing.T) {
+	voters := ringVoters(20)
+	reversed := make([][]byte, len(voters))
+	for i, voter := range voters {
+		reversed[len(voters)-1-i] = voter
+	}
+
+	rings := SelectRings(voters, []byte("seed"), 4)
+	if reflect.DeepEqual(rings, SelectRings(reversed, []byte("seed"), 4)) == false {
+		t.Error("rings depend on the order of the voters")
+	}
+	if reflect.DeepEqual(rings, SelectRings(voters, []byte("seed"), 4)) == false {
+		t.Error("same seed draws different rings")
+	}
+	if reflect.DeepEqual(rings, SelectRings(voters, []byte("other seed"), 4)) {
+		t.Error("another seed draws the same rings")
+	}
+}
+
+func TestGetRings(t *testing.T) {
+	e := newTestElection(t, 6, nil)
+	e.startAccreditation()
+	stopped := e.stopAccreditationTx(func(in *TxAcInput) {
+		in.RingSize = 2
+	})
+	block := e.mustAdd(stopped)
+
+	rings, err := e.bc.GetRings(e.pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := RingSeed(e.ringSecret(), e.pubKey, block.Hash)
+	if reflect.DeepEqual(rings, SelectRings(e.voterKeys, seed, 2)) == false {
+		t.Error("rings are not seeded with the revealed ring secret and the accreditation block")
+	}
+
+	for i := range e.voters {
+		ring := e.ring(i)
+		if reflect.DeepEqual(ring, e.ring(i)) == false {
+			t.Fatalf("ring of voter %d changes between calls", i)
+		}
+		if isValidator(ring, e.voterKeys[i]) == false {
+			t.Fatalf("ring of voter %d does not hold the voter", i)
+		}
+	}
+
+	if _, err := e.bc.GetRing(e.pubKey, []byte("unknown voter")); errors.Is(err, ErrNotInVoterRoll) == false {
+		t.Errorf("expected %v, got %v", ErrNotInVoterRoll, err)
+	}
+}
+
+func TestRingSeed(t *testing.T) {
+	seed := RingSeed([]byte("secret"), []byte("election"), []byte("block"))
+	for _, other := range [][]byte{
+		RingSeed([]byte("secret"), []byte("election"), []byte("other block")),
+		RingSeed([]byte("other secret"), []byte("election"), []byte("block")),
+		RingSeed([]byte("secret"), []byte("other election"), []byte("block")),
+	} {
+		if bytes.Compare(seed, other) == 0 {
+			t.Error("expected the seed to depend on the secret, the election and the block")
+		}
+	}
+}
+
+func TestValidateRingSecret(t *testing.T) {
+	tests := []struct {
+		name       string
+		commitment func(e *testElection) []byte
+		secret     []byte
+		valid      bool
+	}{
+		{"revealed secret", nil, nil, true},
+		{"other secret", nil, []byte("ground secret"), false},
+		{"missing secret", nil, []byte{}, false},
+		{"no commitment", func(e *testElection) []byte { return nil }, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestElection(t, 3, nil)
+			out := NewAccreditationTxOutput(e.pubKey, e.election.ID, nil, nil, 1)
+			out.AccreditationTx.RingCommitment = RingCommitment(e.ringSecret())
+			if test.commitment != nil {
+				out.AccreditationTx.RingCommitment = test.commitment(e)
+			}
+			out.AccreditationTx.Signers, out.AccreditationTx.SigWitnesses = e.sign(out.AccreditationTx.ToByte())
+			e.acOut = e.tx(ACCREDITATION_TX_TYPE, TxInput{}, *out)
+			e.mustAdd(e.acOut)
+
+			tx := e.stopAccreditationTx(func(in *TxAcInput) {
+				if test.secret != nil {
+					in.RingSecret = test.secret
+				}
+			})
+			_, err := e.add(tx)
+			if test.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expected the accreditation to be rejected")
+			}
+		})
+	}
+}
+
+func TestValidateBallotRing(t *testing.T) {
+	e := newTestElection(t, 6, nil)
+	e.startAccreditation()
+	e.mustAdd(e.stopAccreditationTx(func(in *TxAcInput) {
+		in.RingSize = 3
+	}))
+
+	ring := e.ring(0)
+	other := e.ring(0)[:2]
+	for _, key := range e.voterKeys {
+		if isValidator(ring, key) == false {
+			other = append(append([][]byte{}, other...), key)
+			break
+		}
+	}
+
+	tests := []struct {
+		name  string
+		ring  [][]byte
+		valid bool
+	}{
+		{"election ring", ring, true},
+		{"uncompressed ring", uncompressed(t, ring), true},
+		{"mixed ring", other, false},
+		{"partial ring", ring[:2], false},
+		{"voter twice", append([][]byte{ring[0], uncompressed(t, ring[:1])[0]}, ring[2:]...), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := e.bc.validateRing(e.ballotOutputTx(test.ring))
+			if test.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !test.valid && errors.Is(err, ErrInvalidRing) == false {
+				t.Fatalf("expected %v, got %v", ErrInvalidRing, err)
+			}
+		})
+	}
+}
+
+func TestValidateAcVotersEncoding(t *testing.T) {
+	e := newTestElection(t, 3, nil)
+	e.startAccreditation()
+
+	tx := e.stopAccreditationTx(func(in *TxAcInput) {
+		in.Voters = append([][]byte{}, e.voterKeys...)
+		in.Voters[2] = uncompressed(t, e.voterKeys[:1])[0]
+	})
+	if err := e.bc.validateRing(tx); errors.Is(err, ErrInvalidAcVoters) == false {
+		t.Fatalf("expected %v, got %v", ErrInvalidAcVoters, err)
+	}
+}
+
+func TestValidateKeyImage(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(e *testElection) *Transaction
+		err  error
+	}{
+		{
+			name: "first ballot of the voter",
+			run: func(e *testElection) *Transaction {
+				out := e.ballotOutputTx(e.ring(0))
+				e.mustAdd(out)
+				return e.ballotInputTx(0, out, nil)
+			},
+		},
+		{
+			name: "another member of the ring",
+			run: func(e *testElection) *Transaction {
+				e.vote(0, e.candidates[0])
+				out := e.ballotOutputTx(e.ring(1))
+				e.mustAdd(out)
+				return e.ballotInputTx(1, out, nil)
+			},
+		},
+		{
+			name: "voter spending another ballot of the ring",
+			run: func(e *testElection) *Transaction {
+				e.vote(0, e.candidates[0])
+				out := e.ballotOutputTx(e.ring(0))
+				e.mustAdd(out)
+				return e.ballotInputTx(0, out, nil)
+			},
+			err: ErrKeyImageUsed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestElection(t, 3, nil)
+			e.startAccreditation()
+			e.stopAccreditation()
+			e.startVoting()
+			tx := test.run(e)
+			err := e.bc.validateKeyImage(tx)
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if _, err = e.add(tx); test.err == nil && err != nil {
+				t.Fatalf("unexpected error adding the ballot: %v", err)
+			} else if test.err != nil && err == nil {
+				t.Fatal("expected the ballot to be rejected")
+			}
+		})
+	}
+}
+
+// A member of a shared ring cannot spend the ballots issued to the others
+func TestRingMemberSpendingTheRing(t *testing.T) {
+	e := newTestElection(t, 3, nil)
+	e.startAccreditation()
+	e.stopAccreditation()
+	e.startVoting()
+
+	var outs []*Transaction
+	for range e.voterKeys {
+		out := e.ballotOutputTx(e.ring(0))
+		e.mustAdd(out)
+		outs = append(outs, out)
+	}
+	e.mustAdd(e.ballotInputTx(0, outs[0], nil))
+	if _, err := e.add(e.ballotInputTx(0, outs[1], nil)); err == nil {
+		t.Fatal("expected the second ballot of the voter to be rejected")
+	}
+	if _, err := e.add(e.ballotInputTx(0, outs[1], nil), e.ballotInputTx(0, outs[2], nil)); err == nil {
+		t.Fatal("expected the ballots of the voter to be rejected")
+	}
+	e.mustAdd(e.ballotInputTx(1, outs[1], nil))
+
+	results, err := e.bc.QueryResult(e.pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if votes := results[DEFAULT_RACE_ID].Tally[hex.EncodeToString(e.candidates[0])]; votes != 2 {
+		t.Fatalf("expected 2 votes, got %d", votes)
+	}
+}
+
+func TestValidateBlockKeyImages(t *testing.T) {
+	e := newTestElection(t, 3, nil)
+	e.startAccreditation()
+	e.stopAccreditation()
+	e.startVoting()
+	first, second := e.ballotOutputTx(e.ring(0)), e.ballotOutputTx(e.ring(0))
+	e.mustAdd(first, second)
+
+	err := validateBlockKeyImages([]*Transaction{e.ballotInputTx(0, first, nil), e.ballotInputTx(1, second, nil)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = validateBlockKeyImages([]*Transaction{e.ballotInputTx(0, first, nil), e.ballotInputTx(0, second, nil)})
+	if errors.Is(err, ErrKeyImageUsed) == false {
+		t.Fatalf("expected %v, got %v", ErrKeyImageUsed, err)
+	}
+}
@@ -0,0 +1,54 @@
+package blockchain
+
+import (
+	"sort"
+)
+
+// ViewKey decrypts the secret messages of ballot outputs. It is implemented
+// by the view wallet of the voters.
+type ViewKey interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// ScannedBallot is a ballot output whose secret message was decrypted with
+// the view key of the voter
+type ScannedBallot struct {
+	TxID    string         `json:"tx_id"`
+	Output  TxBallotOutput `json:"output"`
+	Message []byte         `json:"message"`
+}
+
+// ScanBallots returns the ballot outputs addressed to the view key, sorted by
+// transaction ID. Outputs of other voters cannot be decrypted and are skipped.
+// RSA secret messages of earlier versions are not authenticated, so one of
+// them can rarely be taken for a ballot of the voter.
+func ScanBallots(outputs []map[string]TxBallotOutput, view ViewKey) []ScannedBallot {
+	var scanned []ScannedBallot
+	for _, output := range outputs {
+		for txId, ballot := range output {
+			if len(ballot.SecretMessage) == 0 {
+				continue
+			}
+			message, err := view.Decrypt(ballot.SecretMessage)
+			if err != nil {
+				continue
+			}
+			scanned = append(scanned, ScannedBallot{txId, ballot, message})
+		}
+	}
+	sort.Slice(scanned, func(i, j int) bool {
+		return scanned[i].TxID < scanned[j].TxID
+	})
+
+	return scanned
+}
+
+// ScanBallotOutputs returns the unused ballot outputs of the election
+// addressed to the view key
+func (bc *Blockchain) ScanBallotOutputs(pubKey []byte, view ViewKey) ([]ScannedBallot, error) {
+	outputs, err := bc.GetUnUsedBallotTxOutputs(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	return ScanBallots(outputs, view), nil
+}
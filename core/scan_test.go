@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+func encryptView(t *testing.T, view *wallet.WalletView, message string) []byte {
+	t.Helper()
+	ciphertext, err := view.Encrypt([]byte(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ciphertext
+}
+
+func TestScanBallots(t *testing.T) {
+	voter, other := wallet.MakeViewWallet(), wallet.MakeViewWallet()
+
+	tests := []struct {
+		name     string
+		outputs  []map[string]TxBallotOutput
+		expected []string // IDs of the scanned ballots
+	}{
+		{"no outputs", nil, nil},
+		{
+			name: "ballot of the voter",
+			outputs: []map[string]TxBallotOutput{
+				{"01": {SecretMessage: encryptView(t, voter, "ballot")}},
+			},
+			expected: []string{"01"},
+		},
+		{
+			name: "ballots of other voters",
+			outputs: []map[string]TxBallotOutput{
+				{"01": {SecretMessage: encryptView(t, other, "ballot")}},
+				{"02": {SecretMessage: encryptView(t, voter, "ballot")}},
+			},
+			expected: []string{"02"},
+		},
+		{
+			name: "no secret message",
+			outputs: []map[string]TxBallotOutput{
+				{"01": {}},
+			},
+		},
+		{
+			name: "sorted by transaction",
+			outputs: []map[string]TxBallotOutput{
+				{"03": {SecretMessage: encryptView(t, voter, "ballot")}},
+				{"01": {SecretMessage: encryptView(t, voter, "ballot")}},
+				{"02": {SecretMessage: encryptView(t, voter, "ballot")}},
+			},
+			expected: []string{"01", "02", "03"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanned := ScanBallots(test.outputs, voter)
+			if len(scanned) != len(test.expected) {
+				t.Fatalf("expected %d ballots, got %d", len(test.expected), len(scanned))
+			}
+			for i, ballot := range scanned {
+				if ballot.TxID != test.expected[i] {
+					t.Errorf("expected ballot %s, got %s", test.expected[i], ballot.TxID)
+				}
+				if string(ballot.Message) != "ballot" {
+					t.Errorf("expected the decrypted message, got %q", ballot.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestScanBallotOutputs(t *testing.T) {
+	e := newTestElection(t, 3, nil)
+	e.startAccreditation()
+	e.stopAccreditation()
+	e.startVoting()
+
+	voter, other := wallet.MakeViewWallet(), wallet.MakeViewWallet()
+	issue := func(view *wallet.WalletView) *Transaction {
+		out := NewBallotTxOutput(e.pubKey, encryptView(t, view, "ballot"), e.election.ID, e.ring(0), nil, nil, time.Now().Unix())
+		out.BallotTx.Signers, out.BallotTx.SigWitnesses = e.sign(out.BallotTx.ToByte())
+		return e.tx(BALLOT_TX_TYPE, TxInput{}, *out)
+	}
+	ballot := issue(voter)
+	e.mustAdd(ballot, issue(other))
+
+	scanned, err := e.bc.ScanBallotOutputs(e.pubKey, voter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != 1 || scanned[0].TxID != hex.EncodeToString(ballot.ID) {
+		t.Fatalf("expected the ballot of the voter, got %v", scanned)
+	}
+	if string(scanned[0].Message) != "ballot" {
+		t.Errorf("expected the decrypted message, got %q", scanned[0].Message)
+	}
+
+	e.mustAdd(e.ballotInputTx(0, ballot, nil))
+	if scanned, err = e.bc.ScanBallotOutputs(e.pubKey, voter); err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != 0 {
+		t.Errorf("expected the cast ballot to be skipped, got %v", scanned)
+	}
+}
@@ -0,0 +1,145 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/database"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+)
+
+var (
+	ErrNotCommissionTx = errors.New("Transaction is not signed by the commission")
+
+	nodeKeyKey = []byte("nodekey")
+)
+
+// NodeSigner signs on behalf of the node: its consensus signatures of the
+// blocks it adds, the ballot receipts it issues and its multisig
+// contributions as a commission member. The keys either sit in the node
+// wallet or are held by a remote signer.
+type NodeSigner interface {
+	// PublicKey returns the public key of the signatures
+	PublicKey() []byte
+
+	SignBlock(header BlockHeader) ([]byte, error)
+	SignReceipt(r Receipt) ([]byte, error)
+	SignTx(tx Transaction) ([]byte, error)
+}
+
+// LocalSigner signs with a private key loaded by the node
+type LocalSigner struct {
+	privKey ecdsa.PrivateKey
+	pubKey  []byte
+}
+
+func NewLocalSigner(privKey ecdsa.PrivateKey, pubKey []byte) *LocalSigner {
+	return &LocalSigner{privKey, pubKey}
+}
+
+func (s *LocalSigner) PublicKey() []byte {
+	return s.pubKey
+}
+
+// SignBlock signs the block hash, once it is checked against the header
+func (s *LocalSigner) SignBlock(header BlockHeader) ([]byte, error) {
+	if header.VerifyHash() == false {
+		return nil, fmt.Errorf("%w: hash does not match the header", ErrInvalidBlockHeader)
+	}
+	return s.sign(header.Hash), nil
+}
+
+// SignReceipt signs the receipt hash, the receipt must name the signer key
+func (s *LocalSigner) SignReceipt(r Receipt) ([]byte, error) {
+	if keys.Equal(r.NodePubKey, s.pubKey) == false {
+		return nil, fmt.Errorf("%w: receipt is not issued for the signer key", ErrInvalidReceipt)
+	}
+	return s.sign(r.Hash()), nil
+}
+
+// SignTx returns the commission contribution of the signer to the transaction
+func (s *LocalSigner) SignTx(tx Transaction) ([]byte, error) {
+	data, err := tx.SigningData()
+	if err != nil {
+		return nil, err
+	}
+	return s.sign(data), nil
+}
+
+// NodeKeySigner returns a signer with the node key kept in the blockchain
+// store, the key is created the first time. Nodes without a wallet or a
+// remote signer sign their blocks with it.
+func (bc *Blockchain) NodeKeySigner() (*LocalSigner, error) {
+	var privKey *ecdsa.PrivateKey
+
+	data, err := bc.crud.ps.Get(nodeKeyKey)
+	switch {
+	case err == nil:
+		if privKey, err = x509.ParseECPrivateKey(data); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, database.ErrKeyNotFound):
+		if privKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			return nil, err
+		}
+		if data, err = x509.MarshalECPrivateKey(privKey); err != nil {
+			return nil, err
+		}
+		if err = bc.crud.Save(nodeKeyKey, data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	return NewLocalSigner(*privKey, keys.FromECDSA(&privKey.PublicKey).Bytes()), nil
+}
+
+func (s *LocalSigner) sign(data []byte) []byte {
+	ms := multisig.NewMultisig(1)
+	ms.AddSignature(data, s.pubKey, s.privKey)
+	return ms.Sigs[0]
+}
+
+// SigningData returns the data the commission signs for the transaction. The
+// election public key of the transaction data must match the transaction.
+// Ballot inputs are ring signed by the voters and are rejected.
+func (tx *Transaction) SigningData() ([]byte, error) {
+	var data, pubKey []byte
+
+	switch {
+	case tx.Output.ElectionTx.IsSet():
+		data, pubKey = tx.Output.ElectionTx.ToByte(), tx.Output.ElectionTx.ElectionPubKey
+	case tx.Input.ElectionTx.IsSet():
+		data, pubKey = tx.Input.ElectionTx.ToByte(), tx.Input.ElectionTx.ElectionPubKey
+	case tx.Output.AccreditationTx.IsSet():
+		data, pubKey = tx.Output.AccreditationTx.ToByte(), tx.Output.AccreditationTx.ElectionPubKey
+	case tx.Input.AccreditationTx.IsSet():
+		txCopy := tx.Input.AccreditationTx.TrimmedCopy()
+		txCopy.ElectionPubKey = tx.ElectionPubkey
+		data, pubKey = txCopy.ToByte(), tx.ElectionPubkey
+	case tx.Output.VotingTx.IsSet():
+		data, pubKey = tx.Output.VotingTx.ToByte(), tx.Output.VotingTx.ElectionPubKey
+	case tx.Input.VotingTx.IsSet():
+		txCopy := tx.Input.VotingTx.TrimmedCopy()
+		txCopy.ElectionPubKey = tx.ElectionPubkey
+		data, pubKey = txCopy.ToByte(), tx.ElectionPubkey
+	case tx.Output.BallotTx.IsSet():
+		data, pubKey = tx.Output.BallotTx.ToByte(), tx.Output.BallotTx.ElectionPubKey
+	case tx.Output.RevocationTx.IsSet():
+		data, pubKey = tx.Output.RevocationTx.ToByte(), tx.Output.RevocationTx.ElectionPubKey
+	default:
+		return nil, ErrNotCommissionTx
+	}
+
+	if bytes.Compare(pubKey, tx.ElectionPubkey) != 0 {
+		return nil, ErrInvalidTransactionPubkey
+	}
+	return data, nil
+}
@@ -0,0 +1,119 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTooManyAccredited = errors.New("Accredited voters exceed the total people of the election")
+	ErrTooManyBallots    = errors.New("Ballots exceed the number of accredited voters")
+)
+
+// ElectionStats holds the turnout statistics of an election
+type ElectionStats struct {
+	ElectionPubKey      []byte `json:"election_pubkey"`
+	Eligible            int64  `json:"eligible"`
+	Accredited          int64  `json:"accredited"`
+	AccreditationClosed bool   `json:"accreditation_closed"`
+	IssuedBallots       int64  `json:"issued_ballots"`
+	CastBallots         int64  `json:"cast_ballots"`
+	Turnout             int64  `json:"turnout"` // Percentage of the eligible voters who cast a ballot
+}
+
+// GetElectionStats aggregates the eligible, accredited, issued and cast
+// ballot counts of an election
+func (bc *Blockchain) GetElectionStats(pubKey []byte) (ElectionStats, error) {
+	stats := ElectionStats{ElectionPubKey: pubKey}
+
+	txs, err := bc.GetTransactionsByPubkey(pubKey)
+	if err != nil {
+		return stats, err
+	}
+	for i := range txs {
+		stats.add(&txs[i])
+	}
+	return stats, nil
+}
+
+// add counts the transaction in the statistics
+func (stats *ElectionStats) add(tx *Transaction) {
+	switch {
+	case tx.Output.ElectionTx.IsSet():
+		stats.Eligible = tx.Output.ElectionTx.TotalPeople
+	case tx.Input.AccreditationTx.IsSet():
+		stats.Accredited = tx.Input.AccreditationTx.AccreditedCount
+		stats.AccreditationClosed = true
+	case tx.Output.BallotTx.IsSet():
+		stats.IssuedBallots++
+	case tx.Input.BallotTx.IsSet():
+		stats.CastBallots++
+	}
+	if stats.Eligible > 0 {
+		stats.Turnout = stats.CastBallots * 100 / stats.Eligible
+	}
+}
+
+// validateCounts rejects transactions that accredit more voters than the
+// election TotalPeople or issue more ballots than there are accredited voters.
+// Until accreditation is closed ballots are capped by the TotalPeople.
+func (bc *Blockchain) validateCounts(tx *Transaction) error {
+	if tx.Input.AccreditationTx.IsSet() == false && tx.Output.BallotTx.IsSet() == false {
+		return nil
+	}
+
+	stats, err := bc.GetElectionStats(tx.ElectionPubkey)
+	if err != nil {
+		return err
+	}
+	return checkCounts(stats, tx)
+}
+
+// validateBlockCounts checks the counts of the transactions of a block in
+// order, each one sees the transactions of the block before it
+func (bc *Blockchain) validateBlockCounts(txs []*Transaction) error {
+	elections := make(map[string]*ElectionStats)
+	for _, tx := range txs {
+		stats, ok := elections[string(tx.ElectionPubkey)]
+		if !ok {
+			chainStats, err := bc.GetElectionStats(tx.ElectionPubkey)
+			if err != nil {
+				return err
+			}
+			stats = &chainStats
+			elections[string(tx.ElectionPubkey)] = stats
+		}
+		if err := checkCounts(*stats, tx); err != nil {
+			return fmt.Errorf("transaction %x: %w", tx.ID, err)
+		}
+		stats.add(tx)
+	}
+	return nil
+}
+
+// checkCounts checks the transaction against the statistics of its election
+func checkCounts(stats ElectionStats, tx *Transaction) error {
+	acIn := tx.Input.AccreditationTx
+	if acIn.IsSet() == false && tx.Output.BallotTx.IsSet() == false {
+		return nil
+	}
+
+	if acIn.IsSet() {
+		if acIn.AccreditedCount < 0 || acIn.AccreditedCount > stats.Eligible {
+			return fmt.Errorf("%w: %d accredited for %d people", ErrTooManyAccredited, acIn.AccreditedCount, stats.Eligible)
+		}
+		if acIn.AccreditedCount < stats.IssuedBallots {
+			return fmt.Errorf("%w: %d accredited but %d ballots issued", ErrTooManyBallots, acIn.AccreditedCount, stats.IssuedBallots)
+		}
+		return nil
+	}
+
+	limit := stats.Eligible
+	if stats.AccreditationClosed {
+		limit = stats.Accredited
+	}
+	if stats.IssuedBallots+1 > limit {
+		return fmt.Errorf("%w: %d ballots already issued for %d voters", ErrTooManyBallots, stats.IssuedBallots, limit)
+	}
+	return nil
+}
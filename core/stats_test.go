@@ -0,0 +1,66 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateBlockCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		stop    bool // Stop accreditation before issuing the ballots
+		ballots int  // Ballot outputs added in a single block
+		err     error
+	}{
+		{"accredited voters", true, 3, nil},
+		{"more than accredited voters", true, 4, ErrTooManyBallots},
+		{"total people before accreditation stops", false, 3, nil},
+		{"more than total people", false, 4, ErrTooManyBallots},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestElection(t, 3, nil)
+			e.startAccreditation()
+			ring := e.voterKeys
+			if test.stop {
+				e.stopAccreditation()
+				ring = e.ring(0)
+			}
+			var txs []*Transaction
+			for i := 0; i < test.ballots; i++ {
+				txs = append(txs, e.ballotOutputTx(ring))
+			}
+			_, err := e.add(txs...)
+			if errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateBlockCountsAccreditation(t *testing.T) {
+	e := newTestElection(t, 3, nil)
+	e.startAccreditation()
+
+	// Accreditation stopping with fewer voters than the ballots issued in the
+	// same block
+	txs := []*Transaction{e.ballotOutputTx(e.voterKeys), e.ballotOutputTx(e.voterKeys)}
+	txs = append(txs, e.stopAccreditationTx(func(in *TxAcInput) {
+		in.AccreditedCount = 1
+		in.Voters = e.voterKeys[:1]
+		in.RingSize = 1
+	}))
+	if _, err := e.add(txs...); errors.Is(err, ErrTooManyBallots) == false {
+		t.Fatalf("expected %v, got %v", ErrTooManyBallots, err)
+	}
+
+	e.mustAdd(txs[0])
+	stats, err := e.bc.GetElectionStats(e.pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Eligible != 3 || stats.IssuedBallots != 1 || stats.AccreditationClosed {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,157 @@
+package blockchain
+
+import (
+	"encoding/hex"
+)
+
+// Outcomes of a referendum question
+const QUESTION_PASSED = "passed"
+const QUESTION_FAILED = "failed"
+
+// RaceResult is the tally of a single race
+type RaceResult struct {
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	BallotFormat string         `json:"ballot_format"`
+	Ballots      int            `json:"ballots"`
+	Tally        map[string]int `json:"tally"`
+	Outcome      string         `json:"outcome,omitempty"` // Whether a question passed or failed
+	Turnout      int64          `json:"turnout,omitempty"` // Percentage of the eligible voters who voted on a question
+}
+
+// tallyBallots counts the ballots cast in each race of an election using the
+// tally method of the race ballot format. Results are keyed by race ID,
+// candidates by their hex encoded public key and invalid ballots are ignored.
+func tallyBallots(election TxElectionOutput, ballots []TxBallotInput) map[string]RaceResult {
+	var results = make(map[string]RaceResult)
+	var choices = make(map[string][][][]byte)
+	var races = make(map[string]Race)
+
+	for _, race := range election.GetRaces() {
+		races[race.ID] = race
+	}
+	for _, ballot := range ballots {
+		if election.ValidateBallot(ballot) != nil {
+			continue
+		}
+		for _, selection := range ballot.RaceSelections() {
+			race := races[selection.RaceID]
+			choices[selection.RaceID] = append(choices[selection.RaceID], race.listedChoices(selection.Choices))
+		}
+	}
+
+	for _, race := range election.GetRaces() {
+		result := RaceResult{
+			ID:           race.ID,
+			Title:        race.Title,
+			BallotFormat: race.GetBallotFormat(),
+			Ballots:      len(choices[race.ID]),
+		}
+		switch race.GetBallotFormat() {
+		case RANKED_BALLOT:
+			result.Tally = tallyRanked(race.Candidates, choices[race.ID])
+		default:
+			// Plurality, approval and pick-k ballots give one vote to every
+			// selected candidate
+			result.Tally = tallyApproval(race.Candidates, choices[race.ID])
+		}
+		if race.GetType() == QUESTION_RACE {
+			result.Outcome, result.Turnout = questionOutcome(race, result, election.TotalPeople)
+		}
+		results[race.ID] = result
+	}
+	return results
+}
+
+// questionOutcome decides if a referendum question passed. Abstentions count
+// towards the turnout, which is relative to the TotalPeople of the election,
+// but not towards the majority.
+func questionOutcome(race Race, result RaceResult, totalPeople int64) (string, int64) {
+	var turnout int64
+	if totalPeople > 0 {
+		turnout = int64(result.Ballots) * 100 / totalPeople
+	}
+	if turnout < race.MinTurnout {
+		return QUESTION_FAILED, turnout
+	}
+
+	yes := result.Tally[hex.EncodeToString(YesOption)]
+	no := result.Tally[hex.EncodeToString(NoOption)]
+
+	passed := false
+	switch race.GetThreshold() {
+	case SIMPLE_MAJORITY:
+		passed = yes > no
+	case TWO_THIRDS_MAJORITY:
+		passed = yes > 0 && yes*3 >= (yes+no)*2
+	}
+	if passed {
+		return QUESTION_PASSED, turnout
+	}
+	return QUESTION_FAILED, turnout
+}
+
+// tallyApproval gives a vote to each candidate selected on a ballot
+func tallyApproval(candidates [][]byte, ballots [][][]byte) map[string]int {
+	var results = make(map[string]int)
+
+	for _, v := range candidates {
+		results[hex.EncodeToString(v)] = 0
+	}
+	for _, choices := range ballots {
+		for _, choice := range choices {
+			results[hex.EncodeToString(choice)] += 1
+		}
+	}
+	return results
+}
+
+// tallyRanked runs an instant-runoff count. Every round each ballot counts for
+// its highest ranked candidate still in the race and the candidate with the
+// fewest votes is eliminated, until a candidate holds a majority of the
+// ballots still in play. Ties for elimination are broken in favour of the
+// candidate listed first in the race. The final round is returned and
+// eliminated candidates are reported with zero votes.
+func tallyRanked(candidates [][]byte, ballots [][][]byte) map[string]int {
+	var results map[string]int
+	eliminated := make(map[string]bool)
+
+	for {
+		results = make(map[string]int)
+		for _, v := range candidates {
+			results[hex.EncodeToString(v)] = 0
+		}
+
+		active := 0
+		for _, choices := range ballots {
+			for _, choice := range choices {
+				candidate := hex.EncodeToString(choice)
+				if eliminated[candidate] == false {
+					results[candidate] += 1
+					active++
+					break
+				}
+			}
+		}
+
+		remaining := len(candidates) - len(eliminated)
+		if remaining <= 1 || active == 0 {
+			return results
+		}
+
+		lowest := ""
+		for _, v := range candidates {
+			candidate := hex.EncodeToString(v)
+			if eliminated[candidate] {
+				continue
+			}
+			if results[candidate]*2 > active {
+				return results
+			}
+			if lowest == "" || results[candidate] <= results[lowest] {
+				lowest = candidate
+			}
+		}
+		eliminated[lowest] = true
+	}
+}
@@ -0,0 +1,155 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+var (
+	candidateA = []byte("A")
+	candidateB = []byte("B")
+	candidateC = []byte("C")
+)
+
+func votes(tally map[string]int, candidate []byte) int {
+	return tally[hex.EncodeToString(candidate)]
+}
+
+func TestTallyRanked(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates [][]byte
+		ballots    [][][]byte
+		expected   []int // Votes of A, B and C in the final round
+	}{
+		{
+			name:       "majority in the first round",
+			candidates: [][]byte{candidateA, candidateB, candidateC},
+			ballots:    [][][]byte{{candidateA}, {candidateA, candidateB}, {candidateB}},
+			expected:   []int{2, 1, 0},
+		},
+		{
+			name:       "votes transfer from the eliminated candidate",
+			candidates: [][]byte{candidateA, candidateB, candidateC},
+			ballots: [][][]byte{
+				{candidateA}, {candidateA}, {candidateB}, {candidateB}, {candidateC, candidateB},
+			},
+			expected: []int{2, 3, 0},
+		},
+		{
+			name:       "tie broken in favour of the first listed candidate",
+			candidates: [][]byte{candidateA, candidateB, candidateC},
+			ballots:    [][][]byte{{candidateA, candidateC}, {candidateB, candidateC}},
+			expected:   []int{1, 0, 0},
+		},
+		{
+			name:       "tie broken by the order of the candidates",
+			candidates: [][]byte{candidateB, candidateA, candidateC},
+			ballots:    [][][]byte{{candidateA, candidateC}, {candidateB, candidateC}},
+			expected:   []int{0, 1, 0},
+		},
+		{
+			name:       "exhausted ballots leave the count",
+			candidates: [][]byte{candidateA, candidateB, candidateC},
+			ballots: [][][]byte{
+				{candidateA}, {candidateA}, {candidateB}, {candidateB}, {candidateC},
+			},
+			// C is eliminated, its ballot has no other choice and B loses the tie
+			expected: []int{2, 0, 0},
+		},
+		{
+			name:       "no ballots",
+			candidates: [][]byte{candidateA, candidateB, candidateC},
+			expected:   []int{0, 0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tally := tallyRanked(test.candidates, test.ballots)
+			for i, candidate := range [][]byte{candidateA, candidateB, candidateC} {
+				if got := votes(tally, candidate); got != test.expected[i] {
+					t.Fatalf("candidate %s: expected %d votes, got %d", candidate, test.expected[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestTallyApproval(t *testing.T) {
+	candidates := [][]byte{candidateA, candidateB, candidateC}
+	tally := tallyApproval(candidates, [][][]byte{
+		{candidateA, candidateB},
+		{candidateB},
+		{candidateB, candidateC},
+	})
+	for i, expected := range []int{1, 3, 1} {
+		if got := votes(tally, candidates[i]); got != expected {
+			t.Fatalf("candidate %s: expected %d votes, got %d", candidates[i], expected, got)
+		}
+	}
+}
+
+func TestTallyBallotsSkipsInvalidBallots(t *testing.T) {
+	election := TxElectionOutput{
+		Candidates:   [][]byte{candidateA, candidateB, candidateC},
+		BallotFormat: RANKED_BALLOT,
+	}
+	ballots := []TxBallotInput{
+		{Choices: [][]byte{candidateA, candidateB}},
+		{Choices: [][]byte{candidateA, candidateA}}, // Ranks a candidate twice
+		{Choices: [][]byte{[]byte("D")}},            // Unknown candidate
+		{Choices: [][]byte{candidateB, candidateC}},
+		{Choices: [][]byte{candidateB, candidateA}},
+	}
+	result := tallyBallots(election, ballots)[DEFAULT_RACE_ID]
+	if result.Ballots != 3 {
+		t.Fatalf("expected 3 valid ballots, got %d", result.Ballots)
+	}
+	if got := votes(result.Tally, candidateB); got != 2 {
+		t.Fatalf("expected 2 votes for B, got %d", got)
+	}
+}
+
+func TestQuestionOutcome(t *testing.T) {
+	yes, no, abstain := YesOption, NoOption, AbstainOption
+	tests := []struct {
+		name      string
+		threshold string
+		turnout   int64 // Minimum turnout of the question
+		ballots   [][]byte
+		outcome   string
+		expected  int64 // Turnout of the question
+	}{
+		{"simple majority", SIMPLE_MAJORITY, 0, [][]byte{yes, yes, no}, QUESTION_PASSED, 30},
+		{"simple majority tie", SIMPLE_MAJORITY, 0, [][]byte{yes, no}, QUESTION_FAILED, 20},
+		{"abstentions do not count", SIMPLE_MAJORITY, 0, [][]byte{yes, abstain, abstain}, QUESTION_PASSED, 30},
+		{"two thirds reached", TWO_THIRDS_MAJORITY, 0, [][]byte{yes, yes, no}, QUESTION_PASSED, 30},
+		{"two thirds missed", TWO_THIRDS_MAJORITY, 0, [][]byte{yes, yes, no, no}, QUESTION_FAILED, 40},
+		{"only abstentions", TWO_THIRDS_MAJORITY, 0, [][]byte{abstain}, QUESTION_FAILED, 10},
+		{"turnout reached with abstentions", SIMPLE_MAJORITY, 30, [][]byte{yes, abstain, abstain}, QUESTION_PASSED, 30},
+		{"turnout missed", SIMPLE_MAJORITY, 50, [][]byte{yes, yes, yes, yes}, QUESTION_FAILED, 40},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			election := TxElectionOutput{
+				TotalPeople: 10,
+				Races: []Race{
+					{ID: "question", Type: QUESTION_RACE, Threshold: test.threshold, MinTurnout: test.turnout},
+				},
+			}
+			if err := election.ValidateFormat(); err != nil {
+				t.Fatal(err)
+			}
+			var ballots []TxBallotInput
+			for _, choice := range test.ballots {
+				ballots = append(ballots, TxBallotInput{Races: []RaceSelection{{"question", [][]byte{choice}}}})
+			}
+			result := tallyBallots(election, ballots)["question"]
+			if result.Outcome != test.outcome || result.Turnout != test.expected {
+				t.Fatalf("expected %s with %d%% turnout, got %s with %d%%", test.outcome, test.expected, result.Outcome, result.Turnout)
+			}
+		})
+	}
+}
@@ -2,14 +2,12 @@ package blockchain
 
 import (
 	"bytes"
-	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/sha256"
 	"encoding/gob"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"math/big"
 	"math/rand"
 	"reflect"
 	"strings"
@@ -24,6 +22,7 @@ const VOTING_TX_TYPE = "voting_tx"
 const ACCREDITATION_TX_TYPE = "accreditation_tx"
 const BALLOT_TX_TYPE = "ballot_tx"
 const ELECTION_TX_TYPE = "election_tx"
+const REVOCATION_TX_TYPE = "revocation_tx"
 
 var (
 	DefaultCurve = elliptic.P256()
@@ -32,6 +31,7 @@ var (
 		ACCREDITATION_TX_TYPE,
 		BALLOT_TX_TYPE,
 		ELECTION_TX_TYPE,
+		REVOCATION_TX_TYPE,
 	}
 	ErrInvalidTransaction       = errors.New("Invalid transaction input")
 	ErrInvalidTransactionID     = errors.New("Invalid transaction ID")
@@ -77,6 +77,10 @@ func (tx *Transaction) verifyElectionTx(prevTx Transaction) (verified bool) {
 	electionIn := tx.Input.ElectionTx
 	// fmt.Println(electionIn.IsSet(), electionOut.IsSet())
 	if electionOut.IsSet() {
+		if err = electionOut.ValidateFormat(); err != nil {
+			logger.Error(err)
+			return false
+		}
 		ms := multisig.MultiSig{
 			PubKeys: electionOut.Signers,
 			Sigs:    electionOut.SigWitnesses,
@@ -212,34 +216,59 @@ func (tx *Transaction) verifyBallotTx(prevTx Transaction) bool {
 		if prevTx.IsSet() == false {
 			return false
 		}
+		// Blind ballots are checked against the election output
+		if ballotIn.IsBlind() {
+			return verifyBlindBallot(ballotIn, prevTx.Output.ElectionTx)
+		}
 
-		numOfKeys := uint(len(ballotIn.PubKeys))
-		keyring := ringsig.NewPublicKeyRing(numOfKeys)
-		keyRingByte := ballotIn.PubKeys
-		for i := 0; i < len(keyRingByte); i++ {
-			pub := keyRingByte[i]
-			x := big.Int{}
-			y := big.Int{}
-			keyLen := len(pub)
-			x.SetBytes(pub[:(keyLen / 2)])
-			y.SetBytes(pub[(keyLen / 2):])
-
-			rawPubKey := ecdsa.PublicKey{Curve: DefaultCurve, X: &x, Y: &y}
-			keyring.Add(rawPubKey)
+		keyring, err := ringsig.ParsePublicKeyRing(ballotIn.PubKeys)
+		if err != nil {
+			logger.Error(err)
+			return false
 		}
 
 		signature := new(ringsig.RingSign)
-		signature.FromByte(ballotIn.Signature)
+		if err := signature.FromByte(ballotIn.Signature); err != nil {
+			logger.Error(err)
+			return false
+		}
 		txCopy := ballotIn.TrimmedCopy()
 		txCopy.ElectionPubKey = prevTx.Output.BallotTx.ElectionPubKey
 		txCopy.PubKeys = prevTx.Output.BallotTx.PubKeys
 
-		verified := ringsig.Verify(keyring, txCopy.ToByte(), signature)
+		// The key image of the voter is bound to the election, see
+		// validateKeyImage
+		verified := ringsig.VerifyLinkable(keyring, txCopy.ToByte(), txCopy.ElectionPubKey, signature)
 
 		return verified
 	}
 	return false
 }
+func (tx *Transaction) verifyRevocationTx(prevTx Transaction) bool {
+	revocationOut := tx.Output.RevocationTx
+	if revocationOut.IsSet() == false || prevTx.IsSet() == false {
+		return false
+	}
+
+	// Only the commission of the election may revoke its certificates
+	election := prevTx.Output.ElectionTx
+	for _, signer := range revocationOut.Signers {
+		if isSigner(election, signer) == false {
+			logger.Errorf("Signer %x is not part of the commission", signer)
+			return false
+		}
+	}
+	ms := multisig.MultiSig{
+		PubKeys: revocationOut.Signers,
+		Sigs:    revocationOut.SigWitnesses,
+	}
+	verified, err := ms.Verify(revocationOut.ToByte())
+	if err != nil {
+		logger.Error(err)
+	}
+	return verified
+}
+
 func (tx *Transaction) Verify(prevTx Transaction) bool {
 	switch tx.Type {
 	case ELECTION_TX_TYPE:
@@ -254,6 +283,9 @@ func (tx *Transaction) Verify(prevTx Transaction) bool {
 	case BALLOT_TX_TYPE:
 		// Verify ballot Transaction
 		return tx.verifyBallotTx(prevTx)
+	case REVOCATION_TX_TYPE:
+		// Verify certificate revocation Transaction
+		return tx.verifyRevocationTx(prevTx)
 	}
 
 	return false
@@ -314,6 +346,8 @@ func (tx *Transaction) String() string {
 	case BALLOT_TX_TYPE:
 		lines = append(lines, tx.Input.BallotTx.String())
 		lines = append(lines, tx.Output.BallotTx.String())
+	case REVOCATION_TX_TYPE:
+		lines = append(lines, tx.Output.RevocationTx.String())
 	}
 
 	return strings.Join(lines, "\n")
@@ -348,6 +382,11 @@ func (tx *Transaction) Valid(utxo UnusedXTOSet) bool {
 				}
 			}
 		case BALLOT_TX_TYPE:
+			// Blind ballots spend their token, checked by the blockchain
+			if tx.Input.BallotTx.IsBlind() {
+				validInput = true
+				break
+			}
 			validoutputs := utxo.FindUnUsedBallotTxOuputs(tx.ElectionPubkey)
 
 			for k := range validoutputs {
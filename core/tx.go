@@ -2,7 +2,10 @@ package blockchain
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/gob"
+	"fmt"
+	"reflect"
 
 	logger "github.com/sirupsen/logrus"
 )
@@ -15,10 +18,11 @@ type TxInput struct {
 }
 
 type TxOutput struct {
-	ElectionTx      TxElectionOutput `json:"election_tx,omitempty"`
-	AccreditationTx TxAcOutput       `json:"accreditation_tx,omitempty"`
-	VotingTx        TxVotingOutput   `json:"voting_tx,omitempty"`
-	BallotTx        TxBallotOutput   `json:"ballot_tx,omitempty"`
+	ElectionTx      TxElectionOutput   `json:"election_tx,omitempty"`
+	AccreditationTx TxAcOutput         `json:"accreditation_tx,omitempty"`
+	VotingTx        TxVotingOutput     `json:"voting_tx,omitempty"`
+	BallotTx        TxBallotOutput     `json:"ballot_tx,omitempty"`
+	RevocationTx    TxRevocationOutput `json:"revocation_tx,omitempty"`
 }
 
 type TxOutputs struct {
@@ -60,6 +64,31 @@ func (out *TxOutput) IsLockWithKeyHash(pubKey []byte) bool {
 	if out.AccreditationTx.IsSet() {
 		return bytes.Compare(out.AccreditationTx.ElectionPubKey, pubKey) == 0
 	}
+	if out.RevocationTx.IsSet() {
+		return bytes.Compare(out.RevocationTx.ElectionPubKey, pubKey) == 0
+	}
 
 	return false
 }
+
+// payloadField is a field added to a transaction after its first version
+type payloadField struct {
+	name  string
+	value interface{}
+}
+
+// signingHash hashes the signing payload of a transaction: its first version,
+// trimmed, followed by the fields added since then that are set. Transactions
+// that do not use the new fields keep the payload they were signed with.
+func signingHash(first interface{}, fields ...payloadField) []byte {
+	payload := fmt.Sprintf("%x", first)
+	for _, field := range fields {
+		value := reflect.ValueOf(field.value)
+		if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+			continue
+		}
+		payload += fmt.Sprintf(" %s:%x", field.name, field.value)
+	}
+	hash := sha256.Sum256([]byte(payload))
+	return hash[:]
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Signing payloads of transactions created before the fields added to them
+// later, computed with the first version of the transactions
+func TestSigningPayloadFirstVersion(t *testing.T) {
+	k := []byte("election")
+	id := []byte("txid")
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{
+			name: "election output",
+			data: (&TxElectionOutput{ID: "x", Signers: [][]byte{[]byte("s")}, ElectionPubKey: k, Title: "Title",
+				Description: "Desc", TotalPeople: 10, Candidates: [][]byte{[]byte("a"), []byte("b")}}).ToByte(),
+			expected: "fbe1e28755d3081d735aae71b42ae0a42f34dafb34cd5775a4b74ff655f08219",
+		},
+		{
+			name:     "accreditation output",
+			data:     (&TxAcOutput{ID: "x", TxID: id, ElectionPubKey: k, Timestamp: 1600000000}).ToByte(),
+			expected: "d7e925a99e35745a625e4a666c3b56210b7e490e998e4ba1a73376c073c0ae26",
+		},
+		{
+			name: "accreditation input",
+			data: (&TxAcInput{TxID: id, TxOut: []byte("out"), ElectionPubKey: k, AccreditedCount: 3,
+				Timestamp: 1600000000}).ToByte(),
+			expected: "07b8c8061647a7a6fcff71209b5f0397fb32e10d3033f49b04d817ed70ba9a7e",
+		},
+		{
+			name: "ballot output",
+			data: (&TxBallotOutput{ID: "x", TxID: id, SecretMessage: []byte("secret"), PubKeys: [][]byte{[]byte("p")},
+				ElectionPubKey: k, Timestamp: 1600000000}).ToByte(),
+			expected: "af06b6db82e12256e6256b87ff93147e06c3a100a0ce7bfece53fb7250830cfa",
+		},
+		{
+			name: "ballot input",
+			data: (&TxBallotInput{TxID: id, Signature: []byte("sig"), TxOut: []byte("out"), Candidate: []byte("a"),
+				ElectionPubKey: k, Timestamp: 1600000000}).ToByte(),
+			expected: "ce4b60c4a207a511564966be05ee53d740a368767fe3606ed816184bf1d1f979",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hex.EncodeToString(test.data); got != test.expected {
+				t.Fatalf("expected payload %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSigningPayloadNewFields(t *testing.T) {
+	election := TxElectionOutput{ElectionPubKey: []byte("election"), Candidates: [][]byte{[]byte("a")}}
+	base := election.ToByte()
+
+	tests := []struct {
+		name   string
+		change func(out *TxElectionOutput)
+		signed bool
+	}{
+		{"ballot format", func(out *TxElectionOutput) { out.BallotFormat = APPROVAL_BALLOT }, true},
+		{"max choices", func(out *TxElectionOutput) { out.MaxChoices = 2 }, true},
+		{"races", func(out *TxElectionOutput) { out.Races = []Race{{ID: "r"}} }, true},
+		{"blind key", func(out *TxElectionOutput) { out.BlindKey = []byte("key") }, true},
+		{"empty races", func(out *TxElectionOutput) { out.Races = []Race{} }, false},
+		{"signers", func(out *TxElectionOutput) { out.Signers = [][]byte{[]byte("s")} }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := election
+			test.change(&out)
+			if changed := string(out.ToByte()) != string(base); changed != test.signed {
+				t.Fatalf("expected payload change %v, got %v", test.signed, changed)
+			}
+		})
+	}
+
+	// The same value in two new fields gives two payloads
+	first := TxBallotInput{Token: []byte("value")}
+	second := TxBallotInput{TokenSignature: []byte("value")}
+	if string(first.ToByte()) == string(second.ToByte()) {
+		t.Fatal("fields are not told apart in the payload")
+	}
+}
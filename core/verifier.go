@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// BlockVerifier checks the transactions of a block concurrently with a fixed
+// number of workers. Verification stops at the first invalid transaction.
+type BlockVerifier struct {
+	workers int
+}
+
+// NewBlockVerifier creates a block verifier with the given number of workers,
+// one per CPU when workers is not positive
+func NewBlockVerifier(workers int) *BlockVerifier {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &BlockVerifier{workers}
+}
+
+// Verify runs the check on every transaction and returns the error of the
+// first invalid transaction in block order. Transactions not picked up by a
+// worker yet are skipped once a check fails, the transactions before it were
+// all picked up so the reported error does not depend on the scheduling.
+func (v *BlockVerifier) Verify(txs []*Transaction, check func(tx *Transaction) error) error {
+	jobs := make(chan int)
+	abort := make(chan struct{})
+	var once sync.Once
+	var mutex sync.Mutex
+	failed := -1
+	var failure error
+
+	var wg sync.WaitGroup
+	for w := 0; w < v.workers && w < len(txs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				err := check(txs[i])
+				if err == nil {
+					continue
+				}
+				mutex.Lock()
+				if failed < 0 || i < failed {
+					failed, failure = i, fmt.Errorf("transaction %d (%x): %w", i, txs[i].ID, err)
+				}
+				mutex.Unlock()
+				once.Do(func() { close(abort) })
+			}
+		}()
+	}
+
+dispatch:
+	for i := range txs {
+		select {
+		case jobs <- i:
+		case <-abort:
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return failure
+}
@@ -0,0 +1,173 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/ringsig"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+func testTxs(n int) []*Transaction {
+	txs := make([]*Transaction, n)
+	for i := range txs {
+		txs[i] = &Transaction{ID: []byte(fmt.Sprintf("tx%d", i))}
+	}
+	return txs
+}
+
+// txIndex returns the index of a transaction of testTxs
+func txIndex(tx *Transaction) int {
+	var i int
+	fmt.Sscanf(string(tx.ID), "tx%d", &i)
+	return i
+}
+
+func TestNewBlockVerifier(t *testing.T) {
+	if v := NewBlockVerifier(0); v.workers != runtime.NumCPU() {
+		t.Errorf("expected one worker per CPU, got %d", v.workers)
+	}
+	if v := NewBlockVerifier(3); v.workers != 3 {
+		t.Errorf("expected 3 workers, got %d", v.workers)
+	}
+}
+
+func TestBlockVerifierWorkers(t *testing.T) {
+	for _, workers := range []int{1, 2, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			var active, most, checked int32
+			check := func(tx *Transaction) error {
+				n := atomic.AddInt32(&active, 1)
+				for {
+					m := atomic.LoadInt32(&most)
+					if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&active, -1)
+				atomic.AddInt32(&checked, 1)
+				return nil
+			}
+
+			if err := NewBlockVerifier(workers).Verify(testTxs(32), check); err != nil {
+				t.Fatal(err)
+			}
+			if checked != 32 {
+				t.Fatalf("expected every transaction to be checked, got %d", checked)
+			}
+			if most > int32(workers) {
+				t.Fatalf("expected at most %d concurrent checks, got %d", workers, most)
+			}
+		})
+	}
+}
+
+func TestBlockVerifierAbort(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	var checked int32
+	check := func(tx *Transaction) error {
+		atomic.AddInt32(&checked, 1)
+		if txIndex(tx) == 0 {
+			return errInvalid
+		}
+		time.Sleep(100 * time.Microsecond)
+		return nil
+	}
+
+	txs := testTxs(1000)
+	err := NewBlockVerifier(2).Verify(txs, check)
+	if errors.Is(err, errInvalid) == false {
+		t.Fatalf("expected %v, got %v", errInvalid, err)
+	}
+	if checked == int32(len(txs)) {
+		t.Fatal("expected the transactions after the invalid one to be skipped")
+	}
+}
+
+func TestBlockVerifierDeterministicError(t *testing.T) {
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+	check := func(tx *Transaction) error {
+		switch txIndex(tx) {
+		case 5:
+			// The first invalid transaction fails after the second one
+			time.Sleep(2 * time.Millisecond)
+			return errFirst
+		case 7:
+			return errSecond
+		}
+		return nil
+	}
+
+	for run := 0; run < 20; run++ {
+		err := NewBlockVerifier(8).Verify(testTxs(16), check)
+		if errors.Is(err, errFirst) == false || strings.HasPrefix(err.Error(), "transaction 5 ") == false {
+			t.Fatalf("run %d: expected the error of transaction 5, got %v", run, err)
+		}
+	}
+}
+
+// ringBallots returns ballots ring signed by members of a ring of the given
+// size, each with the ballot output it spends
+func ringBallots(b *testing.B, ballots, size int) ([]*Transaction, map[string]Transaction) {
+	b.Helper()
+	election := []byte("election")
+	var privKeys []*ecdsa.PrivateKey
+	var ring [][]byte
+	for i := 0; i < size; i++ {
+		priv, pub := wallet.NewKeyPair()
+		privKeys = append(privKeys, priv)
+		ring = append(ring, pub)
+	}
+	keyring, err := ringsig.ParsePublicKeyRing(ring)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	txs := make([]*Transaction, ballots)
+	prevTxs := make(map[string]Transaction)
+	for i := range txs {
+		out := NewBallotTxOutput(election, nil, nil, ring, nil, nil, time.Now().Unix())
+		prev := Transaction{ID: []byte(fmt.Sprintf("out%d", i)), Type: BALLOT_TX_TYPE, ElectionPubkey: election, Output: *out}
+
+		in := NewBallotTxInput(election, []byte("candidate"), nil, prev.ID, nil, ring, time.Now().Unix())
+		sig, err := ringsig.SignLinkable(privKeys[i%size], keyring, in.BallotTx.ToByte(), election)
+		if err != nil {
+			b.Fatal(err)
+		}
+		in.BallotTx.Signature = sig.ToByte()
+		txs[i] = &Transaction{ID: []byte(fmt.Sprintf("in%d", i)), Type: BALLOT_TX_TYPE, ElectionPubkey: election, Input: *in}
+		prevTxs[string(txs[i].ID)] = prev
+	}
+	return txs, prevTxs
+}
+
+// BenchmarkBlockVerifier verifies a block of ring signed ballots with several
+// worker counts
+func BenchmarkBlockVerifier(b *testing.B) {
+	for _, ballots := range []int{16, 64} {
+		txs, prevTxs := ringBallots(b, ballots, 16)
+		check := func(tx *Transaction) error {
+			if tx.Verify(prevTxs[string(tx.ID)]) == false {
+				return ErrInvalidTransaction
+			}
+			return nil
+		}
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("Ballots_%d/Workers_%d", ballots, workers), func(b *testing.B) {
+				verifier := NewBlockVerifier(workers)
+				for i := 0; i < b.N; i++ {
+					if err := verifier.Verify(txs, check); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrNotInVoterRoll = errors.New("Voter is not part of the voter roll")
+)
+
+// VoterRoll is the list of eligible voter public keys of an election. Only its
+// merkle root is committed on-chain by the accreditation transaction.
+type VoterRoll struct {
+	keys [][]byte
+	tree *MerkleTree
+}
+
+// NewVoterRoll builds the merkle tree of the eligible voter keys. Keys are
+// stored with their on-chain encoding and sorted so the same roll always
+// yields the same root.
+func NewVoterRoll(keys [][]byte) (*VoterRoll, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("Voter roll is empty")
+	}
+	sorted := make([][]byte, len(keys))
+	for i, key := range keys {
+		sorted[i] = []byte(keyID(key))
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+	for i := 1; i < len(sorted); i++ {
+		if bytes.Compare(sorted[i-1], sorted[i]) == 0 {
+			return nil, fmt.Errorf("Voter %x appears twice in the voter roll", sorted[i])
+		}
+	}
+
+	return &VoterRoll{sorted, NewMerkleTree(sorted)}, nil
+}
+
+// Root returns the merkle root committed by the accreditation transaction
+func (roll *VoterRoll) Root() []byte {
+	return roll.tree.RootNode.Data
+}
+
+// Proof returns the membership proof of a voter key
+func (roll *VoterRoll) Proof(key []byte) (MerkleProof, error) {
+	key = []byte(keyID(key))
+	index := sort.Search(len(roll.keys), func(i int) bool {
+		return bytes.Compare(roll.keys[i], key) >= 0
+	})
+	if index == len(roll.keys) || bytes.Compare(roll.keys[index], key) != 0 {
+		return MerkleProof{}, ErrNotInVoterRoll
+	}
+
+	return roll.tree.Proof(index)
+}
+
+// VerifyRollProof checks that the key is part of the voter roll with the
+// given merkle root
+func VerifyRollProof(root, key []byte, proof MerkleProof) bool {
+	return VerifyProof(root, []byte(keyID(key)), proof)
+}
+
+// validateVoterRoll checks that every key a ballot output is issued to is
+// part of the voter roll committed when accreditation started. Elections
+// accredited without a voter roll are not checked.
+func (bc *Blockchain) validateVoterRoll(tx *Transaction) error {
+	ballotOut := tx.Output.BallotTx
+	if ballotOut.IsSet() == false {
+		return nil
+	}
+
+	txAc, err := bc.FindTxWithAcOutByPubkey(tx.ElectionPubkey)
+	if err != nil {
+		return err
+	}
+	root := txAc.Output.AccreditationTx.VoterRoot
+	if txAc.Output.AccreditationTx.IsSet() == false || len(root) == 0 {
+		return nil
+	}
+
+	if len(ballotOut.VoterProofs) != len(ballotOut.PubKeys) {
+		return fmt.Errorf("%w: %d proofs for %d keys", ErrNotInVoterRoll, len(ballotOut.VoterProofs), len(ballotOut.PubKeys))
+	}
+	for i, key := range ballotOut.PubKeys {
+		if VerifyRollProof(root, key, ballotOut.VoterProofs[i]) == false {
+			return fmt.Errorf("%w: %x", ErrNotInVoterRoll, key)
+		}
+	}
+	return nil
+}
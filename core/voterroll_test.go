@@ -0,0 +1,86 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVoterRoll(t *testing.T) {
+	voters := [][]byte{[]byte("carol"), []byte("alice"), []byte("bob")}
+	roll, err := NewVoterRoll(voters)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reversed := [][]byte{voters[2], voters[1], voters[0]}
+	other, err := NewVoterRoll(reversed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(roll.Root(), other.Root()) != 0 {
+		t.Error("root depends on the order of the voters")
+	}
+
+	for _, voter := range voters {
+		proof, err := roll.Proof(voter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if VerifyRollProof(roll.Root(), voter, proof) == false {
+			t.Errorf("proof of %s does not verify", voter)
+		}
+		if VerifyRollProof(roll.Root(), []byte("mallory"), proof) {
+			t.Errorf("proof of %s verifies for another voter", voter)
+		}
+	}
+
+	if _, err := roll.Proof([]byte("mallory")); err != ErrNotInVoterRoll {
+		t.Errorf("expected %v, got %v", ErrNotInVoterRoll, err)
+	}
+}
+
+func TestNewVoterRollInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		voters [][]byte
+	}{
+		{"empty roll", nil},
+		{"duplicate voter", [][]byte{[]byte("alice"), []byte("bob"), []byte("alice")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewVoterRoll(test.voters); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestVoterRollKeyEncodings(t *testing.T) {
+	voters := newTestKeys(t, 3)
+	other := uncompressed(t, voters)
+
+	roll, err := NewVoterRoll(voters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixed, err := NewVoterRoll([][]byte{other[0], voters[1], other[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(roll.Root(), mixed.Root()) != 0 {
+		t.Error("root depends on the encoding of the voters")
+	}
+
+	proof, err := roll.Proof(other[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifyRollProof(roll.Root(), other[1], proof) == false || VerifyRollProof(roll.Root(), voters[1], proof) == false {
+		t.Error("proof does not verify for both encodings")
+	}
+
+	if _, err := NewVoterRoll([][]byte{voters[0], other[0]}); err == nil {
+		t.Error("expected a voter given with two encodings to be rejected")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/elliptic"
 	"fmt"
 	"log"
-	"math/big"
 	"time"
 
 	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
@@ -60,18 +59,10 @@ func TestCrypto() {
 	// // message = []byte("Big Is Watching")
 	// fmt.Println(ringsig.Verify(keyring, message, signature))
 
-	keyring = ringsig.NewPublicKeyRing(numOfKeys)
 	fmt.Println(len(keyRingByte))
-	for i := 0; i < len(keyRingByte); i++ {
-		pub := keyRingByte[i]
-		x := big.Int{}
-		y := big.Int{}
-		keyLen := len(pub)
-		x.SetBytes(pub[:(keyLen / 2)])
-		y.SetBytes(pub[(keyLen / 2):])
-
-		rawPubKey := ecdsa.PublicKey{Curve: DefaultCurve, X: &x, Y: &y}
-		keyring.Add(rawPubKey)
+	keyring, err = ringsig.ParsePublicKeyRing(keyRingByte)
+	if err != nil {
+		log.Panic(err)
 	}
 
 	byteSig := signature.ToByte()
@@ -346,10 +337,11 @@ func CastBallot(txId []byte, txOut []byte, utxo *blockchain.UnusedXTOSet) *block
 	)
 
 	// Sign message
-	signature, err := ringsig.Sign(
+	signature, err := ringsig.SignLinkable(
 		&sysWallet.Main.PrivateKey,
 		keyring,
 		bTxIn.BallotTx.ToByte(),
+		electionPubkey,
 	)
 	if err != nil {
 		log.Panic(err)
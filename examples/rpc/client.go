@@ -14,6 +14,7 @@ import (
 	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
 	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
 	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/ringsig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/prompt"
 	"github.com/thedhejavu/ev-blockchain-protocol/rpc"
 	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
 )
@@ -21,6 +22,7 @@ import (
 const numOfKeys = 3
 
 var (
+	passphrase   []byte
 	DefaultCurve = elliptic.P256()
 	keyring      *ringsig.PublicKeyRing
 	signers      [][]byte
@@ -28,6 +30,61 @@ var (
 	sigCount     = 4
 )
 
+// walletPassphrase asks once for the passphrase of the engine wallets
+func walletPassphrase() []byte {
+	if passphrase == nil {
+		var err error
+		if passphrase, err = prompt.Passphrase("Wallets passphrase: "); err != nil {
+			logger.Panic(err)
+		}
+	}
+	return passphrase
+}
+
+// saveWallet encrypts the wallet into its keystore
+func saveWallet(wallets *wallet.Wallets, userId string) {
+	if err := wallets.Save(userId, walletPassphrase()); err != nil {
+		logger.Panic(err)
+	}
+}
+
+// newWallets creates and saves the wallets prefix_0 to prefix_count-1
+func newWallets(prefix string, count int) []wallet.WalletGroup {
+	wallets, err := wallet.InitializeWallets()
+	if err != nil {
+		logger.Panic(err)
+	}
+	var groups []wallet.WalletGroup
+	for i := 0; i < count; i++ {
+		userId := wallets.AddWallet(fmt.Sprintf("%s_%d", prefix, i))
+		saveWallet(wallets, userId)
+		w, err := wallets.GetWallet(userId)
+		if err != nil {
+			logger.Panic(err)
+		}
+		groups = append(groups, w)
+	}
+	return groups
+}
+
+// unlockWallets unlocks the wallets prefix_0 to prefix_count-1 before they
+// are used
+func unlockWallets(prefix string, count int) []wallet.WalletGroup {
+	wallets, err := wallet.InitializeWallets()
+	if err != nil {
+		logger.Panic(err)
+	}
+	var groups []wallet.WalletGroup
+	for i := 0; i < count; i++ {
+		w, err := wallets.Unlock(fmt.Sprintf("%s_%d", prefix, i), walletPassphrase())
+		if err != nil {
+			logger.Panic(err)
+		}
+		groups = append(groups, w)
+	}
+	return groups
+}
+
 type BlockchainRepo struct {
 	client *rpc.Client
 }
@@ -149,17 +206,10 @@ func (repo *BlockchainRepo) StartElection(pubkey, title, description string, tot
 		Candidates:     candidates,
 	}
 
+	commission := newWallets("signers", sigCount)
 	mu := multisig.NewMultisig(sigCount)
-	for i := 0; i < sigCount; i++ {
-		// Initialize system identity wallet
-		wallets, _ := wallet.InitializeWallets()
-		// Add new identity to the wallet with the User ID
-		userId := wallets.AddWallet(fmt.Sprintf("signers_%d", i))
-		wallets.Save()
-		w, err := wallets.GetWallet(userId)
-		if err != nil {
-			logger.Panic(err)
-		}
+	for i := range commission {
+		w := &commission[i]
 		mu.AddSignature(
 			txOut.ToByte(),
 			w.Main.PublicKey,
@@ -211,15 +261,10 @@ func (repo *BlockchainRepo) StopElection(pubkey string) (rpc.Result, error) {
 		ElectionPubKey: electionPubkey,
 	}
 
+	commission := unlockWallets("signers", sigCount)
 	mu := multisig.NewMultisig(sigCount)
-	for i := 0; i < sigCount; i++ {
-		// Initialize system identity wallet
-		wallets, _ := wallet.InitializeWallets()
-		userId := fmt.Sprintf("signers_%d", i)
-		w, err := wallets.GetWallet(userId)
-		if err != nil {
-			logger.Panic(err)
-		}
+	for i := range commission {
+		w := &commission[i]
 		mu.AddSignature(
 			txIn.ToByte(),
 			w.Main.PublicKey,
@@ -272,15 +317,10 @@ func (repo *BlockchainRepo) StartAccreditation(pubkey, txElectionOutId string) (
 		Timestamp:      time.Now().Unix(),
 	}
 
+	commission := unlockWallets("signers", sigCount)
 	mu := multisig.NewMultisig(sigCount)
-	for i := 0; i < sigCount; i++ {
-		// Initialize system identity wallet
-		wallets, _ := wallet.InitializeWallets()
-		userId := fmt.Sprintf("signers_%d", i)
-		w, err := wallets.GetWallet(userId)
-		if err != nil {
-			logger.Panic(err)
-		}
+	for i := range commission {
+		w := &commission[i]
 		mu.AddSignature(
 			txOut.ToByte(),
 			w.Main.PublicKey,
@@ -335,15 +375,10 @@ func (repo *BlockchainRepo) StopAccreditation(pubkey, txElectionOutId string, tx
 		AccreditedCount: 100,
 	}
 
+	commission := unlockWallets("signers", sigCount)
 	mu := multisig.NewMultisig(sigCount)
-	for i := 0; i < sigCount; i++ {
-		// Initialize system identity wallet
-		wallets, _ := wallet.InitializeWallets()
-		userId := fmt.Sprintf("signers_%d", i)
-		w, err := wallets.GetWallet(userId)
-		if err != nil {
-			logger.Panic(err)
-		}
+	for i := range commission {
+		w := &commission[i]
 		mu.AddSignature(
 			txIn.ToByte(),
 			w.Main.PublicKey,
@@ -396,15 +431,10 @@ func (repo *BlockchainRepo) StartVoting(pubkey string, txElectionOutId string) (
 		Timestamp:      time.Now().Unix(),
 	}
 
+	commission := unlockWallets("signers", sigCount)
 	mu := multisig.NewMultisig(sigCount)
-	for i := 0; i < sigCount; i++ {
-		// Initialize system identity wallet
-		wallets, _ := wallet.InitializeWallets()
-		userId := fmt.Sprintf("signers_%d", i)
-		w, err := wallets.GetWallet(userId)
-		if err != nil {
-			logger.Panic(err)
-		}
+	for i := range commission {
+		w := &commission[i]
 		mu.AddSignature(
 			txOut.ToByte(),
 			w.Main.PublicKey,
@@ -459,15 +489,10 @@ func (repo *BlockchainRepo) StopVoting(pubkey, txElectionOutId, txVotingOutId st
 		Timestamp:      time.Now().Unix(),
 	}
 
+	commission := unlockWallets("signers", sigCount)
 	mu := multisig.NewMultisig(sigCount)
-	for i := 0; i < sigCount; i++ {
-		// Initialize system identity wallet
-		wallets, _ := wallet.InitializeWallets()
-		userId := fmt.Sprintf("signers_%d", i)
-		w, err := wallets.GetWallet(userId)
-		if err != nil {
-			logger.Panic(err)
-		}
+	for i := range commission {
+		w := &commission[i]
 		mu.AddSignature(
 			txIn.ToByte(),
 			w.Main.PublicKey,
@@ -515,21 +540,14 @@ func (repo *BlockchainRepo) CreateBallot(userId, pubkey, txElectionOutId string)
 	electionPubkey, _ := base64.StdEncoding.DecodeString(pubkey)
 	secretMessage := []byte("This is my ballot secret message")
 	wallets, _ := wallet.InitializeWallets()
-	userWallet, _ := wallets.GetWallet(userId)
+	userWallet, _ := wallets.Unlock(userId, walletPassphrase())
 	msg, _ := userWallet.View.Encrypt(secretMessage)
 
 	keyRingByte = append(keyRingByte, userWallet.Main.PublicKey)
 	// Generate Decoy keys
-	for i := 0; i < numOfKeys-1; i++ {
-
-		// Initialize system identity wallet
-		wallets, _ := wallet.InitializeWallets()
-		userId := wallets.AddWallet(fmt.Sprintf("decoy_%d", i))
-		wallets.Save()
-		w, _ := wallets.GetWallet(userId)
+	for _, w := range newWallets("decoy", numOfKeys-1) {
 		// add the public key part to the ring
 		keyRingByte = append(keyRingByte, w.Main.PublicKey)
-
 	}
 
 	txOut := blockchain.TxBallotOutput{
@@ -542,15 +560,10 @@ func (repo *BlockchainRepo) CreateBallot(userId, pubkey, txElectionOutId string)
 		Timestamp:      time.Now().Unix(),
 	}
 
+	commission := unlockWallets("signers", sigCount)
 	mu := multisig.NewMultisig(sigCount)
-	for i := 0; i < sigCount; i++ {
-		// Initialize system identity wallet
-		wallets, _ := wallet.InitializeWallets()
-		userId := fmt.Sprintf("signers_%d", i)
-		w, err := wallets.GetWallet(userId)
-		if err != nil {
-			logger.Panic(err)
-		}
+	for i := range commission {
+		w := &commission[i]
 		mu.AddSignature(
 			txOut.ToByte(),
 			w.Main.PublicKey,
@@ -597,7 +610,7 @@ func (repo *BlockchainRepo) CastBallot(userId, pubkey, txElectionOutId, candidat
 	txId, _ := base64.StdEncoding.DecodeString(txElectionOutId)
 	wallets, _ := wallet.InitializeWallets()
 	// Get user wallet from UserId
-	userWallet, _ := wallets.GetWallet(userId)
+	userWallet, _ := wallets.Unlock(userId, walletPassphrase())
 
 	var txOut []byte
 	var keyRingByte [][]byte
@@ -618,12 +631,10 @@ func (repo *BlockchainRepo) CastBallot(userId, pubkey, txElectionOutId, candidat
 	keyring = ringsig.NewPublicKeyRing(numOfKeys)
 	keyring.Add(userWallet.Main.PrivateKey.PublicKey)
 	// Generate Decoy keys
-	for i := 0; i < numOfKeys-1; i++ {
-		// Initialize system identity wallet
-		userId := fmt.Sprintf("decoy_%d", i)
-		w, _ := wallets.GetWallet(userId)
+	decoys := unlockWallets("decoy", numOfKeys-1)
+	for i := range decoys {
 		// add the public key part to the ring
-		keyring.Add(w.Main.PrivateKey.PublicKey)
+		keyring.Add(decoys[i].Main.PrivateKey.PublicKey)
 	}
 
 	txIn := blockchain.TxBallotInput{
@@ -637,10 +648,11 @@ func (repo *BlockchainRepo) CastBallot(userId, pubkey, txElectionOutId, candidat
 	}
 
 	// Sign message
-	signature, err := ringsig.Sign(
+	signature, err := ringsig.SignLinkable(
 		&userWallet.Main.PrivateKey,
 		keyring,
 		txIn.ToByte(),
+		electionPubkey,
 	)
 
 	txIn.Signature = signature.ToByte()
@@ -674,12 +686,7 @@ func (repo *BlockchainRepo) CastBallot(userId, pubkey, txElectionOutId, candidat
 func Candidates() [][]byte {
 	var candidates [][]byte
 
-	for i := 0; i < 4; i++ {
-		wallets, _ := wallet.InitializeWallets()
-		// Add new identity to the wallet with the User ID
-		userId := wallets.AddWallet(fmt.Sprintf("candidates_%d", i))
-		wallets.Save()
-		w, _ := wallets.GetWallet(userId)
+	for _, w := range newWallets("candidates", 4) {
 		candidates = append(candidates, w.Main.PublicKey)
 	}
 
@@ -688,10 +695,7 @@ func Candidates() [][]byte {
 func GetCandidates() [][]byte {
 	var candidates [][]byte
 
-	for i := 0; i < 4; i++ {
-		wallets, _ := wallet.InitializeWallets()
-		userId := fmt.Sprintf("candidates_%d", i)
-		w, _ := wallets.GetWallet(userId)
+	for _, w := range unlockWallets("candidates", 4) {
 		candidates = append(candidates, w.Main.PublicKey)
 	}
 
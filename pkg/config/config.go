@@ -1,3 +1,11 @@
 package config
 
-type Config struct{}
+type Config struct {
+	// Number of workers verifying the transactions of a block, one per CPU
+	// when not set
+	VerifierWorkers int
+
+	// Public keys of the nodes whose ballot receipts are trusted, the key of
+	// the node signer is always trusted
+	ReceiptKeys [][]byte
+}
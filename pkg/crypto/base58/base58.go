@@ -67,6 +67,16 @@ func (b Base58) ToHex() []byte {
 	return append(make([]byte, oneCount), value.Bytes()...) //convert big.Int to bytes
 }
 
+//checks that every letter is part of the alphabet
+func (b Base58) IsValid() bool {
+	for i := 0; i < len(b); i++ {
+		if _, ok := revalp[string(b[i:i+1])]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func (b Base58) Base582Big() *big.Int {
 	answer := new(big.Int)
 	for i := 0; i < len(b); i++ {
@@ -0,0 +1,208 @@
+// Package blindsig implements Chaum blind signatures with RSA and a full
+// domain hash. The voter blinds a token before handing it to the commission,
+// the commission signs it without seeing it and the voter unblinds the
+// signature into a regular signature of the token. The commission cannot link
+// the token it later sees on a ballot to the voter it signed it for.
+//
+// The signing key must only be used for blind signatures, the signer signs
+// whatever value it is given.
+package blindsig
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"math/big"
+)
+
+const (
+	// Size of the RSA keys generated for blind signatures
+	KeyBits = 2048
+
+	publicKeyType  = "RSA PUBLIC KEY"
+	privateKeyType = "RSA PRIVATE KEY"
+)
+
+var (
+	ErrInvalidKey       = errors.New("Invalid blind signature key")
+	ErrInvalidBlinded   = errors.New("Blinded message is out of range")
+	ErrInvalidSignature = errors.New("Invalid blind signature")
+
+	one = big.NewInt(1)
+)
+
+// GenerateKey creates the RSA key of the commission signing the tokens
+func GenerateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, KeyBits)
+}
+
+// MarshalPublicKey encodes the public key as a PEM block
+func MarshalPublicKey(pub *rsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyType, Bytes: der}), nil
+}
+
+// ParsePublicKey decodes a PEM encoded public key
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != publicKeyType {
+		return nil, ErrInvalidKey
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok || pub.N.BitLen() < KeyBits {
+		return nil, ErrInvalidKey
+	}
+	return pub, nil
+}
+
+// MarshalPrivateKey encodes the private key as a PEM block
+func MarshalPrivateKey(priv *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyType, Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+}
+
+// ParsePrivateKey decodes a PEM encoded private key
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != privateKeyType {
+		return nil, ErrInvalidKey
+	}
+	priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil || priv.N.BitLen() < KeyBits {
+		return nil, ErrInvalidKey
+	}
+	return priv, nil
+}
+
+// hashToInt hashes the message to an integer modulo N, the digest is expanded
+// to the size of the modulus with a counter
+func hashToInt(pub *rsa.PublicKey, message []byte) *big.Int {
+	size := (pub.N.BitLen() + 7) / 8
+	digest := make([]byte, 0, size+sha256.Size)
+	var counter [4]byte
+	for i := uint32(0); len(digest) < size; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h := sha256.New()
+		h.Write(counter[:])
+		h.Write(message)
+		digest = h.Sum(digest)
+	}
+	m := new(big.Int).SetBytes(digest[:size])
+	return m.Mod(m, pub.N)
+}
+
+// toBytes encodes a value modulo N with the size of the modulus
+func toBytes(pub *rsa.PublicKey, x *big.Int) []byte {
+	out := make([]byte, (pub.N.BitLen()+7)/8)
+	b := x.Bytes()
+	copy(out[len(out)-len(b):], b)
+	return out
+}
+
+// fromBytes decodes a value that must be in the range [1, N)
+func fromBytes(pub *rsa.PublicKey, data []byte) (*big.Int, bool) {
+	if len(data) != (pub.N.BitLen()+7)/8 {
+		return nil, false
+	}
+	x := new(big.Int).SetBytes(data)
+	if x.Sign() <= 0 || x.Cmp(pub.N) >= 0 {
+		return nil, false
+	}
+	return x, true
+}
+
+// Blind hides the message with a random factor. The blinded message goes to
+// the signer, the unblinder stays with the voter to unblind the signature.
+func Blind(pub *rsa.PublicKey, message []byte) (blinded, unblinder []byte, err error) {
+	var r, rInv *big.Int
+	for rInv == nil {
+		if r, err = rand.Int(rand.Reader, pub.N); err != nil {
+			return nil, nil, err
+		}
+		if r.Cmp(one) <= 0 {
+			continue
+		}
+		rInv = new(big.Int).ModInverse(r, pub.N)
+	}
+
+	// m * r^e mod N
+	m := hashToInt(pub, message)
+	re := new(big.Int).Exp(r, big.NewInt(int64(pub.E)), pub.N)
+	m.Mul(m, re).Mod(m, pub.N)
+
+	return toBytes(pub, m), toBytes(pub, r), nil
+}
+
+// Sign signs a blinded message
+func Sign(priv *rsa.PrivateKey, blinded []byte) ([]byte, error) {
+	m, ok := fromBytes(&priv.PublicKey, blinded)
+	if !ok {
+		return nil, ErrInvalidBlinded
+	}
+	s := new(big.Int).Exp(m, priv.D, priv.N)
+
+	// A faulty signature could leak the key, check it before releasing it
+	sig := toBytes(&priv.PublicKey, s)
+	if VerifyBlinded(&priv.PublicKey, blinded, sig) == false {
+		return nil, ErrInvalidSignature
+	}
+	return sig, nil
+}
+
+// VerifyBlinded checks the signature of a blinded message
+func VerifyBlinded(pub *rsa.PublicKey, blinded, blindSig []byte) bool {
+	m, ok := fromBytes(pub, blinded)
+	if !ok {
+		return false
+	}
+	s, ok := fromBytes(pub, blindSig)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Exp(s, big.NewInt(int64(pub.E)), pub.N).Cmp(m) == 0
+}
+
+// Unblind turns the signature of the blinded message into a signature of the
+// message
+func Unblind(pub *rsa.PublicKey, message, blindSig, unblinder []byte) ([]byte, error) {
+	s, ok := fromBytes(pub, blindSig)
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+	r, ok := fromBytes(pub, unblinder)
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+	rInv := new(big.Int).ModInverse(r, pub.N)
+	if rInv == nil {
+		return nil, ErrInvalidSignature
+	}
+
+	// s' * r^-1 mod N
+	s.Mul(s, rInv).Mod(s, pub.N)
+	sig := toBytes(pub, s)
+	if Verify(pub, message, sig) == false {
+		return nil, ErrInvalidSignature
+	}
+	return sig, nil
+}
+
+// Verify checks the unblinded signature of the message
+func Verify(pub *rsa.PublicKey, message, sig []byte) bool {
+	s, ok := fromBytes(pub, sig)
+	if !ok {
+		return false
+	}
+	m := hashToInt(pub, message)
+	return new(big.Int).Exp(s, big.NewInt(int64(pub.E)), pub.N).Cmp(m) == 0
+}
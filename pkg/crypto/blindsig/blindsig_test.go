@@ -0,0 +1,117 @@
+package blindsig
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBlindSignUnblind(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &priv.PublicKey
+	token := []byte("one-time ballot token")
+
+	blinded, unblinder, err := Blind(pub, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(blinded, toBytes(pub, hashToInt(pub, token))) {
+		t.Fatal("blinded message reveals the token")
+	}
+	blindSig, err := Sign(priv, blinded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifyBlinded(pub, blinded, blindSig) == false {
+		t.Fatal("blind signature does not verify")
+	}
+
+	sig, err := Unblind(pub, token, blindSig, unblinder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Verify(pub, token, sig) == false {
+		t.Fatal("unblinded signature does not verify")
+	}
+	if bytes.Equal(sig, blindSig) {
+		t.Fatal("unblinded signature equals the blind signature")
+	}
+	if Verify(pub, []byte("another token"), sig) {
+		t.Fatal("signature verifies another token")
+	}
+	sig[len(sig)-1] ^= 1
+	if Verify(pub, token, sig) {
+		t.Fatal("tampered signature verifies")
+	}
+}
+
+func TestBlindingIsRandomized(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := []byte("token")
+	first, _, err := Blind(&priv.PublicKey, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := Blind(&priv.PublicKey, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, second) {
+		t.Fatal("same token blinded twice gives the same message")
+	}
+}
+
+func TestOtherKey(t *testing.T) {
+	priv, _ := GenerateKey()
+	other, _ := GenerateKey()
+	token := []byte("token")
+
+	blinded, unblinder, err := Blind(&priv.PublicKey, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The blinded message may not even fit the modulus of the other key
+	blindSig, err := Sign(other, blinded)
+	if err != nil {
+		return
+	}
+	if VerifyBlinded(&priv.PublicKey, blinded, blindSig) {
+		t.Fatal("signature of another key verifies")
+	}
+	if _, err = Unblind(&priv.PublicKey, token, blindSig, unblinder); err != ErrInvalidSignature {
+		t.Fatalf("expected %v, got %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParsePublicKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.N.Cmp(priv.N) != 0 || pub.E != priv.E {
+		t.Fatal("public key changed")
+	}
+	parsed, err := ParsePrivateKey(MarshalPrivateKey(priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.D.Cmp(priv.D) != 0 {
+		t.Fatal("private key changed")
+	}
+	if _, err = ParsePublicKey([]byte("not a key")); err != ErrInvalidKey {
+		t.Fatalf("expected %v, got %v", ErrInvalidKey, err)
+	}
+}
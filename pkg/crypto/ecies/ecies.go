@@ -0,0 +1,110 @@
+// Package ecies encrypts messages to a P-256 public key with an ephemeral
+// ECDH key agreement, HKDF-SHA256 and AES-256-GCM.
+//
+// A ciphertext is 0x00 | version | ephemeral public key (SEC1 compressed) |
+// nonce | sealed message. The leading zero and version tell it apart from the
+// untagged RSA ciphertexts of earlier versions.
+package ecies
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Version of the ciphertexts produced by Encrypt
+const Version byte = 0x01
+
+const (
+	headerSize = 2 + keys.CompressedSize
+	keySize    = 32
+	nonceSize  = 12
+	kdfInfo    = "ev-blockchain-protocol/ecies"
+)
+
+var (
+	ErrInvalidCiphertext = errors.New("Invalid ciphertext")
+	ErrDecryption        = errors.New("Message cannot be decrypted with this key")
+)
+
+var curve = elliptic.P256()
+
+// IsHybrid reports whether the ciphertext has the header of this scheme
+func IsHybrid(ciphertext []byte) bool {
+	return len(ciphertext) > headerSize && ciphertext[0] == 0x00 && ciphertext[1] == Version
+}
+
+// Encrypt encrypts the message to the public key
+func Encrypt(pub *ecdsa.PublicKey, message []byte) ([]byte, error) {
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, keys.ErrInvalidKey
+	}
+	ephemeral, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte{0x00, Version}, keys.FromECDSA(&ephemeral.PublicKey).Compressed()...)
+
+	x, _ := curve.ScalarMult(pub.X, pub.Y, ephemeral.D.Bytes())
+	aead, err := newAEAD(x, header, pub)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	ciphertext := append(header, nonce...)
+	return aead.Seal(ciphertext, nonce, message, header), nil
+}
+
+// Decrypt decrypts a ciphertext encrypted to the public key of priv
+func Decrypt(priv *ecdsa.PrivateKey, ciphertext []byte) ([]byte, error) {
+	if !IsHybrid(ciphertext) || len(ciphertext) < headerSize+nonceSize {
+		return nil, ErrInvalidCiphertext
+	}
+	header := ciphertext[:headerSize]
+	ephemeral, err := keys.Parse(header[2:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCiphertext, err)
+	}
+
+	x, _ := curve.ScalarMult(ephemeral.X, ephemeral.Y, priv.D.Bytes())
+	aead, err := newAEAD(x, header, &priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := ciphertext[headerSize : headerSize+nonceSize]
+	message, err := aead.Open(nil, nonce, ciphertext[headerSize+nonceSize:], header)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return message, nil
+}
+
+// newAEAD derives the message key from the shared secret, bound to the
+// ephemeral and recipient keys
+func newAEAD(shared *big.Int, header []byte, recipient *ecdsa.PublicKey) (cipher.AEAD, error) {
+	secret := make([]byte, keySize)
+	info := append(append([]byte(kdfInfo), header...), keys.FromECDSA(recipient).Compressed()...)
+	kdf := hkdf.New(sha256.New, shared.FillBytes(make([]byte, keySize)), nil, info)
+	if _, err := io.ReadFull(kdf, secret); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
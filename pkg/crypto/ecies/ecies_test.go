@@ -0,0 +1,60 @@
+package ecies
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Longer than what RSA-2048 PKCS#1 v1.5 can encrypt
+	message := bytes.Repeat([]byte("secret ballot message "), 40)
+
+	ciphertext, err := Encrypt(&priv.PublicKey, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsHybrid(ciphertext) {
+		t.Fatal("ciphertext has no version header")
+	}
+	decrypted, err := Decrypt(priv, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, message) {
+		t.Fatal("decrypted message does not match")
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err = Decrypt(other, ciphertext); err != ErrDecryption {
+		t.Fatalf("expected ErrDecryption with another key, got %v", err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ciphertext, err := Encrypt(&priv.PublicKey, []byte("candidate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 2; i < len(ciphertext); i++ {
+		tampered := append([]byte{}, ciphertext...)
+		tampered[i] ^= 0x01
+		if _, err = Decrypt(priv, tampered); err == nil {
+			t.Fatalf("ciphertext tampered at byte %d decrypted", i)
+		}
+	}
+	if _, err = Decrypt(priv, ciphertext[:headerSize+nonceSize-1]); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext for a truncated ciphertext, got %v", err)
+	}
+	if _, err = Decrypt(priv, append([]byte{0x00, Version + 1}, ciphertext[2:]...)); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext for an unknown version, got %v", err)
+	}
+}
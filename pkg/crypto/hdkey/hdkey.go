@@ -0,0 +1,223 @@
+// Package hdkey derives P-256 keys from a seed following SLIP-0010. Only
+// hardened derivation is supported so keys of different paths cannot be
+// linked together without the seed.
+package hdkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedOffset is added to the index of hardened children
+const HardenedOffset uint32 = 0x80000000
+
+const (
+	masterKey = "Nist256p1 seed"
+	keySize   = 32
+)
+
+var (
+	ErrInvalidSeed   = errors.New("Seed must be between 16 and 64 bytes")
+	ErrNotHardened   = errors.New("Only hardened derivation is supported")
+	ErrInvalidPath   = errors.New("Invalid derivation path")
+	ErrInvalidRSAKey = errors.New("Invalid RSA key size")
+)
+
+var curve = elliptic.P256()
+
+// Key is an extended private key
+type Key struct {
+	key       []byte
+	chainCode []byte
+}
+
+// NewMaster derives the master key of the seed
+func NewMaster(seed []byte) (*Key, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrInvalidSeed
+	}
+	I := hmacSHA512([]byte(masterKey), seed)
+	for !validScalar(I[:keySize]) {
+		I = hmacSHA512([]byte(masterKey), I)
+	}
+	return &Key{I[:keySize], I[keySize:]}, nil
+}
+
+// Child derives the hardened child key at the index
+func (k *Key) Child(index uint32) (*Key, error) {
+	if index < HardenedOffset {
+		return nil, ErrNotHardened
+	}
+	N := curve.Params().N
+	ser := make([]byte, 4)
+	binary.BigEndian.PutUint32(ser, index)
+
+	data := append(append([]byte{0x00}, k.key...), ser...)
+	for {
+		I := hmacSHA512(k.chainCode, data)
+		IL := new(big.Int).SetBytes(I[:keySize])
+		if IL.Cmp(N) < 0 {
+			child := IL.Add(IL, new(big.Int).SetBytes(k.key))
+			child.Mod(child, N)
+			if child.Sign() != 0 {
+				key := make([]byte, keySize)
+				child.FillBytes(key)
+				return &Key{key, I[keySize:]}, nil
+			}
+		}
+		data = append(append([]byte{0x01}, I[keySize:]...), ser...)
+	}
+}
+
+// Derive follows a path such as m/1776'/0'/0'
+func (k *Key) Derive(path string) (*Key, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return k.DeriveIndexes(indexes)
+}
+
+// DeriveIndexes derives the children at each index in turn
+func (k *Key) DeriveIndexes(indexes []uint32) (*Key, error) {
+	key := k
+	for _, index := range indexes {
+		var err error
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ParsePath decodes a path of hardened indexes such as m/1776'/0'/0'
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "H")
+		if !hardened {
+			return nil, fmt.Errorf("%w: %q", ErrNotHardened, path)
+		}
+		index, err := strconv.ParseUint(part[:len(part)-1], 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+		}
+		indexes = append(indexes, uint32(index)+HardenedOffset)
+	}
+	return indexes, nil
+}
+
+// ChainCode returns the chain code of the key
+func (k *Key) ChainCode() []byte {
+	return append([]byte{}, k.chainCode...)
+}
+
+// ECDSA returns the key as an ECDSA P-256 private key
+func (k *Key) ECDSA() *ecdsa.PrivateKey {
+	priv := new(ecdsa.PrivateKey)
+	priv.Curve = curve
+	priv.D = new(big.Int).SetBytes(k.key)
+	priv.X, priv.Y = curve.ScalarBaseMult(k.key)
+	return priv
+}
+
+// RSA deterministically generates an RSA key of the given size from the key.
+// The primes are drawn from an HMAC-SHA512 stream keyed by the private key,
+// so the same key always gives the same RSA key.
+func (k *Key) RSA(bits int) (*rsa.PrivateKey, error) {
+	if bits < 1024 || bits%16 != 0 {
+		return nil, ErrInvalidRSAKey
+	}
+	stream := &hmacStream{key: hmacSHA512(k.key, []byte("RSA key")), counter: 0}
+	e := big.NewInt(65537)
+	one := big.NewInt(1)
+
+	for {
+		p := stream.prime(bits/2, e)
+		q := stream.prime(bits/2, e)
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
+			continue
+		}
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		d := new(big.Int).ModInverse(e, phi)
+		if d == nil {
+			continue
+		}
+
+		priv := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		priv.Precompute()
+		if err := priv.Validate(); err != nil {
+			return nil, err
+		}
+		return priv, nil
+	}
+}
+
+// hmacStream is a deterministic byte stream
+type hmacStream struct {
+	key     []byte
+	counter uint64
+}
+
+func (s *hmacStream) read(n int) []byte {
+	out := make([]byte, 0, n+sha512.Size)
+	for len(out) < n {
+		ctr := make([]byte, 8)
+		binary.BigEndian.PutUint64(ctr, s.counter)
+		s.counter++
+		out = append(out, hmacSHA512(s.key, ctr)...)
+	}
+	return out[:n]
+}
+
+// prime draws odd candidates with the two top bits set until one is a prime
+// p with p-1 coprime to e
+func (s *hmacStream) prime(bits int, e *big.Int) *big.Int {
+	one := big.NewInt(1)
+	for {
+		b := s.read((bits + 7) / 8)
+		p := new(big.Int).SetBytes(b)
+		p.Rsh(p, uint(len(b)*8-bits))
+		p.SetBit(p, bits-1, 1)
+		p.SetBit(p, bits-2, 1)
+		p.SetBit(p, 0, 1)
+		if !p.ProbablyPrime(20) {
+			continue
+		}
+		if new(big.Int).GCD(nil, nil, e, new(big.Int).Sub(p, one)).Cmp(one) == 0 {
+			return p
+		}
+	}
+}
+
+func validScalar(b []byte) bool {
+	k := new(big.Int).SetBytes(b)
+	return k.Sign() != 0 && k.Cmp(curve.Params().N) < 0
+}
+
+func hmacSHA512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
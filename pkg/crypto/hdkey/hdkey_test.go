@@ -0,0 +1,95 @@
+package hdkey
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func decodeHex(t testing.TB, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// SLIP-0010 test vector 1 for nist256p1
+func TestDeriveVector(t *testing.T) {
+	master, err := NewMaster(decodeHex(t, "000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []struct {
+		path, chainCode, private, public string
+	}{
+		{
+			"m",
+			"beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea",
+			"612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2",
+			"0266874dc6ade47b3ecd096745ca09bcd29638dd52c2c12117b11ed3e458cfa9e8",
+		},
+		{
+			"m/0'",
+			"3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11",
+			"6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c",
+			"0384610f5ecffe8fda089363a41f56a5c7ffc1d81b59a612d0d649b2d22355590c",
+		},
+	} {
+		key, err := master.Derive(v.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv := key.ECDSA()
+		if !bytes.Equal(key.ChainCode(), decodeHex(t, v.chainCode)) {
+			t.Fatalf("%s: chain code %x", v.path, key.ChainCode())
+		}
+		if !bytes.Equal(key.key, decodeHex(t, v.private)) {
+			t.Fatalf("%s: private key %x", v.path, key.key)
+		}
+		if public := elliptic.MarshalCompressed(priv.Curve, priv.X, priv.Y); !bytes.Equal(public, decodeHex(t, v.public)) {
+			t.Fatalf("%s: public key %x", v.path, public)
+		}
+	}
+}
+
+func TestDeriveRSA(t *testing.T) {
+	master, _ := NewMaster(decodeHex(t, "000102030405060708090a0b0c0d0e0f"))
+	key, _ := master.Derive("m/1776'/1'/0'")
+
+	first, err := key.RSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := key.RSA(2048)
+	if first.N.Cmp(second.N) != 0 || first.N.BitLen() != 2048 {
+		t.Fatal("RSA key is not deterministic")
+	}
+
+	other, _ := master.Derive("m/1776'/1'/1'")
+	third, _ := other.RSA(2048)
+	if first.N.Cmp(third.N) == 0 {
+		t.Fatal("different keys gave the same RSA key")
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	indexes, err := ParsePath("m/1776'/2H/0'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 3 || indexes[1] != HardenedOffset+2 {
+		t.Fatalf("unexpected indexes %v", indexes)
+	}
+	for _, path := range []string{"m/0", "1776'/0'", "m/-1'", "m/2147483648'", "m//"} {
+		if _, err := ParsePath(path); err == nil {
+			t.Fatalf("path %q accepted", path)
+		}
+	}
+	if _, err := NewMaster(make([]byte, 8)); !errors.Is(err, ErrInvalidSeed) {
+		t.Fatalf("expected an invalid seed, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+// Package identity issues and verifies the X.509 certificates binding the
+// main keys of voters to their identity attributes. The election commission
+// runs the certificate authority, its self-signed certificate is committed in
+// the election output.
+package identity
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+const (
+	certificateType = "CERTIFICATE"
+	serialBits      = 128
+	// Certificates are backdated to tolerate clock skew between the nodes
+	backdate = time.Hour
+)
+
+var (
+	ErrInvalidCertificate = errors.New("Invalid certificate")
+	ErrNotCA              = errors.New("Certificate is not a certificate authority")
+	ErrNotIssuedByCA      = errors.New("Certificate is not issued by the certificate authority")
+	ErrKeyMismatch        = errors.New("Certificate is not issued for this key")
+	ErrMissingIdentity    = errors.New("Missing voter ID")
+)
+
+// Identity holds the attributes of a voter certified by the commission
+type Identity struct {
+	VoterID string `json:"voter_id"`
+	Name    string `json:"name"`
+	Region  string `json:"region"`
+}
+
+// NewSerial returns a random positive certificate serial number
+func NewSerial() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), serialBits)
+	for {
+		serial, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return nil, err
+		}
+		if serial.Sign() > 0 {
+			return serial, nil
+		}
+	}
+}
+
+// NewCA creates the self-signed certificate of the commission authority
+func NewCA(name string, priv *ecdsa.PrivateKey, validity time.Duration) ([]byte, error) {
+	serial, err := NewSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   name,
+			Organization: []string{name},
+		},
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		return nil, err
+	}
+	return encode(der), nil
+}
+
+// Issue creates the certificate of a voter main key, signed by the commission
+// authority
+func Issue(caCert []byte, caKey *ecdsa.PrivateKey, voter *ecdsa.PublicKey, id Identity, validity time.Duration) ([]byte, error) {
+	if id.VoterID == "" {
+		return nil, ErrMissingIdentity
+	}
+	ca, err := ParseCA(caCert)
+	if err != nil {
+		return nil, err
+	}
+	if !sameKey(ca.PublicKey.(*ecdsa.PublicKey), &caKey.PublicKey) {
+		return nil, fmt.Errorf("%w: signing key does not match the authority", ErrKeyMismatch)
+	}
+
+	serial, err := NewSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   id.Name,
+			SerialNumber: id.VoterID,
+			Province:     nonEmpty(id.Region),
+			Organization: ca.Subject.Organization,
+		},
+		NotBefore:   now.Add(-backdate),
+		NotAfter:    now.Add(validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if template.NotAfter.After(ca.NotAfter) {
+		template.NotAfter = ca.NotAfter
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, voter, caKey)
+	if err != nil {
+		return nil, err
+	}
+	return encode(der), nil
+}
+
+// Parse decodes a PEM or DER encoded certificate
+func Parse(data []byte) (*x509.Certificate, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != certificateType {
+			return nil, fmt.Errorf("%w: unexpected %s block", ErrInvalidCertificate, block.Type)
+		}
+		data = block.Bytes
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCertificate, err)
+	}
+	return cert, nil
+}
+
+// ParseCA decodes the certificate of a commission authority
+func ParseCA(data []byte) (*x509.Certificate, error) {
+	ca, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if !ca.IsCA || ca.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return nil, ErrNotCA
+	}
+	if _, ok := ca.PublicKey.(*ecdsa.PublicKey); !ok {
+		return nil, fmt.Errorf("%w: authority key is not ECDSA", ErrInvalidCertificate)
+	}
+	if err = ca.CheckSignatureFrom(ca); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotCA, err)
+	}
+	return ca, nil
+}
+
+// Verify checks that the certificate is issued by the authority for the voter
+// key and valid at the given time. The voter key is SEC1 encoded.
+func Verify(caCert, cert, voter []byte, at time.Time) (*x509.Certificate, error) {
+	ca, err := ParseCA(caCert)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := Parse(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: at,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotIssuedByCA, err)
+	}
+
+	voterKey, err := keys.ParseAny(voter)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok || !sameKey(pub, &voterKey.PublicKey) {
+		return nil, ErrKeyMismatch
+	}
+	if IdentityOf(leaf).VoterID == "" {
+		return nil, ErrMissingIdentity
+	}
+	return leaf, nil
+}
+
+// IdentityOf returns the identity attributes of a voter certificate
+func IdentityOf(cert *x509.Certificate) Identity {
+	id := Identity{
+		VoterID: cert.Subject.SerialNumber,
+		Name:    cert.Subject.CommonName,
+	}
+	if len(cert.Subject.Province) > 0 {
+		id.Region = cert.Subject.Province[0]
+	}
+	return id
+}
+
+// Serial returns the serial number bytes recorded by revocations
+func Serial(cert *x509.Certificate) []byte {
+	return cert.SerialNumber.Bytes()
+}
+
+// Fingerprint returns the SHA-256 hash of the certificate, committed on-chain
+// next to its serial so the certificate can be checked once published
+func Fingerprint(cert *x509.Certificate) []byte {
+	hash := sha256.Sum256(cert.Raw)
+	return hash[:]
+}
+
+func encode(der []byte) []byte {
+	var buf bytes.Buffer
+	pem.Encode(&buf, &pem.Block{Type: certificateType, Bytes: der})
+	return buf.Bytes()
+}
+
+func sameKey(a, b *ecdsa.PublicKey) bool {
+	return a.X.Cmp(b.X) == 0 && a.Y.Cmp(b.Y) == 0
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
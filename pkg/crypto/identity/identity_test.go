@@ -0,0 +1,76 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+func TestIssueVerify(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	voterKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	voter := keys.FromECDSA(&voterKey.PublicKey).Bytes()
+
+	caCert, err := NewCA("Electoral Commission", caKey, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := Identity{VoterID: "V-0001", Name: "Ada", Region: "Lagos"}
+	cert, err := Issue(caCert, caKey, &voterKey.PublicKey, id, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := Verify(caCert, cert, voter, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IdentityOf(leaf) != id {
+		t.Fatalf("expected identity %+v, got %+v", id, IdentityOf(leaf))
+	}
+	if len(Serial(leaf)) == 0 {
+		t.Fatal("certificate has no serial")
+	}
+	if len(Fingerprint(leaf)) != sha256.Size {
+		t.Fatal("certificate has no fingerprint")
+	}
+
+	if _, err = Verify(caCert, cert, voter, time.Now().Add(2*time.Hour)); !errors.Is(err, ErrNotIssuedByCA) {
+		t.Fatalf("expected an expired certificate to fail, got %v", err)
+	}
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other := keys.FromECDSA(&otherKey.PublicKey).Bytes()
+	if _, err = Verify(caCert, cert, other, time.Now()); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch for another voter, got %v", err)
+	}
+}
+
+func TestVerifyOtherCA(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rogueKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	voterKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	caCert, _ := NewCA("Electoral Commission", caKey, time.Hour)
+	rogueCert, _ := NewCA("Electoral Commission", rogueKey, time.Hour)
+	cert, err := Issue(rogueCert, rogueKey, &voterKey.PublicKey, Identity{VoterID: "V-0002"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	voter := keys.FromECDSA(&voterKey.PublicKey).Bytes()
+	if _, err = Verify(caCert, cert, voter, time.Now()); !errors.Is(err, ErrNotIssuedByCA) {
+		t.Fatalf("expected ErrNotIssuedByCA, got %v", err)
+	}
+
+	if _, err = Issue(caCert, rogueKey, &voterKey.PublicKey, Identity{VoterID: "V-0003"}, time.Hour); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("expected issuing with another key to fail, got %v", err)
+	}
+	if _, err = ParseCA(cert); err != ErrNotCA {
+		t.Fatalf("expected a voter certificate not to be a CA, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Encoded key sizes on P-256
+const (
+	coordinateSize   = 32
+	CompressedSize   = 1 + coordinateSize
+	UncompressedSize = 1 + 2*coordinateSize
+)
+
+var (
+	ErrInvalidKey   = errors.New("Invalid public key")
+	ErrAmbiguousKey = errors.New("Ambiguous legacy public key")
+)
+
+// PublicKey is a P-256 public key encoded with the SEC1 point encoding
+type PublicKey struct {
+	ecdsa.PublicKey
+}
+
+// FromECDSA wraps an ECDSA P-256 public key
+func FromECDSA(pub *ecdsa.PublicKey) *PublicKey {
+	return &PublicKey{ecdsa.PublicKey{Curve: elliptic.P256(), X: pub.X, Y: pub.Y}}
+}
+
+// Parse decodes a SEC1 compressed or uncompressed public key. Any other
+// length, prefix or a point that is not on the curve is rejected.
+func Parse(b []byte) (*PublicKey, error) {
+	curve := elliptic.P256()
+	var x, y *big.Int
+
+	switch {
+	case len(b) == CompressedSize && (b[0] == 0x02 || b[0] == 0x03):
+		x, y = elliptic.UnmarshalCompressed(curve, b)
+	case len(b) == UncompressedSize && b[0] == 0x04:
+		x, y = elliptic.Unmarshal(curve, b)
+	}
+	if x == nil {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidKey, len(b))
+	}
+	return &PublicKey{ecdsa.PublicKey{Curve: curve, X: x, Y: y}}, nil
+}
+
+// ParseLegacy decodes a public key stored as the concatenation of the
+// big-endian X and Y values without leading zeros. Shorter keys have several
+// possible splits, the one giving a point on the curve is used.
+func ParseLegacy(b []byte) (*PublicKey, error) {
+	curve := elliptic.P256()
+	var found *PublicKey
+
+	for split := len(b) - coordinateSize; split <= coordinateSize; split++ {
+		if split < 1 || split >= len(b) {
+			continue
+		}
+		x := new(big.Int).SetBytes(b[:split])
+		y := new(big.Int).SetBytes(b[split:])
+		if !curve.IsOnCurve(x, y) {
+			continue
+		}
+		if found != nil {
+			return nil, ErrAmbiguousKey
+		}
+		found = &PublicKey{ecdsa.PublicKey{Curve: curve, X: x, Y: y}}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidKey, len(b))
+	}
+	return found, nil
+}
+
+// ParseAny decodes a SEC1 public key, falling back to the legacy encoding
+func ParseAny(b []byte) (*PublicKey, error) {
+	if IsLegacy(b) {
+		return ParseLegacy(b)
+	}
+	return Parse(b)
+}
+
+// IsLegacy checks if the key does not use the SEC1 encoding
+func IsLegacy(b []byte) bool {
+	switch len(b) {
+	case CompressedSize:
+		return b[0] != 0x02 && b[0] != 0x03
+	case UncompressedSize:
+		return b[0] != 0x04
+	}
+	return true
+}
+
+// Migrate converts a stored public key to the SEC1 compressed encoding.
+// SEC1 keys are only validated and returned as they are.
+func Migrate(b []byte) ([]byte, error) {
+	if !IsLegacy(b) {
+		if _, err := Parse(b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	pub, err := ParseLegacy(b)
+	if err != nil {
+		return nil, err
+	}
+	return pub.Compressed(), nil
+}
+
+// Equal checks if two encoded public keys are the same point, whatever their
+// encoding. Keys that cannot be decoded are compared byte by byte.
+func Equal(a, b []byte) bool {
+	pa, errA := ParseAny(a)
+	pb, errB := ParseAny(b)
+	if errA != nil || errB != nil {
+		return string(a) == string(b)
+	}
+	return pa.X.Cmp(pb.X) == 0 && pa.Y.Cmp(pb.Y) == 0
+}
+
+// Compressed returns the 33 bytes SEC1 compressed encoding
+func (k *PublicKey) Compressed() []byte {
+	return elliptic.MarshalCompressed(k.Curve, k.X, k.Y)
+}
+
+// Uncompressed returns the 65 bytes SEC1 uncompressed encoding
+func (k *PublicKey) Uncompressed() []byte {
+	return elliptic.Marshal(k.Curve, k.X, k.Y)
+}
+
+// Bytes returns the encoding used for keys stored on the chain
+func (k *PublicKey) Bytes() []byte {
+	return k.Compressed()
+}
+
+// ECDSA returns the key as an ECDSA public key
+func (k *PublicKey) ECDSA() *ecdsa.PublicKey {
+	return &k.PublicKey
+}
@@ -0,0 +1,98 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+// shortKey generates a key whose Y value has a leading zero byte, a case the
+// legacy decoding got wrong
+func shortKey(t testing.TB) *ecdsa.PrivateKey {
+	for i := 0; i < 10000; i++ {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(priv.X.Bytes()) == coordinateSize && len(priv.Y.Bytes()) < coordinateSize {
+			return priv
+		}
+	}
+	t.Fatal("no key with a short Y value generated")
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pub := FromECDSA(&priv.PublicKey)
+
+	for _, encoded := range [][]byte{pub.Compressed(), pub.Uncompressed()} {
+		parsed, err := Parse(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.X.Cmp(priv.X) != 0 || parsed.Y.Cmp(priv.Y) != 0 {
+			t.Fatalf("%d bytes key decoded to another point", len(encoded))
+		}
+	}
+	if len(pub.Bytes()) != CompressedSize {
+		t.Fatalf("expected %d bytes keys, got %d", CompressedSize, len(pub.Bytes()))
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pub := FromECDSA(&priv.PublicKey)
+
+	wrongPrefix := pub.Uncompressed()
+	wrongPrefix[0] = 0x02
+	offCurve := pub.Uncompressed()
+	offCurve[UncompressedSize-1] ^= 0x01
+	legacy := append(priv.X.Bytes(), priv.Y.Bytes()...)
+
+	for name, encoded := range map[string][]byte{
+		"empty":        {},
+		"truncated":    pub.Compressed()[:CompressedSize-1],
+		"wrong prefix": wrongPrefix,
+		"off curve":    offCurve,
+		"legacy":       legacy,
+		"trailing":     append(pub.Compressed(), 0x00),
+	} {
+		if _, err := Parse(encoded); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("%s key: expected an invalid key, got %v", name, err)
+		}
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	priv := shortKey(t)
+	legacy := append(priv.X.Bytes(), priv.Y.Bytes()...)
+
+	// The old decoding split the key in the middle
+	x := legacy[:len(legacy)/2]
+	if bytes.Equal(x, priv.X.Bytes()) {
+		t.Fatal("legacy key split correctly in the middle")
+	}
+
+	migrated, err := Migrate(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(migrated, FromECDSA(&priv.PublicKey).Compressed()) {
+		t.Fatal("legacy key migrated to another point")
+	}
+	if !Equal(legacy, migrated) {
+		t.Fatal("legacy and migrated keys are not equal")
+	}
+	if again, _ := Migrate(migrated); !bytes.Equal(again, migrated) {
+		t.Fatal("migrating a SEC1 key changed it")
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if Equal(legacy, FromECDSA(&other.PublicKey).Compressed()) {
+		t.Fatal("different keys are equal")
+	}
+}
@@ -1 +1,78 @@
 package multisig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/signer"
+)
+
+func TestVerifyMixedSchemes(t *testing.T) {
+	data := []byte("start election")
+	ms := NewMultisig(3)
+	for _, scheme := range []signer.Scheme{signer.ECDSAP256, signer.Ed25519, signer.SchnorrP256} {
+		s, err := signer.Generate(scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ms.AddSigner(data, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if verified, err := ms.Verify(data); !verified || err != nil {
+		t.Fatalf("valid multisig rejected (%v)", err)
+	}
+}
+
+func TestVerifyEverySignature(t *testing.T) {
+	data := []byte("start election")
+	ms := NewMultisig(3)
+	for i := 0; i < 3; i++ {
+		s, _ := signer.Generate(signer.Ed25519)
+		ms.AddSigner(data, s)
+	}
+
+	// Only the last signature is wrong
+	ms.Sigs[2][len(ms.Sigs[2])-1] ^= 0x01
+	if verified, _ := ms.Verify(data); verified {
+		t.Fatal("multisig with an invalid signature verified")
+	}
+
+	ms.Sigs = ms.Sigs[:2]
+	if verified, _ := ms.Verify(data); verified {
+		t.Fatal("multisig with a missing signature verified")
+	}
+}
+
+func TestVerifyAggregate(t *testing.T) {
+	data := []byte("stop election")
+	ms := NewMultisig(4)
+	privKeys := make([]*ecdsa.PrivateKey, 0, 4)
+	for i := 0; i < 4; i++ {
+		priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		ms.AddSignature(data, keys.FromECDSA(&priv.PublicKey).Bytes(), *priv)
+		privKeys = append(privKeys, priv)
+	}
+
+	if err := ms.Aggregate(data, privKeys[1:]); err == nil {
+		t.Fatal("aggregated without every private key")
+	}
+	if err := ms.Aggregate(data, privKeys); err != nil {
+		t.Fatal(err)
+	}
+	if len(ms.Sigs) != 1 {
+		t.Fatalf("expected a single signature, got %d", len(ms.Sigs))
+	}
+	if verified, err := ms.Verify(data); !verified || err != nil {
+		t.Fatalf("valid aggregate multisig rejected (%v)", err)
+	}
+
+	ms.PubKeys = ms.PubKeys[:3]
+	if verified, _ := ms.Verify(data); verified {
+		t.Fatal("aggregate multisig verified without a signer")
+	}
+}
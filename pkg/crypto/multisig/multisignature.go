@@ -2,9 +2,11 @@ package multisig
 
 import (
 	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"math/big"
+	"fmt"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/musig"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/signer"
 )
 
 type MultiSig struct {
@@ -20,38 +22,67 @@ func NewMultisig(n int) *MultiSig {
 	}
 }
 
-// AddSignature adds a signature to the multisig
+// AddSignature adds an ECDSA P-256 signature to the multisig
 func (sig *MultiSig) AddSignature(dataToSign []byte, PubKey []byte, privKey ecdsa.PrivateKey) {
-	r, s, err := ecdsa.Sign(rand.Reader, &privKey, dataToSign)
+	signature, err := signer.NewECDSASigner(&privKey).Sign(dataToSign)
 	if err != nil {
 		panic(err)
 	}
-	signature := append(r.Bytes(), s.Bytes()...)
 	sig.Sigs = append(sig.Sigs, signature)
 	sig.PubKeys = append(sig.PubKeys, PubKey)
 }
 
-// Verify all signatures of the multisig
-func (sig *MultiSig) Verify(data []byte) (bool, error) {
+// AddSigner adds the tagged signature of a signer of any registered scheme to
+// the multisig
+func (sig *MultiSig) AddSigner(dataToSign []byte, s signer.Signer) error {
+	signature, err := s.Sign(dataToSign)
+	if err != nil {
+		return err
+	}
+	sig.Sigs = append(sig.Sigs, signature)
+	sig.PubKeys = append(sig.PubKeys, s.PublicKey())
+	return nil
+}
 
-	for i := 0; i < len(sig.PubKeys); i++ {
-		r := big.Int{}
-		s := big.Int{}
-		sigLen := len(sig.Sigs[i])
-		r.SetBytes(sig.Sigs[i][:(sigLen / 2)])
-		s.SetBytes(sig.Sigs[i][(sigLen / 2):])
+// Aggregate replaces the signatures of the multisig with a single MuSig2
+// signature of all its keys. The private keys must be given in the order of
+// the public keys.
+func (sig *MultiSig) Aggregate(dataToSign []byte, privKeys []*ecdsa.PrivateKey) error {
+	if len(privKeys) != len(sig.PubKeys) {
+		return fmt.Errorf("%w: expected %d private keys, got %d", musig.ErrNotSigner, len(sig.PubKeys), len(privKeys))
+	}
+	for i, priv := range privKeys {
+		if !keys.Equal(keys.FromECDSA(&priv.PublicKey).Bytes(), sig.PubKeys[i]) {
+			return fmt.Errorf("%w: private key %d", musig.ErrNotSigner, i)
+		}
+	}
 
-		x := big.Int{}
-		y := big.Int{}
-		pubKey := sig.PubKeys[i]
-		keyLen := len(pubKey)
-		x.SetBytes(pubKey[:(keyLen / 2)])
-		y.SetBytes(pubKey[(keyLen / 2):])
+	signature, err := musig.Sign(privKeys, dataToSign)
+	if err != nil {
+		return err
+	}
+	sig.Sigs = [][]byte{signature}
+	return nil
+}
 
-		rawPubKey := ecdsa.PublicKey{Curve: elliptic.P256(), X: &x, Y: &y}
+// Verify all signatures of the multisig. Keys and signatures tagged with a
+// signature scheme are verified with it, untagged ones as ECDSA P-256.
+// Untagged keys may use the SEC1 or the legacy encoding. A single aggregate
+// signature is verified against all the keys at once.
+func (sig *MultiSig) Verify(data []byte) (bool, error) {
+	if len(sig.PubKeys) > 0 && len(sig.Sigs) == 1 && musig.IsAggregate(sig.Sigs[0]) {
+		return musig.Verify(sig.PubKeys, data, sig.Sigs[0])
+	}
+	if len(sig.PubKeys) == 0 || len(sig.PubKeys) != len(sig.Sigs) {
+		return false, nil
+	}
 
-		return ecdsa.Verify(&rawPubKey, data, &r, &s), nil
+	for i := 0; i < len(sig.PubKeys); i++ {
+		verified, err := signer.Verify(sig.PubKeys[i], data, sig.Sigs[i])
+		if err != nil || !verified {
+			return false, err
+		}
 	}
 
-	return false, nil
+	return true, nil
 }
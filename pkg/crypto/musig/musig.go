@@ -0,0 +1,384 @@
+package musig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/signer"
+)
+
+// MuSig2 aggregate signatures on P-256. All the signers of a quorum exchange
+// two public nonces, then each returns a partial signature and the partial
+// signatures add up to a single Schnorr P-256 signature of the aggregate key.
+// The signature is the compressed nonce point R followed by the 32 bytes s,
+// so its size does not depend on the number of signers.
+
+const (
+	scalarSize      = 32
+	PublicNonceSize = 2 * keys.CompressedSize
+	PartialSize     = scalarSize
+)
+
+var (
+	ErrNoKeys           = errors.New("No keys to aggregate")
+	ErrDuplicateKey     = errors.New("Duplicate key in the quorum")
+	ErrInvalidNonce     = errors.New("Invalid public nonce")
+	ErrNonceReused      = errors.New("Secret nonce already used")
+	ErrInvalidPartial   = errors.New("Invalid partial signature")
+	ErrNotSigner        = errors.New("Key is not part of the quorum")
+	ErrDegenerate       = errors.New("Aggregate point is the point at infinity")
+	ErrInvalidSignature = errors.New("Invalid aggregate signature")
+)
+
+var curve = elliptic.P256()
+
+// taggedHash hashes the values with a domain separation tag
+func taggedHash(tag string, values ...[]byte) *big.Int {
+	h := sha256.New()
+	h.Write([]byte(tag))
+	for _, v := range values {
+		h.Write(v)
+	}
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, curve.Params().N)
+}
+
+// parseKey decodes a quorum member key. Untagged keys are ECDSA P-256 keys,
+// tagged ones must be P-256 keys as well.
+func parseKey(pubKey []byte) (*keys.PublicKey, error) {
+	scheme, raw, tagged := signer.Untag(pubKey)
+	if !tagged {
+		return keys.ParseAny(raw)
+	}
+	if scheme != signer.ECDSAP256 && scheme != signer.SchnorrP256 {
+		return nil, fmt.Errorf("%w: 0x%02x keys cannot be aggregated", signer.ErrSchemeMismatch, byte(scheme))
+	}
+	return keys.Parse(raw)
+}
+
+// KeyAgg is the aggregate of the keys of a quorum
+type KeyAgg struct {
+	keys []*keys.PublicKey
+	hash []byte
+	key  *keys.PublicKey
+}
+
+// AggregateKeys combines the keys of a quorum into a single key. Every key is
+// weighted by a coefficient bound to the whole quorum so no member can choose
+// its key to cancel the others. The order of the keys does not matter.
+func AggregateKeys(pubKeys [][]byte) (*KeyAgg, error) {
+	if len(pubKeys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	parsed := make([]*keys.PublicKey, 0, len(pubKeys))
+	encoded := make([][]byte, 0, len(pubKeys))
+	for i, b := range pubKeys {
+		pub, err := parseKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("quorum key %d: %w", i, err)
+		}
+		parsed = append(parsed, pub)
+		encoded = append(encoded, pub.Compressed())
+	}
+
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+	for i := 1; i < len(encoded); i++ {
+		if bytes.Equal(encoded[i-1], encoded[i]) {
+			return nil, ErrDuplicateKey
+		}
+	}
+	agg := &KeyAgg{keys: parsed, hash: taggedHash("MuSig/keys", encoded...).Bytes()}
+
+	var x, y *big.Int
+	for _, pub := range parsed {
+		a := agg.coefficient(pub)
+		px, py := curve.ScalarMult(pub.X, pub.Y, a.Bytes())
+		if x == nil {
+			x, y = px, py
+			continue
+		}
+		x, y = curve.Add(x, y, px, py)
+	}
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, ErrDegenerate
+	}
+	agg.key = keys.FromECDSA(&ecdsa.PublicKey{Curve: curve, X: x, Y: y})
+
+	return agg, nil
+}
+
+// coefficient returns the weight of a key in the aggregate
+func (agg *KeyAgg) coefficient(pub *keys.PublicKey) *big.Int {
+	return taggedHash("MuSig/coefficient", agg.hash, pub.Compressed())
+}
+
+// contains checks if the key is a member of the quorum
+func (agg *KeyAgg) contains(pub *keys.PublicKey) bool {
+	for _, k := range agg.keys {
+		if k.X.Cmp(pub.X) == 0 && k.Y.Cmp(pub.Y) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PublicKey returns the aggregate key tagged as a Schnorr P-256 key.
+// Aggregate signatures verify as Schnorr signatures of this key.
+func (agg *KeyAgg) PublicKey() []byte {
+	return signer.Tag(signer.SchnorrP256, agg.key.Uncompressed())
+}
+
+// SecretNonce is the secret part of the nonces of a signer. It must only be
+// used for one signature.
+type SecretNonce struct {
+	k1, k2 *big.Int
+}
+
+// NewNonce generates the nonces of a signer for the message and returns the
+// secret nonce with the public nonce to share with the other signers. The
+// nonces mix the private key and the message into fresh randomness.
+func NewNonce(priv *ecdsa.PrivateKey, msg []byte) (*SecretNonce, []byte, error) {
+	entropy := make([]byte, scalarSize)
+	if _, err := io.ReadFull(rand.Reader, entropy); err != nil {
+		return nil, nil, err
+	}
+
+	N := curve.Params().N
+	k := make([]*big.Int, 2)
+	for i := range k {
+		for counter := byte(0); k[i] == nil || k[i].Sign() == 0; counter++ {
+			h := sha256.New()
+			h.Write(priv.D.Bytes())
+			h.Write(msg)
+			h.Write(entropy)
+			h.Write([]byte{byte(i), counter})
+			k[i] = new(big.Int).SetBytes(h.Sum(nil))
+			k[i].Mod(k[i], N)
+		}
+	}
+
+	r1x, r1y := curve.ScalarBaseMult(k[0].Bytes())
+	r2x, r2y := curve.ScalarBaseMult(k[1].Bytes())
+	public := append(
+		elliptic.MarshalCompressed(curve, r1x, r1y),
+		elliptic.MarshalCompressed(curve, r2x, r2y)...,
+	)
+
+	return &SecretNonce{k[0], k[1]}, public, nil
+}
+
+// parseNonce decodes the two points of a public nonce
+func parseNonce(public []byte) (r1x, r1y, r2x, r2y *big.Int, err error) {
+	if len(public) != PublicNonceSize {
+		return nil, nil, nil, nil, ErrInvalidNonce
+	}
+	r1x, r1y = elliptic.UnmarshalCompressed(curve, public[:keys.CompressedSize])
+	r2x, r2y = elliptic.UnmarshalCompressed(curve, public[keys.CompressedSize:])
+	if r1x == nil || r2x == nil {
+		return nil, nil, nil, nil, ErrInvalidNonce
+	}
+	return
+}
+
+// Session is the signing of one message by a quorum once the public nonces of
+// every signer are known
+type Session struct {
+	agg    *KeyAgg
+	msg    []byte
+	nonces [][]byte
+	b, e   *big.Int
+	R      []byte
+}
+
+// NewSession starts the signing of the message by the quorum from the public
+// nonces of all its signers
+func NewSession(agg *KeyAgg, publicNonces [][]byte, msg []byte) (*Session, error) {
+	if len(publicNonces) != len(agg.keys) {
+		return nil, fmt.Errorf("%w: expected %d nonces, got %d", ErrInvalidNonce, len(agg.keys), len(publicNonces))
+	}
+
+	var x1, y1, x2, y2 *big.Int
+	for i, public := range publicNonces {
+		r1x, r1y, r2x, r2y, err := parseNonce(public)
+		if err != nil {
+			return nil, fmt.Errorf("%w: signer %d", err, i)
+		}
+		if x1 == nil {
+			x1, y1, x2, y2 = r1x, r1y, r2x, r2y
+			continue
+		}
+		x1, y1 = curve.Add(x1, y1, r1x, r1y)
+		x2, y2 = curve.Add(x2, y2, r2x, r2y)
+	}
+	if (x1.Sign() == 0 && y1.Sign() == 0) || (x2.Sign() == 0 && y2.Sign() == 0) {
+		return nil, ErrDegenerate
+	}
+
+	// R = R1 + b*R2 with b bound to the key, both nonces and the message
+	b := taggedHash(
+		"MuSig/noncecoef",
+		agg.key.Uncompressed(),
+		elliptic.MarshalCompressed(curve, x1, y1),
+		elliptic.MarshalCompressed(curve, x2, y2),
+		msg,
+	)
+	bx, by := curve.ScalarMult(x2, y2, b.Bytes())
+	rx, ry := curve.Add(x1, y1, bx, by)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return nil, ErrDegenerate
+	}
+	R := elliptic.MarshalCompressed(curve, rx, ry)
+
+	return &Session{
+		agg:    agg,
+		msg:    msg,
+		nonces: publicNonces,
+		b:      b,
+		e:      signer.SchnorrChallenge(R, agg.key.Uncompressed(), msg),
+		R:      R,
+	}, nil
+}
+
+// Sign returns the partial signature of a quorum member. The secret nonce is
+// cleared so it cannot be used again.
+func (s *Session) Sign(priv *ecdsa.PrivateKey, nonce *SecretNonce) ([]byte, error) {
+	if nonce.k1 == nil {
+		return nil, ErrNonceReused
+	}
+	pub := keys.FromECDSA(&priv.PublicKey)
+	if !s.agg.contains(pub) {
+		return nil, ErrNotSigner
+	}
+	N := curve.Params().N
+
+	// s_i = k1 + b*k2 + e*a_i*x_i
+	sv := new(big.Int).Mul(s.e, s.agg.coefficient(pub))
+	sv.Mul(sv, priv.D)
+	sv.Add(sv, nonce.k1)
+	sv.Add(sv, new(big.Int).Mul(s.b, nonce.k2))
+	sv.Mod(sv, N)
+	nonce.k1, nonce.k2 = nil, nil
+
+	partial := make([]byte, PartialSize)
+	sv.FillBytes(partial)
+	return partial, nil
+}
+
+// VerifyPartial checks the partial signature of the signer at the given index
+// in the quorum, so a bad signer can be found before aggregating
+func (s *Session) VerifyPartial(index int, partial []byte) bool {
+	if index < 0 || index >= len(s.agg.keys) || len(partial) != PartialSize {
+		return false
+	}
+	sv := new(big.Int).SetBytes(partial)
+	if sv.Cmp(curve.Params().N) >= 0 {
+		return false
+	}
+	r1x, r1y, r2x, r2y, err := parseNonce(s.nonces[index])
+	if err != nil {
+		return false
+	}
+	pub := s.agg.keys[index]
+
+	// s_i*G == R1_i + b*R2_i + e*a_i*X_i
+	lx, ly := curve.ScalarBaseMult(sv.Bytes())
+	bx, by := curve.ScalarMult(r2x, r2y, s.b.Bytes())
+	ea := new(big.Int).Mul(s.e, s.agg.coefficient(pub))
+	ea.Mod(ea, curve.Params().N)
+	px, py := curve.ScalarMult(pub.X, pub.Y, ea.Bytes())
+	qx, qy := curve.Add(r1x, r1y, bx, by)
+	qx, qy = curve.Add(qx, qy, px, py)
+
+	return lx.Cmp(qx) == 0 && ly.Cmp(qy) == 0
+}
+
+// Aggregate adds the partial signatures of all the signers, in the order of
+// the quorum keys, into the tagged aggregate signature
+func (s *Session) Aggregate(partials [][]byte) ([]byte, error) {
+	if len(partials) != len(s.agg.keys) {
+		return nil, fmt.Errorf("%w: expected %d partial signatures, got %d", ErrInvalidPartial, len(s.agg.keys), len(partials))
+	}
+	sv := new(big.Int)
+	for i, partial := range partials {
+		if !s.VerifyPartial(i, partial) {
+			return nil, fmt.Errorf("%w: signer %d", ErrInvalidPartial, i)
+		}
+		sv.Add(sv, new(big.Int).SetBytes(partial))
+	}
+	sv.Mod(sv, curve.Params().N)
+
+	sig := make([]byte, keys.CompressedSize+scalarSize)
+	copy(sig, s.R)
+	sv.FillBytes(sig[keys.CompressedSize:])
+	return signer.Tag(signer.MuSigP256, sig), nil
+}
+
+// Sign runs both rounds for a quorum whose private keys are all available
+// locally and returns the tagged aggregate signature
+func Sign(privKeys []*ecdsa.PrivateKey, msg []byte) ([]byte, error) {
+	pubKeys := make([][]byte, len(privKeys))
+	nonces := make([]*SecretNonce, len(privKeys))
+	publicNonces := make([][]byte, len(privKeys))
+	for i, priv := range privKeys {
+		pubKeys[i] = keys.FromECDSA(&priv.PublicKey).Bytes()
+		secret, public, err := NewNonce(priv, msg)
+		if err != nil {
+			return nil, err
+		}
+		nonces[i], publicNonces[i] = secret, public
+	}
+
+	agg, err := AggregateKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	session, err := NewSession(agg, publicNonces, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	partials := make([][]byte, len(privKeys))
+	for i, priv := range privKeys {
+		if partials[i], err = session.Sign(priv, nonces[i]); err != nil {
+			return nil, err
+		}
+	}
+	return session.Aggregate(partials)
+}
+
+// IsAggregate checks if the signature is tagged as an aggregate signature
+func IsAggregate(sig []byte) bool {
+	scheme, _, tagged := signer.Untag(sig)
+	return tagged && scheme == signer.MuSigP256
+}
+
+// Verify checks that the aggregate signature proves every key of the quorum
+// signed the message
+func Verify(pubKeys [][]byte, msg, sig []byte) (bool, error) {
+	scheme, raw, tagged := signer.Untag(sig)
+	if !tagged || scheme != signer.MuSigP256 {
+		return false, ErrInvalidSignature
+	}
+	agg, err := AggregateKeys(pubKeys)
+	if err != nil {
+		return false, err
+	}
+
+	v, err := signer.Lookup(signer.SchnorrP256)
+	if err != nil {
+		return false, err
+	}
+	return v.Verify(agg.key.Uncompressed(), msg, raw), nil
+}
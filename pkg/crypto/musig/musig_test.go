@@ -0,0 +1,127 @@
+package musig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/signer"
+)
+
+func newQuorum(t testing.TB, size int) ([]*ecdsa.PrivateKey, [][]byte) {
+	privKeys := make([]*ecdsa.PrivateKey, size)
+	pubKeys := make([][]byte, size)
+	for i := range privKeys {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privKeys[i] = priv
+		pubKeys[i] = keys.FromECDSA(&priv.PublicKey).Bytes()
+	}
+	return privKeys, pubKeys
+}
+
+func TestSignVerify(t *testing.T) {
+	msg := []byte("stop accreditation")
+	privKeys, pubKeys := newQuorum(t, 5)
+
+	sig, err := Sign(privKeys, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != 2+keys.CompressedSize+scalarSize {
+		t.Fatalf("unexpected aggregate signature size %d", len(sig))
+	}
+	if verified, err := Verify(pubKeys, msg, sig); !verified || err != nil {
+		t.Fatalf("valid aggregate signature rejected (%v)", err)
+	}
+
+	// The order of the quorum keys does not matter
+	reversed := make([][]byte, len(pubKeys))
+	for i := range pubKeys {
+		reversed[len(pubKeys)-1-i] = pubKeys[i]
+	}
+	if verified, _ := Verify(reversed, msg, sig); !verified {
+		t.Fatal("aggregate signature rejected for reordered keys")
+	}
+
+	if verified, _ := Verify(pubKeys, []byte("stop voting"), sig); verified {
+		t.Fatal("aggregate signature verified for another message")
+	}
+	if verified, _ := Verify(pubKeys[1:], msg, sig); verified {
+		t.Fatal("aggregate signature verified without a quorum member")
+	}
+	_, outsider := newQuorum(t, 1)
+	if verified, _ := Verify(append(pubKeys, outsider...), msg, sig); verified {
+		t.Fatal("aggregate signature verified with an extra quorum member")
+	}
+	if _, err := Verify(append(pubKeys, pubKeys[0]), msg, sig); !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("expected a duplicate key, got %v", err)
+	}
+
+	// The aggregate signature is a Schnorr signature of the aggregate key
+	agg, _ := AggregateKeys(pubKeys)
+	_, raw, _ := signer.Untag(sig)
+	if verified, err := signer.Verify(agg.PublicKey(), msg, signer.Tag(signer.SchnorrP256, raw)); !verified || err != nil {
+		t.Fatalf("aggregate signature rejected as a Schnorr signature (%v)", err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	msg := []byte("start voting")
+	privKeys, pubKeys := newQuorum(t, 3)
+
+	nonces := make([]*SecretNonce, len(privKeys))
+	publicNonces := make([][]byte, len(privKeys))
+	for i, priv := range privKeys {
+		nonces[i], publicNonces[i], _ = NewNonce(priv, msg)
+	}
+	agg, err := AggregateKeys(pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := NewSession(agg, publicNonces, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	partials := make([][]byte, len(privKeys))
+	for i, priv := range privKeys {
+		if partials[i], err = session.Sign(priv, nonces[i]); err != nil {
+			t.Fatal(err)
+		}
+		if !session.VerifyPartial(i, partials[i]) {
+			t.Fatalf("valid partial signature %d rejected", i)
+		}
+	}
+	if _, err := session.Sign(privKeys[0], nonces[0]); !errors.Is(err, ErrNonceReused) {
+		t.Fatalf("expected a reused nonce, got %v", err)
+	}
+	outsider, _ := newQuorum(t, 1)
+	fresh, _, _ := NewNonce(outsider[0], msg)
+	if _, err := session.Sign(outsider[0], fresh); !errors.Is(err, ErrNotSigner) {
+		t.Fatalf("expected a non member, got %v", err)
+	}
+
+	bad := append([][]byte{}, partials...)
+	bad[1] = append([]byte{}, partials[1]...)
+	bad[1][PartialSize-1] ^= 0x01
+	if session.VerifyPartial(1, bad[1]) {
+		t.Fatal("tampered partial signature verified")
+	}
+	if _, err := session.Aggregate(bad); !errors.Is(err, ErrInvalidPartial) {
+		t.Fatalf("expected an invalid partial signature, got %v", err)
+	}
+
+	sig, err := session.Aggregate(partials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified, err := Verify(pubKeys, msg, sig); !verified || err != nil {
+		t.Fatalf("valid aggregate signature rejected (%v)", err)
+	}
+}
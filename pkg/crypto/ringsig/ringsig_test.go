@@ -1,6 +1,7 @@
 package ringsig
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"fmt"
@@ -68,3 +69,199 @@ func benchmarkVerify(b *testing.B, size int) {
 		}
 	})
 }
+
+func newTestSignature(t *testing.T, size int) (*PublicKeyRing, []byte, *RingSign) {
+	ring := NewPublicKeyRing(uint(size))
+	for i := 0; i < size-1; i++ {
+		w := wallet.MakeWalletGroup()
+		ring.Add(w.Main.PrivateKey.PublicKey)
+	}
+	w := wallet.MakeWalletGroup()
+	ring.Add(w.Main.PrivateKey.PublicKey)
+
+	message := []byte("Big Brother Is Watching")
+	sig, err := Sign(&w.Main.PrivateKey, ring, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring, message, sig
+}
+
+func TestSignParsedRing(t *testing.T) {
+	var pubKeys [][]byte
+	for i := 0; i < 3; i++ {
+		pubKeys = append(pubKeys, wallet.MakeWalletGroup().Main.PublicKey)
+	}
+	w := wallet.MakeWalletGroup()
+	pubKeys = append(pubKeys, w.Main.PublicKey)
+
+	ring, err := ParsePublicKeyRing(pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("Big Brother Is Watching")
+	sig, err := Sign(&w.Main.PrivateKey, ring, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(ring, message, sig) {
+		t.Fatal("signature with a decoded ring does not verify")
+	}
+
+	outsider := wallet.MakeWalletGroup()
+	if _, err = Sign(&outsider.Main.PrivateKey, ring, message); err != ErrNotInRing {
+		t.Fatalf("expected ErrNotInRing, got %v", err)
+	}
+}
+
+func TestRingSignBinaryEncoding(t *testing.T) {
+	for _, size := range []int{1, 2, 5, 16} {
+		ring, message, sig := newTestSignature(t, size)
+
+		data := sig.ToByte()
+		if len(data) != headerSize+2*scalarSize*size {
+			t.Fatalf("ring of %d keys encoded in %d bytes", size, len(data))
+		}
+		if len(data) >= len(sig.ToBase58()) && size > 1 {
+			t.Errorf("binary encoding (%d bytes) is not smaller than Base58 (%d bytes)", len(data), len(sig.ToBase58()))
+		}
+
+		decoded := new(RingSign)
+		if err := decoded.FromByte(data); err != nil {
+			t.Fatal(err)
+		}
+		if !Verify(ring, message, decoded) {
+			t.Fatalf("decoded signature of a ring of %d keys does not verify", size)
+		}
+	}
+}
+
+func TestRingSignLegacyEncoding(t *testing.T) {
+	ring, message, sig := newTestSignature(t, 4)
+
+	decoded := new(RingSign)
+	if err := decoded.FromByte([]byte(sig.ToBase58())); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.C) != 4 || len(decoded.T) != 4 {
+		t.Fatalf("decoded %d C and %d T values for a ring of 4 keys", len(decoded.C), len(decoded.T))
+	}
+	if !Verify(ring, message, decoded) {
+		t.Fatal("decoded Base58 signature does not verify")
+	}
+
+	for _, invalid := range []string{"", "1", "1a+b+c&+", "10+2+3&+4&", "1abc+def+ghi&+"} {
+		if err := new(RingSign).FromByte([]byte(invalid)); err == nil {
+			t.Errorf("invalid Base58 signature %q decoded", invalid)
+		}
+	}
+}
+
+func TestRingSignStrictDecoding(t *testing.T) {
+	_, _, sig := newTestSignature(t, 3)
+	data := sig.ToByte()
+
+	mutate := func(f func([]byte) []byte) []byte {
+		return f(append([]byte{}, data...))
+	}
+	cases := map[string][]byte{
+		"truncated":     data[:len(data)-1],
+		"trailing byte": append(append([]byte{}, data...), 0),
+		"no header":     data[:headerSize-1],
+		"empty ring": mutate(func(b []byte) []byte {
+			b[1], b[2] = 0, 0
+			return b[:headerSize]
+		}),
+		"wrong size": mutate(func(b []byte) []byte {
+			b[2] = 4
+			return b
+		}),
+		"invalid point": mutate(func(b []byte) []byte {
+			b[3] = 0x05
+			return b
+		}),
+		"scalar above order": mutate(func(b []byte) []byte {
+			for i := headerSize; i < headerSize+scalarSize; i++ {
+				b[i] = 0xff
+			}
+			return b
+		}),
+	}
+	for name, invalid := range cases {
+		if err := new(RingSign).UnmarshalBinary(invalid); err == nil {
+			t.Errorf("%s: invalid signature decoded", name)
+		}
+	}
+}
+
+func TestSignLinkable(t *testing.T) {
+	signer := wallet.MakeWalletGroup()
+	other := wallet.MakeWalletGroup()
+	newRing := func(size int) *PublicKeyRing {
+		ring := NewPublicKeyRing(uint(size))
+		ring.Add(signer.Main.PrivateKey.PublicKey)
+		ring.Add(other.Main.PrivateKey.PublicKey)
+		for i := 2; i < size; i++ {
+			ring.Add(wallet.MakeWalletGroup().Main.PrivateKey.PublicKey)
+		}
+		return ring
+	}
+	sign := func(w *wallet.WalletGroup, ring *PublicKeyRing, message, scope string) *RingSign {
+		sig, err := SignLinkable(&w.Main.PrivateKey, ring, []byte(message), []byte(scope))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if VerifyLinkable(ring, []byte(message), []byte(scope), sig) == false {
+			t.Fatal("linkable signature does not verify")
+		}
+		return sig
+	}
+
+	ring := newRing(3)
+	first := sign(signer, ring, "ballot 1", "election")
+	tests := []struct {
+		name   string
+		sig    *RingSign
+		linked bool
+	}{
+		{"another message", sign(signer, ring, "ballot 2", "election"), true},
+		{"another ring", sign(signer, newRing(4), "ballot 2", "election"), true},
+		{"another scope", sign(signer, ring, "ballot 1", "other election"), false},
+		{"another signer", sign(other, ring, "ballot 1", "election"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			linked := bytes.Compare(first.KeyImage(), test.sig.KeyImage()) == 0
+			if linked != test.linked {
+				t.Fatalf("expected linked to be %v", test.linked)
+			}
+		})
+	}
+
+	if VerifyLinkable(ring, []byte("ballot 1"), []byte("other election"), first) {
+		t.Error("expected the signature to be bound to its scope")
+	}
+	if Verify(ring, []byte("ballot 1"), first) {
+		t.Error("expected a linkable signature not to verify as a message bound one")
+	}
+	decoded := new(RingSign)
+	if err := decoded.FromByte(first.ToByte()); err != nil {
+		t.Fatal(err)
+	}
+	if VerifyLinkable(ring, []byte("ballot 1"), []byte("election"), decoded) == false {
+		t.Error("decoded linkable signature does not verify")
+	}
+}
+
+func TestHashPoint(t *testing.T) {
+	x, y := hashPoint(DefaultCurve, []byte("election"))
+	if DefaultCurve.IsOnCurve(x, y) == false {
+		t.Fatal("expected a point of the curve")
+	}
+	if sx, sy := hashPoint(DefaultCurve, []byte("election")); sx.Cmp(x) != 0 || sy.Cmp(y) != 0 {
+		t.Fatal("expected the same point for the same scope")
+	}
+	if ox, _ := hashPoint(DefaultCurve, []byte("other election")); ox.Cmp(x) == 0 {
+		t.Fatal("expected another point for another scope")
+	}
+}
@@ -6,15 +6,16 @@ import (
 	"crypto/elliptic"
 	crand "crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"sort"
 	"strings"
-	"sync"
 
 	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/base58"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
 )
 
 // PublicKeyRing is a list of public keys.
@@ -28,6 +29,20 @@ func NewPublicKeyRing(cap uint) *PublicKeyRing {
 	return &PublicKeyRing{make([]ecdsa.PublicKey, 0, cap)}
 }
 
+// ParsePublicKeyRing decodes a ring from encoded public keys. Keys are SEC1
+// encoded, legacy X||Y encoded keys are still accepted.
+func ParsePublicKeyRing(pubKeys [][]byte) (*PublicKeyRing, error) {
+	ring := NewPublicKeyRing(uint(len(pubKeys)))
+	for i, b := range pubKeys {
+		pub, err := keys.ParseAny(b)
+		if err != nil {
+			return nil, fmt.Errorf("ring key %d: %w", i, err)
+		}
+		ring.Add(*pub.ECDSA())
+	}
+	return ring, nil
+}
+
 // Add adds a public key, pub to the ring.
 // All keys added to the ring must use the same curve.
 func (r *PublicKeyRing) Add(pub ecdsa.PublicKey) {
@@ -65,7 +80,9 @@ func (r *PublicKeyRing) Len() int {
 	return len(r.Ring)
 }
 
-// Bytes returns the public key ring as a byte slice.
+// Bytes returns the public key ring as a byte slice. It is only hashed into
+// signatures, so it keeps the original encoding for existing signatures to
+// verify.
 func (r *PublicKeyRing) Bytes() (b []byte) {
 	for _, pub := range r.Ring {
 		b = append(b, pub.X.Bytes()...)
@@ -151,6 +168,9 @@ func (k *RingSign) FromBase58(sig string) error {
 	// [2] --> C
 	// [3] --> T
 
+	if len(sig) == 0 {
+		return ErrInvalidEncoding
+	}
 	stringArray := strings.Split(sig[1:], "+")
 
 	if len(stringArray) != 4 {
@@ -163,39 +183,57 @@ func (k *RingSign) FromBase58(sig string) error {
 	cArray := strings.Split(stringArray[2], "&")
 	tArray := strings.Split(stringArray[3], "&")
 
-	XB58 := base58.Base58(stringArray[0])
-	k.X = XB58.Base582Big()
-
-	YB58 := base58.Base58(stringArray[1])
-	k.Y = YB58.Base582Big()
+	X, err := parseBase58(stringArray[0])
+	if err != nil {
+		return err
+	}
+	Y, err := parseBase58(stringArray[1])
+	if err != nil {
+		return err
+	}
 
+	var C, T []*big.Int
 	for i, c := range cArray {
 		if i == len(cArray)-1 {
 			continue
 		}
-
-		cB58 := base58.Base58(c)
-		k.C = append(k.C, cB58.Base582Big())
+		value, err := parseBase58(c)
+		if err != nil {
+			return err
+		}
+		C = append(C, value)
 	}
 
 	for i, t := range tArray {
-		if i == len(cArray)-1 {
+		if i == len(tArray)-1 {
 			continue
 		}
-
-		tB58 := base58.Base58(t)
-		k.T = append(k.T, tB58.Base582Big())
+		value, err := parseBase58(t)
+		if err != nil {
+			return err
+		}
+		T = append(T, value)
 	}
 
-	if (k.X == nil) || (k.Y == nil) || (k.C == nil) || (k.T == nil) {
+	if len(C) == 0 || len(C) != len(T) {
 		err := errors.New("Failure to parse string signature for Base58 encoded" +
 			" ring signature!")
 		return err
 	}
 
+	k.X, k.Y, k.C, k.T = X, Y, C, T
 	return nil
 }
 
+// parseBase58 decodes a non-empty Base58 number
+func parseBase58(value string) (*big.Int, error) {
+	b58 := base58.Base58(value)
+	if len(value) == 0 || b58.IsValid() == false {
+		return nil, fmt.Errorf("%w: invalid Base58 value %q", ErrInvalidEncoding, value)
+	}
+	return b58.Base582Big(), nil
+}
+
 // ToBase58 returns a ring signature as a Base58 string.
 func (k *RingSign) ToBase58() string {
 	var buffer bytes.Buffer
@@ -220,35 +258,116 @@ func (k *RingSign) ToBase58() string {
 	return buffer.String()
 }
 
-// ToByte returns a ring signature as a Byte.
-func (k *RingSign) ToByte() []byte {
+// Binary encoding of a ring signature:
+//
+//	version (1 byte) | ring size (2 bytes, big endian) | compressed key image
+//	(33 bytes) | C (32 bytes each) | T (32 bytes each)
+//
+// Only P-256 signatures can be encoded.
+const (
+	binaryVersion = 0x02
+	scalarSize    = 32
+	pointSize     = 1 + scalarSize
+	headerSize    = 1 + 2 + pointSize
+	maxRingSize   = 1<<16 - 1
+)
 
-	var buffer bytes.Buffer
-	buffer.WriteString("1") // Version
-	buffer.WriteString(string(base58.Big2Base58(k.X)))
-	buffer.WriteString("+")
-	buffer.WriteString(string(base58.Big2Base58(k.Y)))
-	buffer.WriteString("+")
+var (
+	ErrInvalidEncoding = errors.New("Invalid ring signature encoding")
+	ErrNotInRing       = errors.New("Signer is not in the ring")
+)
 
-	for _, c := range k.C {
-		buffer.WriteString(string(base58.Big2Base58(c)))
-		buffer.WriteString("&")
+// MarshalBinary returns the compact binary encoding of the ring signature
+func (k *RingSign) MarshalBinary() ([]byte, error) {
+	if k.X == nil || k.Y == nil || len(k.C) == 0 || len(k.C) != len(k.T) {
+		return nil, fmt.Errorf("%w: incomplete signature", ErrInvalidEncoding)
+	}
+	if len(k.C) > maxRingSize {
+		return nil, fmt.Errorf("%w: ring of %d keys is too large", ErrInvalidEncoding, len(k.C))
+	}
+	curve := elliptic.P256()
+	if !curve.IsOnCurve(k.X, k.Y) {
+		return nil, fmt.Errorf("%w: key image is not a P-256 point", ErrInvalidEncoding)
 	}
 
-	buffer.WriteString("+")
+	data := make([]byte, headerSize, headerSize+2*scalarSize*len(k.C))
+	data[0] = binaryVersion
+	data[1] = byte(len(k.C) >> 8)
+	data[2] = byte(len(k.C))
+	copy(data[3:], elliptic.MarshalCompressed(curve, k.X, k.Y))
 
-	for _, t := range k.T {
-		buffer.WriteString(string(base58.Big2Base58(t)))
-		buffer.WriteString("&")
+	for _, scalars := range [][]*big.Int{k.C, k.T} {
+		for _, v := range scalars {
+			if v == nil || v.Sign() < 0 || v.BitLen() > 8*scalarSize {
+				return nil, fmt.Errorf("%w: scalar out of range", ErrInvalidEncoding)
+			}
+			buf := make([]byte, scalarSize)
+			data = append(data, v.FillBytes(buf)...)
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes the compact binary encoding of a ring signature.
+// The length must match the ring size, the key image must be a valid point and
+// the scalars must be lower than the curve order.
+func (k *RingSign) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize || data[0] != binaryVersion {
+		return fmt.Errorf("%w: unknown version or truncated header", ErrInvalidEncoding)
+	}
+	size := int(data[1])<<8 | int(data[2])
+	if size == 0 || len(data) != headerSize+2*scalarSize*size {
+		return fmt.Errorf("%w: %d bytes for a ring of %d keys", ErrInvalidEncoding, len(data), size)
+	}
+
+	curve := elliptic.P256()
+	X, Y := elliptic.UnmarshalCompressed(curve, data[3:headerSize])
+	if X == nil {
+		return fmt.Errorf("%w: invalid key image", ErrInvalidEncoding)
+	}
+
+	N := curve.Params().N
+	scalars := make([]*big.Int, 2*size)
+	for i := range scalars {
+		offset := headerSize + i*scalarSize
+		scalars[i] = new(big.Int).SetBytes(data[offset : offset+scalarSize])
+		if scalars[i].Cmp(N) >= 0 {
+			return fmt.Errorf("%w: scalar out of range", ErrInvalidEncoding)
+		}
 	}
 
-	return buffer.Bytes()
+	k.X, k.Y = X, Y
+	k.C = scalars[:size]
+	k.T = scalars[size:]
+	return nil
 }
 
-// FromByte returns a ring signature as a RingSig.
+// KeyImage returns the compressed key image of the signature, signatures of
+// SignLinkable with the same key and scope share it
+func (k *RingSign) KeyImage() []byte {
+	if k.X == nil || k.Y == nil {
+		return nil
+	}
+	return elliptic.MarshalCompressed(elliptic.P256(), k.X, k.Y)
+}
+
+// ToByte returns a ring signature as a Byte, using the compact binary
+// encoding. It returns nil when the signature cannot be encoded.
+func (k *RingSign) ToByte() []byte {
+	data, err := k.MarshalBinary()
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// FromByte returns a ring signature as a RingSig. Both the binary encoding and
+// the legacy Base58 encoding are accepted.
 func (k *RingSign) FromByte(sig []byte) error {
-	k.FromBase58(string(sig))
-	return nil
+	if len(sig) > 0 && sig[0] == binaryVersion {
+		return k.UnmarshalBinary(sig)
+	}
+	return k.FromBase58(string(sig))
 }
 
 func hashG(c elliptic.Curve, m []byte) (hx, hy *big.Int) {
@@ -289,6 +408,38 @@ func hashAllqc(c elliptic.Curve, mR []byte, ax, ay, bx, by []*big.Int) (hash *bi
 	return
 }
 
+// hashPoint hashes the data to a point of the curve whose discrete logarithm
+// is unknown, unlike hashG. Hashes that are not the x coordinate of a point
+// are hashed again with the next counter.
+func hashPoint(c elliptic.Curve, data []byte) (hx, hy *big.Int) {
+	params := c.Params()
+	three := big.NewInt(3)
+	counter := make([]byte, 4)
+	for i := uint32(0); ; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := sha256.New()
+		h.Write([]byte("ringsig key image"))
+		h.Write(data)
+		h.Write(counter)
+		x := new(big.Int).SetBytes(h.Sum(nil))
+		if x.Cmp(params.P) >= 0 {
+			continue
+		}
+
+		// y² = x³ - 3x + b
+		y2 := new(big.Int).Exp(x, three, params.P)
+		y2.Sub(y2, new(big.Int).Mul(three, x))
+		y2.Add(y2, params.B)
+		y2.Mod(y2, params.P)
+		if y := new(big.Int).ModSqrt(y2, params.P); y != nil && y.Sign() != 0 {
+			if y.Bit(0) == 1 {
+				y.Sub(params.P, y)
+			}
+			return x, y
+		}
+	}
+}
+
 // Sign signs an arbitrary length message (which should NOT be the hash of a
 // larger message) using the private key, priv and the public key ring, R.
 // It returns the signature as a struct of type RingSign.
@@ -298,8 +449,33 @@ func Sign(
 	priv *ecdsa.PrivateKey,
 	R *PublicKeyRing,
 	m []byte) (rs *RingSign, err error) {
-	rand := crand.Reader
 	sort.Sort(R)
+	hx, hy := hashG(priv.PublicKey.Curve, append(m, R.Bytes()...)) // H(mR)
+	return sign(priv, R, m, hx, hy)
+}
+
+// SignLinkable signs the message like Sign, with a key image that does not
+// depend on the message: H(scope)^x, where H hashes to a point of unknown
+// discrete logarithm. Signatures of the same key and scope share their key
+// image whatever the message and the ring, so a key signs once per scope.
+func SignLinkable(
+	priv *ecdsa.PrivateKey,
+	R *PublicKeyRing,
+	m []byte,
+	scope []byte) (*RingSign, error) {
+	sort.Sort(R)
+	hx, hy := hashPoint(priv.PublicKey.Curve, scope)
+	return sign(priv, R, m, hx, hy)
+}
+
+// sign computes the ring signature of the message with the key image base
+// point h, the ring is sorted
+func sign(
+	priv *ecdsa.PrivateKey,
+	R *PublicKeyRing,
+	m []byte,
+	hx, hy *big.Int) (rs *RingSign, err error) {
+	rand := crand.Reader
 
 	s := R.Len()
 	ax := make([]*big.Int, s, s)
@@ -312,46 +488,48 @@ func Sign(
 	curve := pub.Curve
 	N := curve.Params().N
 
+	// The signer is found by its point, the ring may be decoded from its
+	// encoded keys
+	id := -1
+	for j, key := range R.Ring {
+		if key.X.Cmp(pub.X) == 0 && key.Y.Cmp(pub.Y) == 0 {
+			id = j
+			break
+		}
+	}
+	if id < 0 {
+		return nil, ErrNotInRing
+	}
+
 	mR := append(m, R.Bytes()...)
-	hx, hy := hashG(curve, mR) // H(mR)
 
-	var id int
-	var wg sync.WaitGroup
+	// The ring members are computed one after the other, like Verify
 	sum := new(big.Int).SetInt64(0)
 	for j := 0; j < s; j++ {
-		wg.Add(1)
-		go func(j int) {
-			defer wg.Done()
-			c[j], err = randFieldElement(curve, rand)
-			if err != nil {
-				return
-			}
-			t[j], err = randFieldElement(curve, rand)
-			if err != nil {
-				return
-			}
+		if c[j], err = randFieldElement(curve, rand); err != nil {
+			return nil, err
+		}
+		if t[j], err = randFieldElement(curve, rand); err != nil {
+			return nil, err
+		}
 
-			if R.Ring[j] == pub {
-				id = j
-				rb := t[j].Bytes()
-				ax[id], ay[id] = curve.ScalarBaseMult(rb)     // g^r
-				bx[id], by[id] = curve.ScalarMult(hx, hy, rb) // H(mR)^r
-			} else {
-				ax1, ay1 := curve.ScalarBaseMult(t[j].Bytes())                       // g^tj
-				ax2, ay2 := curve.ScalarMult(R.Ring[j].X, R.Ring[j].Y, c[j].Bytes()) // yj^cj
-				ax[j], ay[j] = curve.Add(ax1, ay1, ax2, ay2)
-
-				w := new(big.Int)
-				w.Mul(priv.D, c[j])
-				w.Add(w, t[j])
-				w.Mod(w, N)
-				bx[j], by[j] = curve.ScalarMult(hx, hy, w.Bytes()) // H(mR)^(xi*cj+tj)
-				// TODO may need to lock on sum object.
-				sum.Add(sum, c[j]) // Sum needed in Step 3 of the algorithm
-			}
-		}(j)
+		if j == id {
+			rb := t[j].Bytes()
+			ax[id], ay[id] = curve.ScalarBaseMult(rb)     // g^r
+			bx[id], by[id] = curve.ScalarMult(hx, hy, rb) // h^r
+		} else {
+			ax1, ay1 := curve.ScalarBaseMult(t[j].Bytes())                       // g^tj
+			ax2, ay2 := curve.ScalarMult(R.Ring[j].X, R.Ring[j].Y, c[j].Bytes()) // yj^cj
+			ax[j], ay[j] = curve.Add(ax1, ay1, ax2, ay2)
+
+			w := new(big.Int)
+			w.Mul(priv.D, c[j])
+			w.Add(w, t[j])
+			w.Mod(w, N)
+			bx[j], by[j] = curve.ScalarMult(hx, hy, w.Bytes()) // h^(xi*cj+tj)
+			sum.Add(sum, c[j])                                 // Sum needed in Step 3 of the algorithm
+		}
 	}
-	wg.Wait()
 	// Step 3, part 1: cid = H(m,R,{a,b}) - sum(cj) mod N
 	hashmRab := hashAllq(mR, ax, ay, bx, by)
 	// hashmRab := hashAllqc(curve, mR, ax, ay, bx, by)
@@ -364,19 +542,44 @@ func Sign(
 	t[id].Sub(t[id], cx) // here t[id] = ri (initialized inside the for-loop above)
 	t[id].Mod(t[id], N)
 
-	hsx, hsy := curve.ScalarMult(hx, hy, priv.D.Bytes()) // Step 4: H(mR)^xi
+	hsx, hsy := curve.ScalarMult(hx, hy, priv.D.Bytes()) // Step 4: h^xi
 	return &RingSign{hsx, hsy, c, t}, nil
 }
 
 // Verify verifies the signature in rs of m using the public key ring, R. Its
-// return value records whether the signature is valid.
+// return value records whether the signature is valid. The ring members are
+// checked one after the other, callers verifying several signatures run them
+// on their own bounded pool.
 func Verify(R *PublicKeyRing, m []byte, rs *RingSign) bool {
 	sort.Sort(R)
+	if R.Len() == 0 {
+		return false
+	}
+	hx, hy := hashG(R.Ring[0].Curve, append(m, R.Bytes()...))
+	return verify(R, m, rs, hx, hy)
+}
+
+// VerifyLinkable verifies a signature of SignLinkable, its key image must be
+// the one of the signer for the scope
+func VerifyLinkable(R *PublicKeyRing, m []byte, scope []byte, rs *RingSign) bool {
+	sort.Sort(R)
+	if R.Len() == 0 {
+		return false
+	}
+	hx, hy := hashPoint(R.Ring[0].Curve, scope)
+	return verify(R, m, rs, hx, hy)
+}
 
+// verify checks the ring signature of the message with the key image base
+// point h, the ring is sorted
+func verify(R *PublicKeyRing, m []byte, rs *RingSign, hx, hy *big.Int) bool {
 	s := R.Len()
 	if s == 0 {
 		return false
 	}
+	if rs == nil || rs.X == nil || rs.Y == nil || len(rs.C) != s || len(rs.T) != s {
+		return false
+	}
 	c := R.Ring[0].Curve
 	N := c.Params().N
 	x, y := rs.X, rs.Y
@@ -391,34 +594,27 @@ func Verify(R *PublicKeyRing, m []byte, rs *RingSign) bool {
 		return false
 	}
 	mR := append(m, R.Bytes()...)
-	hx, hy := hashG(c, mR)
 
 	sum := new(big.Int).SetInt64(0)
 	ax := make([]*big.Int, s, s)
 	ay := make([]*big.Int, s, s)
 	bx := make([]*big.Int, s, s)
 	by := make([]*big.Int, s, s)
-	var wg sync.WaitGroup
 	for j := 0; j < s; j++ {
 		// Check that cj,tj is in range [0..N]
 		if rs.C[j].Cmp(N) >= 0 || rs.T[j].Cmp(N) >= 0 {
 			return false
 		}
-		wg.Add(1)
-		go func(j int) {
-			defer wg.Done()
-			cb := rs.C[j].Bytes()
-			tb := rs.T[j].Bytes()
-			ax1, ay1 := c.ScalarBaseMult(tb)                       // g^tj
-			ax2, ay2 := c.ScalarMult(R.Ring[j].X, R.Ring[j].Y, cb) // yj^cj
-			ax[j], ay[j] = c.Add(ax1, ay1, ax2, ay2)
-			bx1, by1 := c.ScalarMult(hx, hy, tb) // H(mR)^tj
-			bx2, by2 := c.ScalarMult(x, y, cb)   // tau^cj
-			bx[j], by[j] = c.Add(bx1, by1, bx2, by2)
-		}(j)
+		cb := rs.C[j].Bytes()
+		tb := rs.T[j].Bytes()
+		ax1, ay1 := c.ScalarBaseMult(tb)                       // g^tj
+		ax2, ay2 := c.ScalarMult(R.Ring[j].X, R.Ring[j].Y, cb) // yj^cj
+		ax[j], ay[j] = c.Add(ax1, ay1, ax2, ay2)
+		bx1, by1 := c.ScalarMult(hx, hy, tb) // h^tj
+		bx2, by2 := c.ScalarMult(x, y, cb)   // tau^cj
+		bx[j], by[j] = c.Add(bx1, by1, bx2, by2)
 		sum.Add(sum, rs.C[j])
 	}
-	wg.Wait()
 	hashmRab := hashAllq(mR, ax, ay, bx, by)
 	// hashmRab := hashAllqc(c, mR, ax, ay, bx, by)
 	hashmRab.Mod(hashmRab, N)
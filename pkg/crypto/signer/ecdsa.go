@@ -0,0 +1,84 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+// ECDSA P-256 keys are SEC1 points and signatures the fixed width 32 bytes r
+// and s values
+const scalarSize = 32
+
+type ecdsaVerifier struct{}
+
+type ecdsaSigner struct {
+	priv *ecdsa.PrivateKey
+}
+
+// NewECDSASigner creates an ECDSA P-256 signer from the private key
+func NewECDSASigner(priv *ecdsa.PrivateKey) Signer {
+	return &ecdsaSigner{priv}
+}
+
+func (ecdsaVerifier) Scheme() Scheme {
+	return ECDSAP256
+}
+
+func (ecdsaVerifier) Verify(pubKey, data, sig []byte) bool {
+	pub, err := keys.Parse(pubKey)
+	if err != nil || len(sig) != 2*scalarSize {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:scalarSize])
+	s := new(big.Int).SetBytes(sig[scalarSize:])
+
+	return ecdsa.Verify(pub.ECDSA(), data, r, s)
+}
+
+func (ecdsaVerifier) Generate() (Signer, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewECDSASigner(priv), nil
+}
+
+func (s *ecdsaSigner) Scheme() Scheme {
+	return ECDSAP256
+}
+
+func (s *ecdsaSigner) PublicKey() []byte {
+	return Tag(ECDSAP256, elliptic.Marshal(s.priv.Curve, s.priv.X, s.priv.Y))
+}
+
+func (s *ecdsaSigner) Sign(data []byte) ([]byte, error) {
+	r, sv, err := ecdsa.Sign(rand.Reader, s.priv, data)
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 2*scalarSize)
+	r.FillBytes(sig[:scalarSize])
+	sv.FillBytes(sig[scalarSize:])
+	return Tag(ECDSAP256, sig), nil
+}
+
+// verifyLegacy checks an untagged ECDSA P-256 signature, the concatenation of
+// the big-endian r and s values without leading zeros. Shorter signatures have
+// several possible splits, any of them verifying is enough.
+func verifyLegacy(pub *keys.PublicKey, data, sig []byte) bool {
+	for split := len(sig) - scalarSize; split <= scalarSize; split++ {
+		if split < 1 || split >= len(sig) {
+			continue
+		}
+		r := new(big.Int).SetBytes(sig[:split])
+		s := new(big.Int).SetBytes(sig[split:])
+		if ecdsa.Verify(pub.ECDSA(), data, r, s) {
+			return true
+		}
+	}
+	return false
+}
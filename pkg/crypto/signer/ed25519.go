@@ -0,0 +1,48 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+)
+
+type ed25519Verifier struct{}
+
+type ed25519Signer struct {
+	priv ed25519.PrivateKey
+}
+
+// NewEd25519Signer creates an Ed25519 signer from the private key
+func NewEd25519Signer(priv ed25519.PrivateKey) Signer {
+	return &ed25519Signer{priv}
+}
+
+func (ed25519Verifier) Scheme() Scheme {
+	return Ed25519
+}
+
+func (ed25519Verifier) Verify(pubKey, data, sig []byte) bool {
+	if len(pubKey) != ed25519.PublicKeySize || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(pubKey), data, sig)
+}
+
+func (ed25519Verifier) Generate() (Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewEd25519Signer(priv), nil
+}
+
+func (s *ed25519Signer) Scheme() Scheme {
+	return Ed25519
+}
+
+func (s *ed25519Signer) PublicKey() []byte {
+	return Tag(Ed25519, s.priv.Public().(ed25519.PublicKey))
+}
+
+func (s *ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return Tag(Ed25519, ed25519.Sign(s.priv, data)), nil
+}
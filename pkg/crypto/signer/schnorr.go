@@ -0,0 +1,128 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"math/big"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+// Schnorr signatures on P-256. Keys are SEC1 points and a
+// signature is the compressed nonce point R followed by the 32 bytes scalar s,
+// with s = k + e*x and e = H(R || P || m) mod n.
+type schnorrVerifier struct{}
+
+type schnorrSigner struct {
+	priv *ecdsa.PrivateKey
+}
+
+// NewSchnorrSigner creates a Schnorr P-256 signer from the private key
+func NewSchnorrSigner(priv *ecdsa.PrivateKey) Signer {
+	return &schnorrSigner{priv}
+}
+
+func (schnorrVerifier) Scheme() Scheme {
+	return SchnorrP256
+}
+
+func (schnorrVerifier) Verify(pubKey, data, sig []byte) bool {
+	curve := elliptic.P256()
+	N := curve.Params().N
+
+	pub, err := keys.Parse(pubKey)
+	if err != nil || len(sig) != 1+2*scalarSize {
+		return false
+	}
+	rx, ry := elliptic.UnmarshalCompressed(curve, sig[:1+scalarSize])
+	if rx == nil {
+		return false
+	}
+	s := new(big.Int).SetBytes(sig[1+scalarSize:])
+	if s.Cmp(N) >= 0 {
+		return false
+	}
+
+	// s*G == R + e*P
+	e := SchnorrChallenge(sig[:1+scalarSize], pub.Uncompressed(), data)
+	lx, ly := curve.ScalarBaseMult(s.Bytes())
+	ex, ey := curve.ScalarMult(pub.X, pub.Y, e.Bytes())
+	qx, qy := curve.Add(rx, ry, ex, ey)
+
+	return lx.Cmp(qx) == 0 && ly.Cmp(qy) == 0
+}
+
+func (schnorrVerifier) Generate() (Signer, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewSchnorrSigner(priv), nil
+}
+
+func (s *schnorrSigner) Scheme() Scheme {
+	return SchnorrP256
+}
+
+func (s *schnorrSigner) PublicKey() []byte {
+	return Tag(SchnorrP256, elliptic.Marshal(s.priv.Curve, s.priv.X, s.priv.Y))
+}
+
+func (s *schnorrSigner) Sign(data []byte) ([]byte, error) {
+	curve := elliptic.P256()
+	N := curve.Params().N
+
+	k, err := schnorrNonce(s.priv, data)
+	if err != nil {
+		return nil, err
+	}
+	rx, ry := curve.ScalarBaseMult(k.Bytes())
+	R := elliptic.MarshalCompressed(curve, rx, ry)
+
+	pubKey := elliptic.Marshal(curve, s.priv.X, s.priv.Y)
+	e := SchnorrChallenge(R, pubKey, data)
+
+	sv := new(big.Int).Mul(e, s.priv.D)
+	sv.Add(sv, k)
+	sv.Mod(sv, N)
+
+	sig := make([]byte, 1+2*scalarSize)
+	copy(sig, R)
+	sv.FillBytes(sig[1+scalarSize:])
+	return Tag(SchnorrP256, sig), nil
+}
+
+// SchnorrChallenge hashes the compressed nonce point, the uncompressed public
+// key and the message into a scalar
+func SchnorrChallenge(R, pubKey, data []byte) *big.Int {
+	h := sha256.New()
+	h.Write(R)
+	h.Write(pubKey)
+	h.Write(data)
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, elliptic.P256().Params().N)
+}
+
+// schnorrNonce derives the nonce from the private key, the message and fresh
+// randomness so a weak random source alone does not leak the key
+func schnorrNonce(priv *ecdsa.PrivateKey, data []byte) (*big.Int, error) {
+	N := elliptic.P256().Params().N
+	entropy := make([]byte, scalarSize)
+	if _, err := io.ReadFull(rand.Reader, entropy); err != nil {
+		return nil, err
+	}
+	for counter := byte(0); ; counter++ {
+		h := sha256.New()
+		h.Write(priv.D.Bytes())
+		h.Write(data)
+		h.Write(entropy)
+		h.Write([]byte{counter})
+		k := new(big.Int).SetBytes(h.Sum(nil))
+		if k.Sign() > 0 && k.Cmp(N) < 0 {
+			return k, nil
+		}
+	}
+}
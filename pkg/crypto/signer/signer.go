@@ -0,0 +1,135 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+// Scheme identifies a signature scheme. It is embedded in tagged keys and
+// signatures so several schemes can be used side by side on the network.
+type Scheme byte
+
+const (
+	ECDSAP256   Scheme = 0x01
+	Ed25519     Scheme = 0x02
+	SchnorrP256 Scheme = 0x03
+
+	// MuSigP256 tags MuSig2 aggregate signatures, Schnorr P-256 signatures
+	// of the aggregate of several keys. They are verified by the musig package.
+	MuSigP256 Scheme = 0x04
+)
+
+// tagMarker starts every tagged key and signature. Untagged keys are SEC1
+// points or legacy big-endian integers without leading zeros and legacy
+// signatures are such integers too, so they never start with it.
+const tagMarker = 0x00
+
+var (
+	ErrUnknownScheme  = errors.New("Unknown signature scheme")
+	ErrSchemeMismatch = errors.New("Key and signature use different schemes")
+)
+
+// Signer signs data with a private key of a registered scheme
+type Signer interface {
+	Scheme() Scheme
+
+	// PublicKey returns the tagged public key
+	PublicKey() []byte
+
+	// Sign returns the tagged signature of the data
+	Sign(data []byte) ([]byte, error)
+}
+
+// Verifier checks signatures of a scheme. Keys and signatures passed to it
+// are untagged.
+type Verifier interface {
+	Scheme() Scheme
+	Verify(pubKey, data, sig []byte) bool
+
+	// Generate creates a signer with a new random key
+	Generate() (Signer, error)
+}
+
+var (
+	registry = make(map[Scheme]Verifier)
+	lock     sync.RWMutex
+)
+
+func init() {
+	Register(ecdsaVerifier{})
+	Register(ed25519Verifier{})
+	Register(schnorrVerifier{})
+}
+
+// Register adds a signature scheme, replacing any verifier registered for the
+// same scheme
+func Register(v Verifier) {
+	lock.Lock()
+	defer lock.Unlock()
+	registry[v.Scheme()] = v
+}
+
+// Lookup returns the verifier of a registered scheme
+func Lookup(scheme Scheme) (Verifier, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	v, ok := registry[scheme]
+	if !ok {
+		return nil, fmt.Errorf("%w: 0x%02x", ErrUnknownScheme, byte(scheme))
+	}
+	return v, nil
+}
+
+// Generate creates a signer of the scheme with a new random key
+func Generate(scheme Scheme) (Signer, error) {
+	v, err := Lookup(scheme)
+	if err != nil {
+		return nil, err
+	}
+	return v.Generate()
+}
+
+// Tag prefixes a raw key or signature with its scheme
+func Tag(scheme Scheme, raw []byte) []byte {
+	return append([]byte{tagMarker, byte(scheme)}, raw...)
+}
+
+// Untag splits a tagged key or signature into its scheme and raw value.
+// Untagged values are ECDSA P-256 keys and legacy signatures.
+func Untag(value []byte) (scheme Scheme, raw []byte, tagged bool) {
+	if len(value) >= 2 && value[0] == tagMarker {
+		return Scheme(value[1]), value[2:], true
+	}
+	return ECDSAP256, value, false
+}
+
+// Verify checks the signature of the data with the public key. Both must use
+// the same scheme. Untagged keys are ECDSA P-256 keys in the SEC1 or legacy
+// encoding and untagged signatures legacy ECDSA P-256 ones.
+func Verify(pubKey, data, sig []byte) (bool, error) {
+	keyScheme, rawKey, keyTagged := Untag(pubKey)
+	sigScheme, rawSig, sigTagged := Untag(sig)
+
+	if keyTagged == false {
+		pub, err := keys.ParseAny(rawKey)
+		if err != nil {
+			return false, err
+		}
+		if sigTagged == false {
+			return verifyLegacy(pub, data, rawSig), nil
+		}
+		rawKey = pub.Uncompressed()
+	}
+	if keyScheme != sigScheme || sigTagged == false {
+		return false, ErrSchemeMismatch
+	}
+
+	v, err := Lookup(keyScheme)
+	if err != nil {
+		return false, err
+	}
+	return v.Verify(rawKey, data, rawSig), nil
+}
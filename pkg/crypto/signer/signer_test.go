@@ -0,0 +1,91 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+var schemes = []Scheme{ECDSAP256, Ed25519, SchnorrP256}
+
+func TestSignVerify(t *testing.T) {
+	data := []byte("election result")
+
+	for _, scheme := range schemes {
+		s, err := Generate(scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := s.Sign(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if keyScheme, _, tagged := Untag(s.PublicKey()); !tagged || keyScheme != scheme {
+			t.Fatalf("scheme 0x%02x: key tagged with 0x%02x", byte(scheme), byte(keyScheme))
+		}
+		if verified, err := Verify(s.PublicKey(), data, sig); !verified || err != nil {
+			t.Fatalf("scheme 0x%02x: valid signature rejected (%v)", byte(scheme), err)
+		}
+		if verified, _ := Verify(s.PublicKey(), []byte("other result"), sig); verified {
+			t.Fatalf("scheme 0x%02x: signature verified for other data", byte(scheme))
+		}
+
+		tampered := append([]byte{}, sig...)
+		tampered[len(tampered)-1] ^= 0x01
+		if verified, _ := Verify(s.PublicKey(), data, tampered); verified {
+			t.Fatalf("scheme 0x%02x: tampered signature verified", byte(scheme))
+		}
+
+		other, _ := Generate(scheme)
+		if verified, _ := Verify(other.PublicKey(), data, sig); verified {
+			t.Fatalf("scheme 0x%02x: signature verified with another key", byte(scheme))
+		}
+	}
+}
+
+func TestSchemeMismatch(t *testing.T) {
+	data := []byte("election result")
+	es, _ := Generate(ECDSAP256)
+	schnorrSigner := NewSchnorrSigner(es.(*ecdsaSigner).priv)
+
+	sig, _ := schnorrSigner.Sign(data)
+	if _, err := Verify(es.PublicKey(), data, sig); !errors.Is(err, ErrSchemeMismatch) {
+		t.Fatalf("expected a scheme mismatch, got %v", err)
+	}
+	if _, err := Verify(Tag(Scheme(0x7f), []byte{1}), data, Tag(Scheme(0x7f), []byte{1})); !errors.Is(err, ErrUnknownScheme) {
+		t.Fatalf("expected an unknown scheme, got %v", err)
+	}
+}
+
+func TestLegacySignature(t *testing.T) {
+	data := []byte("election result")
+
+	// Legacy keys and signatures drop leading zeros, try keys until one of
+	// the values is short
+	for i := 0; i < 10000; i++ {
+		priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		r, s, _ := ecdsa.Sign(rand.Reader, priv, data)
+		pubKey := append(priv.X.Bytes(), priv.Y.Bytes()...)
+		sig := append(r.Bytes(), s.Bytes()...)
+		if len(pubKey) == 2*scalarSize && len(sig) == 2*scalarSize {
+			continue
+		}
+
+		if verified, err := Verify(pubKey, data, sig); !verified || err != nil {
+			t.Fatalf("legacy signature rejected (%v)", err)
+		}
+		tagged, _ := NewECDSASigner(priv).Sign(data)
+		if verified, err := Verify(pubKey, data, tagged); !verified || err != nil {
+			t.Fatalf("tagged signature rejected for a legacy key (%v)", err)
+		}
+		schnorr, _ := NewSchnorrSigner(priv).Sign(data)
+		if _, err := Verify(pubKey, data, schnorr); !errors.Is(err, ErrSchemeMismatch) {
+			t.Fatalf("expected a scheme mismatch for a legacy key, got %v", err)
+		}
+		return
+	}
+	t.Fatal("no legacy key with short values generated")
+}
@@ -0,0 +1,64 @@
+// Package prompt reads secrets such as wallet passphrases from the terminal.
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// PassphraseEnv holds the passphrase for scripts running without a terminal
+const PassphraseEnv = "EV_WALLET_PASSPHRASE"
+
+var ErrPassphraseMismatch = errors.New("Passphrases do not match")
+
+// stdin is shared so lines buffered by one prompt are not lost to the next
+var stdin = bufio.NewReader(os.Stdin)
+
+// Passphrase asks for a passphrase without echoing it. The passphrase in the
+// PassphraseEnv environment variable is used instead when set.
+func Passphrase(message string) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(PassphraseEnv); ok {
+		return []byte(passphrase), nil
+	}
+	return Secret(message)
+}
+
+// Secret asks for a secret such as a mnemonic without echoing it
+func Secret(message string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, message)
+	defer fmt.Fprintln(os.Stderr)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		return term.ReadPassword(fd)
+	}
+	line, err := stdin.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// NewPassphrase asks for a new passphrase twice and checks both match
+func NewPassphrase(message string) ([]byte, error) {
+	passphrase, err := Passphrase(message)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := os.LookupEnv(PassphraseEnv); ok {
+		return passphrase, nil
+	}
+	confirmation, err := Passphrase("Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, confirmation) {
+		return nil, ErrPassphraseMismatch
+	}
+	return passphrase, nil
+}
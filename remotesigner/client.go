@@ -0,0 +1,152 @@
+package remotesigner
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+)
+
+// Time the node waits for the signer to answer a request
+const requestTimeout = 10 * time.Second
+
+// Client is the signer of a node whose keys are held by a signer daemon, it
+// implements blockchain.NodeSigner
+type Client struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	pubKey    []byte
+
+	// Requests are sent one at a time over the connection
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Dial connects to the signer daemon at the address. TCP connections require
+// the TLS configuration authenticating the node and the daemon.
+func Dial(addr string, config *TLSConfig) (*Client, error) {
+	network, address, err := parseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{network: network, address: address}
+	if network == "tcp" {
+		if config == nil {
+			return nil, ErrTLSRequired
+		}
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if client.tlsConfig, err = config.client(host); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := client.call(Request{Method: METHOD_PUBLIC_KEY})
+	if err != nil {
+		return nil, err
+	}
+	client.pubKey = response.PubKey
+	return client, nil
+}
+
+func (c *Client) PublicKey() []byte {
+	return c.pubKey
+}
+
+func (c *Client) SignBlock(header blockchain.BlockHeader) ([]byte, error) {
+	return c.sign(Request{Method: METHOD_SIGN_BLOCK, Header: &header})
+}
+
+func (c *Client) SignReceipt(r blockchain.Receipt) ([]byte, error) {
+	return c.sign(Request{Method: METHOD_SIGN_RECEIPT, Receipt: &r})
+}
+
+func (c *Client) SignTx(tx blockchain.Transaction) ([]byte, error) {
+	return c.sign(Request{Method: METHOD_SIGN_TX, Tx: &tx})
+}
+
+func (c *Client) sign(request Request) ([]byte, error) {
+	response, err := c.call(request)
+	if err != nil {
+		return nil, err
+	}
+	return response.Signature, nil
+}
+
+// Close closes the connection to the signer daemon
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// call sends the request and waits for the answer of the signer. The
+// connection is opened again when the previous one was lost.
+func (c *Client) call(request Request) (Response, error) {
+	var response Response
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connect(); err != nil {
+		return response, err
+	}
+
+	err := c.roundTrip(request, &response)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return response, err
+	}
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+	return response, nil
+}
+
+func (c *Client) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	var conn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, c.network, c.address, c.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(c.network, c.address)
+	}
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	return nil
+}
+
+func (c *Client) roundTrip(request Request, response *Response) error {
+	if err := c.conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return err
+	}
+	if err := json.NewEncoder(c.conn).Encode(request); err != nil {
+		return err
+	}
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, response)
+}
@@ -0,0 +1,125 @@
+package remotesigner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+)
+
+var (
+	ErrPolicyDenied = errors.New("Request denied by the signer policy")
+	ErrDoubleSign   = errors.New("Another block was already signed at this height")
+)
+
+// Policy lists what the signer accepts to sign. Anything not allowed is
+// refused.
+type Policy struct {
+	// Election public keys the commission key contributes to
+	Elections []string `json:"elections"`
+	// Phases the commission key contributes to, all phases when empty
+	Phases []string `json:"phases"`
+	// Sign the blocks the node adds
+	Blocks bool `json:"blocks"`
+	// Sign the ballot receipts the node issues
+	Receipts bool `json:"receipts"`
+}
+
+// LoadPolicy reads a JSON policy file
+func LoadPolicy(file string) (Policy, error) {
+	var policy Policy
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return policy, err
+	}
+	err = json.Unmarshal(content, &policy)
+	return policy, err
+}
+
+// CheckTx checks that the transaction is one of the allowed elections and
+// phases
+func (p *Policy) CheckTx(tx *blockchain.Transaction) error {
+	phase, err := Phase(tx)
+	if err != nil {
+		return err
+	}
+	if contains(p.Elections, string(tx.ElectionPubkey)) == false {
+		return fmt.Errorf("%w: election %s", ErrPolicyDenied, tx.ElectionPubkey)
+	}
+	if len(p.Phases) > 0 && contains(p.Phases, phase) == false {
+		return fmt.Errorf("%w: phase %s of election %s", ErrPolicyDenied, phase, tx.ElectionPubkey)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Watermark is the last block the signer signed. It is kept on disk so a
+// restarted signer never signs two different blocks at the same height.
+type Watermark struct {
+	Height int    `json:"height"`
+	Hash   []byte `json:"hash"`
+}
+
+// LoadWatermark reads the watermark file, a missing file is an empty watermark
+func LoadWatermark(file string) (Watermark, error) {
+	var watermark Watermark
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return watermark, nil
+	}
+	if err != nil {
+		return watermark, err
+	}
+	err = json.Unmarshal(content, &watermark)
+	return watermark, err
+}
+
+// Check refuses blocks below the watermark and other blocks at its height.
+// Headers are signed once their hash matches them, and the hash commits to the
+// height, so a signed block cannot come back at another height.
+func (w *Watermark) Check(header blockchain.BlockHeader) error {
+	if header.Height < w.Height {
+		return fmt.Errorf("%w: height %d is below the last signed height %d", ErrDoubleSign, header.Height, w.Height)
+	}
+	if header.Height == w.Height && len(w.Hash) > 0 && bytes.Compare(header.Hash, w.Hash) != 0 {
+		return fmt.Errorf("%w: height %d", ErrDoubleSign, header.Height)
+	}
+	return nil
+}
+
+// Save writes the watermark to a temporary file renamed over the previous one
+func (w *Watermark) Save(file string) error {
+	content, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,135 @@
+package remotesigner
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+)
+
+func electionTx(t *testing.T, pubKey string) *blockchain.Transaction {
+	t.Helper()
+	out := blockchain.NewElectionTxOutput("Election", "", []byte(pubKey), nil, nil, nil, 1)
+	tx, err := blockchain.NewTransaction(blockchain.ELECTION_TX_TYPE, []byte(pubKey), blockchain.TxInput{}, *out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func votingTx(t *testing.T, pubKey string) *blockchain.Transaction {
+	t.Helper()
+	out := blockchain.NewVotingTxOutput([]byte(pubKey), []byte("election"), nil, nil, time.Now().Unix())
+	tx, err := blockchain.NewTransaction(blockchain.VOTING_TX_TYPE, []byte(pubKey), blockchain.TxInput{}, *out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestCheckTx(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		tx     *blockchain.Transaction
+		err    error
+	}{
+		{"allowed election", Policy{Elections: []string{"alpha"}}, electionTx(t, "alpha"), nil},
+		{
+			name:   "allowed phase",
+			policy: Policy{Elections: []string{"alpha"}, Phases: []string{PHASE_START_VOTING}},
+			tx:     votingTx(t, "alpha"),
+		},
+		{"other election", Policy{Elections: []string{"alpha"}}, electionTx(t, "beta"), ErrPolicyDenied},
+		{"no elections", Policy{}, electionTx(t, "alpha"), ErrPolicyDenied},
+		{
+			name:   "phase not allowed",
+			policy: Policy{Elections: []string{"alpha"}, Phases: []string{PHASE_START_VOTING}},
+			tx:     electionTx(t, "alpha"),
+			err:    ErrPolicyDenied,
+		},
+		{"not a commission transaction", Policy{Elections: []string{"alpha"}}, &blockchain.Transaction{}, blockchain.ErrNotCommissionTx},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.CheckTx(test.tx)
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestWatermarkCheck(t *testing.T) {
+	watermark := Watermark{Height: 5, Hash: []byte{0x05}}
+
+	tests := []struct {
+		name   string
+		header blockchain.BlockHeader
+		err    error
+	}{
+		{"next height", blockchain.BlockHeader{Height: 6, Hash: []byte{0x06}}, nil},
+		{"same block", blockchain.BlockHeader{Height: 5, Hash: []byte{0x05}}, nil},
+		{"other block at the height", blockchain.BlockHeader{Height: 5, Hash: []byte{0x55}}, ErrDoubleSign},
+		{"below the height", blockchain.BlockHeader{Height: 4, Hash: []byte{0x04}}, ErrDoubleSign},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := watermark.Check(test.header)
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != nil && errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+
+	var empty Watermark
+	if err := empty.Check(blockchain.BlockHeader{Height: 0, Hash: []byte{0x00}}); err != nil {
+		t.Errorf("expected the genesis block to be accepted, got %v", err)
+	}
+}
+
+func TestWatermarkSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "watermark.json")
+
+	loaded, err := LoadWatermark(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Height != 0 || loaded.Hash != nil {
+		t.Fatalf("expected an empty watermark, got %+v", loaded)
+	}
+
+	watermark := Watermark{Height: 7, Hash: []byte{0x07}}
+	if err = watermark.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = LoadWatermark(file); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Height != watermark.Height || bytes.Compare(loaded.Hash, watermark.Hash) != 0 {
+		t.Errorf("expected %+v, got %+v", watermark, loaded)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected the temporary file to be removed, got %d files", len(files))
+	}
+}
@@ -0,0 +1,138 @@
+// Package remotesigner keeps the signing keys of a node out of the node. The
+// node asks a signer daemon for its block signatures, ballot receipts and
+// commission contributions over a local Unix socket or a TCP connection with
+// mutual TLS, and the daemon checks every request against its policy.
+//
+// Requests and responses are JSON objects, one per line.
+package remotesigner
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+)
+
+// Methods of the signer protocol
+const METHOD_PUBLIC_KEY = "public_key"
+const METHOD_SIGN_BLOCK = "sign_block"
+const METHOD_SIGN_RECEIPT = "sign_receipt"
+const METHOD_SIGN_TX = "sign_tx"
+
+// Election phases the commission contributes a signature to, named after the
+// RPC methods submitting them
+const PHASE_START_ELECTION = "start_election"
+const PHASE_STOP_ELECTION = "stop_election"
+const PHASE_START_ACCREDITATION = "start_accreditation"
+const PHASE_STOP_ACCREDITATION = "stop_accreditation"
+const PHASE_START_VOTING = "start_voting"
+const PHASE_STOP_VOTING = "stop_voting"
+const PHASE_CREATE_BALLOT = "create_ballot"
+const PHASE_REVOKE_CERTIFICATES = "revoke_certificates"
+
+var (
+	ErrInvalidAddress = errors.New("Invalid signer address")
+	ErrTLSRequired    = errors.New("TCP signer connections require mutual TLS")
+	ErrUnknownMethod  = errors.New("Unknown signer method")
+)
+
+// Request is sent by the node to the signer
+type Request struct {
+	Method  string                  `json:"method"`
+	Header  *blockchain.BlockHeader `json:"header,omitempty"`
+	Receipt *blockchain.Receipt     `json:"receipt,omitempty"`
+	Tx      *blockchain.Transaction `json:"tx,omitempty"`
+}
+
+// Response is the answer of the signer to a request
+type Response struct {
+	PubKey    []byte `json:"pubkey,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Phase returns the election phase a commission transaction belongs to
+func Phase(tx *blockchain.Transaction) (string, error) {
+	switch {
+	case tx.Output.ElectionTx.IsSet():
+		return PHASE_START_ELECTION, nil
+	case tx.Input.ElectionTx.IsSet():
+		return PHASE_STOP_ELECTION, nil
+	case tx.Output.AccreditationTx.IsSet():
+		return PHASE_START_ACCREDITATION, nil
+	case tx.Input.AccreditationTx.IsSet():
+		return PHASE_STOP_ACCREDITATION, nil
+	case tx.Output.VotingTx.IsSet():
+		return PHASE_START_VOTING, nil
+	case tx.Input.VotingTx.IsSet():
+		return PHASE_STOP_VOTING, nil
+	case tx.Output.BallotTx.IsSet():
+		return PHASE_CREATE_BALLOT, nil
+	case tx.Output.RevocationTx.IsSet():
+		return PHASE_REVOKE_CERTIFICATES, nil
+	}
+	return "", blockchain.ErrNotCommissionTx
+}
+
+// parseAddress splits a signer address such as unix:///run/ev/signer.sock or
+// tcp://10.0.0.2:7100 into its network and address. Addresses without a
+// scheme are TCP addresses.
+func parseAddress(addr string) (network, address string, err error) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		network, address = "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "tcp://"):
+		network, address = "tcp", strings.TrimPrefix(addr, "tcp://")
+	case strings.Contains(addr, "://"):
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidAddress, addr)
+	default:
+		network, address = "tcp", addr
+	}
+	if address == "" {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidAddress, addr)
+	}
+	return network, address, nil
+}
+
+// Listen opens the listener of the signer daemon. Unix sockets are only
+// accessible to their owner, TCP listeners require the TLS configuration
+// authenticating the nodes.
+func Listen(addr string, config *TLSConfig) (net.Listener, error) {
+	network, address, err := parseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		// Remove the socket left by a previous daemon
+		if info, err := os.Lstat(address); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return nil, fmt.Errorf("%w: %s is not a socket", ErrInvalidAddress, address)
+			}
+			if err = os.Remove(address); err != nil {
+				return nil, err
+			}
+		}
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			return nil, err
+		}
+		if err = os.Chmod(address, 0600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		return listener, nil
+	}
+
+	if config == nil {
+		return nil, ErrTLSRequired
+	}
+	tlsConfig, err := config.server()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen(network, address, tlsConfig)
+}
@@ -0,0 +1,152 @@
+package remotesigner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+
+	logger "github.com/sirupsen/logrus"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+)
+
+// Maximum size of a request line, blocks are sent as headers without their
+// transactions
+const maxRequestSize = 4 << 20
+
+// Server is the signer daemon. It holds the keys and signs the requests of the
+// nodes allowed by its policy.
+type Server struct {
+	signer        blockchain.NodeSigner
+	policy        Policy
+	watermarkFile string
+
+	// Guards the watermark, block signatures are handed out one at a time
+	mu        sync.Mutex
+	watermark Watermark
+}
+
+// NewServer creates a signer daemon, the watermark of the last signed block
+// is loaded from the file
+func NewServer(signer blockchain.NodeSigner, policy Policy, watermarkFile string) (*Server, error) {
+	watermark, err := LoadWatermark(watermarkFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		signer:        signer,
+		policy:        policy,
+		watermarkFile: watermarkFile,
+		watermark:     watermark,
+	}, nil
+}
+
+// Serve answers the requests of the nodes connecting to the listener
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	logger.Infof("Signer connection from %s", conn.RemoteAddr())
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxRequestSize)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var request Request
+		var response Response
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response.Error = err.Error()
+		} else {
+			response = s.Handle(request)
+		}
+		if response.Error != "" {
+			logger.Warnf("Signer refused %s from %s: %s", request.Method, conn.RemoteAddr(), response.Error)
+		}
+		if err := encoder.Encode(response); err != nil {
+			logger.Error(err)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Error(err)
+	}
+}
+
+// Handle checks a request against the policy and signs it
+func (s *Server) Handle(request Request) Response {
+	var sig []byte
+	var err error
+
+	switch request.Method {
+	case METHOD_PUBLIC_KEY:
+		return Response{PubKey: s.signer.PublicKey()}
+	case METHOD_SIGN_BLOCK:
+		sig, err = s.signBlock(request.Header)
+	case METHOD_SIGN_RECEIPT:
+		sig, err = s.signReceipt(request.Receipt)
+	case METHOD_SIGN_TX:
+		sig, err = s.signTx(request.Tx)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownMethod, request.Method)
+	}
+
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return Response{PubKey: s.signer.PublicKey(), Signature: sig}
+}
+
+func (s *Server) signBlock(header *blockchain.BlockHeader) ([]byte, error) {
+	if s.policy.Blocks == false {
+		return nil, fmt.Errorf("%w: blocks", ErrPolicyDenied)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("%w: missing block header", blockchain.ErrInvalidBlockHeader)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.watermark.Check(*header); err != nil {
+		return nil, err
+	}
+	sig, err := s.signer.SignBlock(*header)
+	if err != nil {
+		return nil, err
+	}
+	// The signature is only released once the watermark is on disk
+	watermark := Watermark{header.Height, header.Hash}
+	if err = watermark.Save(s.watermarkFile); err != nil {
+		return nil, err
+	}
+	s.watermark = watermark
+	return sig, nil
+}
+
+func (s *Server) signReceipt(receipt *blockchain.Receipt) ([]byte, error) {
+	if s.policy.Receipts == false {
+		return nil, fmt.Errorf("%w: receipts", ErrPolicyDenied)
+	}
+	if receipt == nil {
+		return nil, fmt.Errorf("%w: missing receipt", blockchain.ErrInvalidReceipt)
+	}
+	return s.signer.SignReceipt(*receipt)
+}
+
+func (s *Server) signTx(tx *blockchain.Transaction) ([]byte, error) {
+	if tx == nil {
+		return nil, blockchain.ErrNotCommissionTx
+	}
+	if err := s.policy.CheckTx(tx); err != nil {
+		return nil, err
+	}
+	return s.signer.SignTx(*tx)
+}
@@ -0,0 +1,237 @@
+package remotesigner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+func newTestSigner(t *testing.T) *blockchain.LocalSigner {
+	t.Helper()
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blockchain.NewLocalSigner(*privKey, keys.FromECDSA(&privKey.PublicKey).Bytes())
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// header returns a block header whose hash matches its content
+func header(height int, root byte) blockchain.BlockHeader {
+	block := blockchain.Block{
+		Timestamp:  1600000000,
+		Version:    blockchain.Version,
+		Height:     height,
+		MerkleRoot: []byte{root},
+		PrevHash:   []byte{0x01},
+	}
+	return blockchain.BlockHeader{
+		Timestamp:  block.Timestamp,
+		Version:    block.Version,
+		Height:     height,
+		MerkleRoot: block.MerkleRoot,
+		PrevHash:   block.PrevHash,
+		Hash:       block.GetHashData(),
+	}
+}
+
+// refused checks that the response is an error wrapping err
+func refused(t *testing.T, response Response, err error) {
+	t.Helper()
+	if response.Signature != nil {
+		t.Fatal("expected no signature")
+	}
+	if strings.HasPrefix(response.Error, err.Error()) == false {
+		t.Fatalf("expected %v, got %q", err, response.Error)
+	}
+}
+
+func TestHandlePolicy(t *testing.T) {
+	signer := newTestSigner(t)
+	block := header(1, 0x01)
+	receipt := blockchain.Receipt{TxID: []byte{0x01}, NodePubKey: signer.PublicKey()}
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		request Request
+		err     error
+	}{
+		{"block", Policy{Blocks: true}, Request{Method: METHOD_SIGN_BLOCK, Header: &block}, nil},
+		{"blocks not allowed", Policy{}, Request{Method: METHOD_SIGN_BLOCK, Header: &block}, ErrPolicyDenied},
+		{"missing header", Policy{Blocks: true}, Request{Method: METHOD_SIGN_BLOCK}, blockchain.ErrInvalidBlockHeader},
+		{"receipt", Policy{Receipts: true}, Request{Method: METHOD_SIGN_RECEIPT, Receipt: &receipt}, nil},
+		{"receipts not allowed", Policy{}, Request{Method: METHOD_SIGN_RECEIPT, Receipt: &receipt}, ErrPolicyDenied},
+		{"missing receipt", Policy{Receipts: true}, Request{Method: METHOD_SIGN_RECEIPT}, blockchain.ErrInvalidReceipt},
+		{
+			name:    "contribution",
+			policy:  Policy{Elections: []string{"alpha"}},
+			request: Request{Method: METHOD_SIGN_TX, Tx: electionTx(t, "alpha")},
+		},
+		{
+			name:    "contribution to another election",
+			policy:  Policy{Elections: []string{"alpha"}},
+			request: Request{Method: METHOD_SIGN_TX, Tx: electionTx(t, "beta")},
+			err:     ErrPolicyDenied,
+		},
+		{
+			name:    "contribution to another phase",
+			policy:  Policy{Elections: []string{"alpha"}, Phases: []string{PHASE_STOP_VOTING}},
+			request: Request{Method: METHOD_SIGN_TX, Tx: electionTx(t, "alpha")},
+			err:     ErrPolicyDenied,
+		},
+		{"missing transaction", Policy{Elections: []string{"alpha"}}, Request{Method: METHOD_SIGN_TX}, blockchain.ErrNotCommissionTx},
+		{"unknown method", Policy{Blocks: true, Receipts: true}, Request{Method: "sign_anything"}, ErrUnknownMethod},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, err := NewServer(signer, test.policy, filepath.Join(tempDir(t), "watermark.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response := server.Handle(test.request)
+			if test.err != nil {
+				refused(t, response, test.err)
+				return
+			}
+			if response.Error != "" || response.Signature == nil {
+				t.Fatalf("expected a signature, got %q", response.Error)
+			}
+		})
+	}
+}
+
+func TestHandleWatermark(t *testing.T) {
+	signer := newTestSigner(t)
+	file := filepath.Join(tempDir(t), "watermark.json")
+	server, err := NewServer(signer, Policy{Blocks: true}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(server *Server, header blockchain.BlockHeader) Response {
+		return server.Handle(Request{Method: METHOD_SIGN_BLOCK, Header: &header})
+	}
+
+	if response := sign(server, header(2, 0x02)); response.Error != "" {
+		t.Fatal(response.Error)
+	}
+	if response := sign(server, header(2, 0x02)); response.Error != "" {
+		t.Fatalf("expected the same block to be signed again, got %q", response.Error)
+	}
+	refused(t, sign(server, header(2, 0x22)), ErrDoubleSign)
+	refused(t, sign(server, header(1, 0x01)), ErrDoubleSign)
+
+	// A restarted signer keeps the watermark
+	restarted, err := NewServer(signer, Policy{Blocks: true}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused(t, sign(restarted, header(2, 0x22)), ErrDoubleSign)
+	if response := sign(restarted, header(3, 0x03)); response.Error != "" {
+		t.Fatal(response.Error)
+	}
+
+	// Blocks whose hash does not match are not signed and leave the watermark
+	forged := header(4, 0x04)
+	forged.Hash = []byte{0x04}
+	refused(t, sign(restarted, forged), blockchain.ErrInvalidBlockHeader)
+	// The hash commits to the height, a block signed at height 2 cannot be
+	// signed again above the watermark
+	moved := header(2, 0x22)
+	moved.Height = 4
+	refused(t, sign(restarted, moved), blockchain.ErrInvalidBlockHeader)
+	watermark, err := LoadWatermark(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watermark.Height != 3 {
+		t.Errorf("expected the watermark at height 3, got %d", watermark.Height)
+	}
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	signer := newTestSigner(t)
+	dir := tempDir(t)
+	policy := Policy{Elections: []string{"alpha"}, Blocks: true}
+	server, err := NewServer(signer, policy, filepath.Join(dir, "watermark.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "unix://" + filepath.Join(dir, "signer.sock")
+	listener, err := Listen(addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go server.Serve(listener)
+
+	info, err := os.Stat(filepath.Join(dir, "signer.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the socket to be only accessible to its owner, got %v", info.Mode().Perm())
+	}
+
+	client, err := Dial(addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if keys.Equal(client.PublicKey(), signer.PublicKey()) == false {
+		t.Fatal("expected the public key of the signer")
+	}
+
+	block := header(1, 0x01)
+	sig, err := client.SignBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Signers, block.SigWitnesses = [][]byte{client.PublicKey()}, [][]byte{sig}
+	if err = block.VerifySignatures([][]byte{signer.PublicKey()}); err != nil {
+		t.Errorf("expected a valid block signature, got %v", err)
+	}
+
+	if _, err = client.SignTx(*electionTx(t, "alpha")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err = client.SignTx(*electionTx(t, "beta")); err == nil || strings.HasPrefix(err.Error(), ErrPolicyDenied.Error()) == false {
+		t.Errorf("expected %v, got %v", ErrPolicyDenied, err)
+	}
+	if _, err = client.SignReceipt(blockchain.Receipt{NodePubKey: signer.PublicKey()}); err == nil {
+		t.Error("expected receipts to be refused")
+	}
+	if _, err = client.SignBlock(header(1, 0x11)); err == nil || strings.HasPrefix(err.Error(), ErrDoubleSign.Error()) == false {
+		t.Errorf("expected %v, got %v", ErrDoubleSign, err)
+	}
+}
+
+func TestDialRequiresTLS(t *testing.T) {
+	if _, err := Dial("tcp://127.0.0.1:7100", nil); errors.Is(err, ErrTLSRequired) == false {
+		t.Fatalf("expected %v, got %v", ErrTLSRequired, err)
+	}
+	if _, err := Listen("tcp://127.0.0.1:0", nil); errors.Is(err, ErrTLSRequired) == false {
+		t.Fatalf("expected %v, got %v", ErrTLSRequired, err)
+	}
+	if _, err := Dial("http://127.0.0.1:7100", nil); errors.Is(err, ErrInvalidAddress) == false {
+		t.Fatalf("expected %v, got %v", ErrInvalidAddress, err)
+	}
+}
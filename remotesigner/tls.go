@@ -0,0 +1,64 @@
+package remotesigner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+var ErrInvalidCA = errors.New("No certificate found in the CA file")
+
+// TLSConfig holds the PEM files authenticating both ends of a TCP signer
+// connection. Each end presents its certificate and only accepts peers whose
+// certificate is issued by the CA.
+type TLSConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	CAFile   string `json:"ca_file"`
+}
+
+func (c *TLSConfig) load() (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	caPEM, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	pool := x509.NewCertPool()
+	if pool.AppendCertsFromPEM(caPEM) == false {
+		return tls.Certificate{}, nil, ErrInvalidCA
+	}
+	return cert, pool, nil
+}
+
+// server returns the configuration of the daemon, nodes must present a
+// client certificate
+func (c *TLSConfig) server() (*tls.Config, error) {
+	cert, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// client returns the configuration of a node connecting to the daemon
+func (c *TLSConfig) client(serverName string) (*tls.Config, error) {
+	cert, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
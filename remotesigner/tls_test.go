@@ -0,0 +1,126 @@
+package remotesigner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues the certificates of the signer and the nodes
+type testCA struct {
+	t    *testing.T
+	dir  string
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestCA(t *testing.T, dir, name string) *testCA {
+	t.Helper()
+	ca := &testCA{t: t, dir: dir}
+	ca.cert, ca.key = ca.issue(name, nil, nil)
+	return ca
+}
+
+func (ca *testCA) writePEM(file, blockType string, der []byte) string {
+	ca.t.Helper()
+	file = filepath.Join(ca.dir, file)
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		ca.t.Fatal(err)
+	}
+	return file
+}
+
+// issue creates a certificate signed by the CA, or a self-signed CA
+// certificate when parent is nil
+func (ca *testCA) issue(name string, parent *x509.Certificate, ips []net.IP) (*x509.Certificate, *ecdsa.PrivateKey) {
+	ca.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  ips,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	signerKey := key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent = template
+	} else {
+		signerKey = ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signerKey)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return cert, key
+}
+
+// config returns the TLS configuration of a peer certified by the CA
+func (ca *testCA) config(name string) *TLSConfig {
+	ca.t.Helper()
+	cert, key := ca.issue(name, ca.cert, []net.IP{net.ParseIP("127.0.0.1")})
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return &TLSConfig{
+		CertFile: ca.writePEM(name+".crt", "CERTIFICATE", cert.Raw),
+		KeyFile:  ca.writePEM(name+".key", "EC PRIVATE KEY", der),
+		CAFile:   ca.writePEM(ca.cert.Subject.CommonName+".crt", "CERTIFICATE", ca.cert.Raw),
+	}
+}
+
+func TestTLSRoundTrip(t *testing.T) {
+	signer := newTestSigner(t)
+	dir := tempDir(t)
+	ca := newTestCA(t, dir, "ca")
+	server, err := NewServer(signer, Policy{Blocks: true}, filepath.Join(dir, "watermark.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := Listen("tcp://127.0.0.1:0", ca.config("signer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go server.Serve(listener)
+	addr := "tcp://" + listener.Addr().String()
+
+	node := ca.config("node")
+	client, err := Dial(addr, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err = client.SignBlock(header(1, 0x01)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Nodes certified by another CA are refused
+	intruder := newTestCA(t, dir, "other").config("intruder")
+	intruder.CAFile = node.CAFile
+	if _, err = Dial(addr, intruder); err == nil {
+		t.Fatal("expected a node of another CA to be refused")
+	}
+}
@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	jrpc "github.com/gumeniukcom/golang-jsonrpc2"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/database"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/config"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/multisig"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+// memStore keeps the blockchain of a test in memory
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string][]byte)}
+}
+
+func (s *memStore) Get(k []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[string(k)]
+	if !ok {
+		return nil, database.ErrKeyNotFound
+	}
+	return append([]byte{}, v...), nil
+}
+
+func (s *memStore) Put(k, v []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[string(k)] = append([]byte{}, v...)
+	return nil
+}
+
+func (s *memStore) Delete(k []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, string(k))
+	return nil
+}
+
+func (s *memStore) Seek(k []byte, f func(k, v []byte)) {
+	s.mu.Lock()
+	var found []string
+	for key := range s.data {
+		if strings.HasPrefix(key, string(k)) {
+			found = append(found, key)
+		}
+	}
+	sort.Strings(found)
+	values := make([][]byte, len(found))
+	for i, key := range found {
+		values[i] = s.data[key]
+	}
+	s.mu.Unlock()
+
+	for i, key := range found {
+		f([]byte(key), values[i])
+	}
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
+
+// newTestHandler serves a blockchain kept in memory
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	bc := blockchain.NewBlockchain(newMemStore(), config.Config{}).Init()
+	return NewHandler(bc, jrpc.New(), nil).(*Handler)
+}
+
+// addElection adds an election signed by a commission of one member, setup
+// can change the election output before it is signed
+func addElection(t *testing.T, h *Handler, pubKey []byte, candidates [][]byte, setup func(out *blockchain.TxElectionOutput)) *blockchain.Transaction {
+	t.Helper()
+	out := blockchain.NewElectionTxOutput("Test", "Test election", pubKey, nil, nil, candidates, 10)
+	if setup != nil {
+		setup(&out.ElectionTx)
+	}
+	priv, pub := wallet.NewKeyPair()
+	mu := multisig.NewMultisig(1)
+	mu.AddSignature(out.ElectionTx.ToByte(), pub, *priv)
+	out.ElectionTx.Signers, out.ElectionTx.SigWitnesses = mu.PubKeys, mu.Sigs
+
+	tx, err := blockchain.NewTransaction(blockchain.ELECTION_TX_TYPE, pubKey, blockchain.TxInput{}, *out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = h.Blockchain.AddBlock([]*blockchain.Transaction{tx}); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// call runs the handler method with the request and decodes its response
+func call(t *testing.T, method func(context.Context, json.RawMessage) (json.RawMessage, int, error), request, response interface{}) (int, error) {
+	t.Helper()
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, code, err := method(context.Background(), data)
+	if err != nil {
+		return code, err
+	}
+	if err = json.Unmarshal(result, response); err != nil {
+		t.Fatal(err)
+	}
+	return code, nil
+}
@@ -4,21 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	jrpc "github.com/gumeniukcom/golang-jsonrpc2"
 	logger "github.com/sirupsen/logrus"
 	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
 )
 
 type Handler struct {
 	Blockchain *blockchain.Blockchain
 	Serve      *jrpc.JSONRPC
+	Signer     blockchain.NodeSigner  // Signs ballot receipts and blocks
+	Light      *blockchain.LightChain // Header-only chain of a light node
 }
 
 type HandlerEntity interface {
 	// Query all election results by election pubkey
 	QueryResults(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
 
+	// Query the results of each race of an election by election pubkey
+	QueryRaceResults(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
 	// Query all un used Ballot transactions
 	QueryUnUsedBallotTxs(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
 
@@ -60,90 +67,270 @@ type HandlerEntity interface {
 
 	//Finf transaction with transaction Output by public key
 	FindTransactionWithTxOutput(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
+	// Query turnout statistics by election pubkey
+	QueryTurnout(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
+	// Get the merkle inclusion proof of a transaction by ID
+	GetTxProof(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
+	// Verify a ballot receipt against the blockchain
+	VerifyReceipt(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
+	// Get the block headers from a height
+	GetHeaders(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
+	// Get the ballot ring of an accredited voter
+	GetRing(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
+	// Scan the unused ballot outputs of an election with a view key
+	ScanBallotOutputs(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
+	// Get the serials of the revoked voter certificates of an election
+	GetRevokedCertificates(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
+
+	// Revoke voter certificates by creating new TxOutput
+	RevokeCertificatesTx(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error)
 }
 
-func NewHandler(bc *blockchain.Blockchain, serve *jrpc.JSONRPC) HandlerEntity {
+func NewHandler(bc *blockchain.Blockchain, serve *jrpc.JSONRPC, signer blockchain.NodeSigner) HandlerEntity {
 	bc = bc.ReInit()
-	handler := &Handler{bc, serve}
+	if signer != nil {
+		bc.SetSigner(signer)
+	}
+	handler := &Handler{bc, serve, signer, nil}
 	registerHandlers(handler)
 	return handler
 }
 
+// NewLightHandler serves the RPC methods of a full node from a light node.
+// Transactions are checked against its block headers, methods carrying private
+// keys are refused and the other methods are forwarded to the full node it
+// follows.
+func NewLightHandler(lc *blockchain.LightChain, remote *RemoteSource, serve *jrpc.JSONRPC) HandlerEntity {
+	handler := &Handler{nil, serve, nil, lc}
+	registerLightHandlers(handler, remote)
+	return handler
+}
+
+// handlerMethod is an RPC method served by the handler
+type handlerMethod struct {
+	name   string
+	method jrpc.RPCMethod
+}
+
+// handlerMethods returns the RPC methods of a full node
+func handlerMethods(h *Handler) []handlerMethod {
+	return []handlerMethod{
+		{"QueryResults", h.QueryResults},
+		{"QueryRaceResults", h.QueryRaceResults},
+		{"QueryUnUsedBallotTxs", h.QueryUnUsedBallotTxs},
+		{"QueryTurnout", h.QueryTurnout},
+		{"GetRing", h.GetRing},
+		{"ScanBallotOutputs", h.ScanBallotOutputs},
+		{"GetRevokedCertificates", h.GetRevokedCertificates},
+		{"QueryBlockchain", h.QueryBlockchain},
+		{"QueryTransactions", h.QueryTransactions},
+		{"QueryTransactionsByPubkey", h.QueryTransactionsByPubkey},
+		{"GetTransaction", h.GetTransaction},
+		{"GetTxProof", h.GetTxProof},
+		{"VerifyReceipt", h.VerifyReceipt},
+		{"GetHeaders", h.GetHeaders},
+		{"FindTxWithTxOutput", h.FindTransactionWithTxOutput},
+		{"StartElection", h.StartElectionTx},
+		{"StopElection", h.StopElectionTx},
+		{"StartAccreditation", h.StartAccreditationTx},
+		{"StopAccreditation", h.StopAccreditationTx},
+		{"StartVoting", h.StartVotingTx},
+		{"StopVoting", h.StopVotingTx},
+		{"CreateBallot", h.CreateBallotTx},
+		{"CastBallot", h.CastBallotTx},
+		{"RevokeCertificates", h.RevokeCertificatesTx},
+	}
+}
+
+// Methods a light node answers from its block headers
+var lightMethods = map[string]bool{
+	"GetTransaction": true,
+	"GetTxProof":     true,
+	"GetHeaders":     true,
+}
+
+// Methods whose requests carry private keys, a light node does not send them
+// on to the full node it follows
+var secretMethods = map[string]bool{
+	"ScanBallotOutputs": true,
+}
+
+func registerLightHandlers(h *Handler, remote *RemoteSource) {
+	for _, m := range handlerMethods(h) {
+		method := m.method
+		switch {
+		case secretMethods[m.name]:
+			method = refuseSecretMethod(m.name)
+		case lightMethods[m.name] == false:
+			method = remote.forward(m.name)
+		}
+		if err := h.Serve.RegisterMethod(m.name, method); err != nil {
+			logger.Panic(err)
+		}
+	}
+}
+
+// refuseSecretMethod returns an RPC method refusing the requests of a method
+// carrying private keys
+func refuseSecretMethod(method string) jrpc.RPCMethod {
+	return func(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("%s carries private keys and is only served by full nodes", method)
+	}
+}
+
 func registerHandlers(h *Handler) {
-	if err := h.Serve.RegisterMethod("QueryResults", h.QueryResults); err != nil {
-		logger.Panic(err)
+	for _, m := range handlerMethods(h) {
+		if err := h.Serve.RegisterMethod(m.name, m.method); err != nil {
+			logger.Panic(err)
+		}
 	}
-	if err := h.Serve.RegisterMethod("QueryUnUsedBallotTxs", h.QueryUnUsedBallotTxs); err != nil {
-		logger.Panic(err)
+}
+
+type QueryResultsRequest struct {
+	PubKey []byte `json:"pubkey"`
+}
+
+type QueryResultsResponse struct {
+	Data map[string]int `json:"data"`
+}
+
+// QueryResults returns the votes of each candidate, elections with several
+// races are queried with QueryRaceResults
+func (h *Handler) QueryResults(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
 	}
-	if err := h.Serve.RegisterMethod("QueryBlockchain", h.QueryBlockchain); err != nil {
-		logger.Panic(err)
+	request := &QueryResultsRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
 	}
-	if err := h.Serve.RegisterMethod("QueryTransactions", h.QueryTransactions); err != nil {
-		logger.Panic(err)
+
+	results, err := h.Blockchain.QueryResult(request.PubKey)
+	if err != nil {
+		logger.Error(err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+	if len(results) > 1 {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Election has %d races, query them with QueryRaceResults", len(results))
+	}
+	response := QueryResultsResponse{
+		Data: map[string]int{},
 	}
-	if err := h.Serve.RegisterMethod("QueryTransactionsByPubkey", h.QueryTransactionsByPubkey); err != nil {
-		logger.Panic(err)
+	for _, race := range results {
+		response.Data = race.Tally
 	}
-	if err := h.Serve.RegisterMethod("GetTransaction", h.GetTransaction); err != nil {
-		logger.Panic(err)
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
 	}
 
-	if err := h.Serve.RegisterMethod("FindTxWithTxOutput", h.FindTransactionWithTxOutput); err != nil {
-		logger.Panic(err)
+	return mdata, jrpc.OK, nil
+}
+
+type QueryRaceResultsResponse struct {
+	Data map[string]blockchain.RaceResult `json:"data"`
+}
+
+func (h *Handler) QueryRaceResults(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
+	}
+	request := &QueryResultsRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
 	}
 
-	if err := h.Serve.RegisterMethod("StartElection", h.StartElectionTx); err != nil {
-		logger.Panic(err)
+	results, err := h.Blockchain.QueryResult(request.PubKey)
+	if err != nil {
+		logger.Error(err)
+		return nil, jrpc.InvalidRequestErrorCode, err
 	}
-	if err := h.Serve.RegisterMethod("StopElection", h.StopElectionTx); err != nil {
-		logger.Panic(err)
+	response := QueryRaceResultsResponse{
+		Data: results,
 	}
-	if err := h.Serve.RegisterMethod("StartAccreditation", h.StartAccreditationTx); err != nil {
-		logger.Panic(err)
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
 	}
-	if err := h.Serve.RegisterMethod("StopAccreditation", h.StopAccreditationTx); err != nil {
-		logger.Panic(err)
+
+	return mdata, jrpc.OK, nil
+}
+
+type QueryTurnoutRequest struct {
+	PubKey []byte `json:"pubkey"`
+}
+
+type QueryTurnoutResponse struct {
+	Data blockchain.ElectionStats `json:"data"`
+}
+
+func (h *Handler) QueryTurnout(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
 	}
-	if err := h.Serve.RegisterMethod("StartVoting", h.StartVotingTx); err != nil {
-		logger.Panic(err)
+	request := &QueryTurnoutRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
 	}
-	if err := h.Serve.RegisterMethod("StopVoting", h.StopVotingTx); err != nil {
-		logger.Panic(err)
+
+	results, err := h.Blockchain.GetElectionStats(request.PubKey)
+	if err != nil {
+		logger.Error(err)
+		return nil, jrpc.InvalidRequestErrorCode, err
 	}
-	if err := h.Serve.RegisterMethod("CreateBallot", h.CreateBallotTx); err != nil {
-		logger.Panic(err)
+	response := QueryTurnoutResponse{
+		Data: results,
 	}
-	if err := h.Serve.RegisterMethod("CastBallot", h.CastBallotTx); err != nil {
-		logger.Panic(err)
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
 	}
+
+	return mdata, jrpc.OK, nil
 }
 
-type QueryResultsRequest struct {
+type GetRingRequest struct {
 	PubKey []byte `json:"pubkey"`
+	Voter  []byte `json:"voter"`
 }
 
-type QueryResultsResponse struct {
-	Data map[string]int `json:"data"`
+type GetRingResponse struct {
+	Data [][]byte `json:"data"`
 }
 
-func (h *Handler) QueryResults(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+func (h *Handler) GetRing(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
 	if data == nil {
 		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
 	}
-	request := &QueryResultsRequest{}
+	request := &GetRingRequest{}
 	err := json.Unmarshal(data, request)
 	if err != nil {
 		logger.Error("UnMarshal Error: ", err)
 		return nil, jrpc.InvalidRequestErrorCode, err
 	}
 
-	results, err := h.Blockchain.QueryResult(request.PubKey)
+	results, err := h.Blockchain.GetRing(request.PubKey, request.Voter)
 	if err != nil {
 		logger.Error(err)
 		return nil, jrpc.InvalidRequestErrorCode, err
 	}
-	response := QueryResultsResponse{
+	response := GetRingResponse{
 		Data: results,
 	}
 	mdata, err := json.Marshal(response)
@@ -155,6 +342,88 @@ func (h *Handler) QueryResults(ctx context.Context, data json.RawMessage) (json.
 	return mdata, jrpc.OK, nil
 }
 
+type ScanBallotOutputsRequest struct {
+	PubKey  []byte `json:"pubkey"`
+	ViewKey []byte `json:"view_key"` // PEM private view keys, they are not kept by the node
+}
+
+type ScanBallotOutputsResponse struct {
+	Data []blockchain.ScannedBallot `json:"data"`
+}
+
+func (h *Handler) ScanBallotOutputs(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
+	}
+	request := &ScanBallotOutputsRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+	view, err := wallet.ParseViewPEM(request.ViewKey)
+	if err != nil {
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+
+	results, err := h.Blockchain.ScanBallotOutputs(request.PubKey, view)
+	if err != nil {
+		logger.Error(err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+	response := ScanBallotOutputsResponse{
+		Data: results,
+	}
+
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
+	}
+	return mdata, jrpc.OK, nil
+}
+
+type GetRevokedCertificatesRequest struct {
+	PubKey []byte `json:"pubkey"`
+}
+
+type GetRevokedCertificatesResponse struct {
+	Data []string `json:"data"`
+}
+
+func (h *Handler) GetRevokedCertificates(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
+	}
+	request := &GetRevokedCertificatesRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+
+	revoked, err := h.Blockchain.GetRevokedSerials(request.PubKey)
+	if err != nil {
+		logger.Error(err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+	results := []string{}
+	for serial := range revoked {
+		results = append(results, serial)
+	}
+	sort.Strings(results)
+	response := GetRevokedCertificatesResponse{
+		Data: results,
+	}
+
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
+	}
+	return mdata, jrpc.OK, nil
+}
+
 type QueryUnUsedBallotTxsRequest struct {
 	PubKey []byte `json:"pubkey"`
 }
@@ -265,7 +534,12 @@ func (h *Handler) GetTransaction(ctx context.Context, data json.RawMessage) (jso
 		logger.Error("Decode Error:", err)
 		return nil, jrpc.InvalidRequestErrorCode, err
 	}
-	results, err := h.Blockchain.GetTransaction(request.ID)
+	var results blockchain.Transaction
+	if h.Light != nil {
+		results, err = h.Light.GetTransaction(request.ID)
+	} else {
+		results, err = h.Blockchain.GetTransaction(request.ID)
+	}
 	if err != nil {
 		logger.Error("Results Error:", err)
 		return nil, jrpc.InvalidRequestErrorCode, err
@@ -281,6 +555,86 @@ func (h *Handler) GetTransaction(ctx context.Context, data json.RawMessage) (jso
 	return mdata, jrpc.OK, nil
 }
 
+type GetTxProofRequest struct {
+	ID []byte `json:"id"`
+}
+
+type GetTxProofResponse struct {
+	Data blockchain.TxProof `json:"data"`
+}
+
+func (h *Handler) GetTxProof(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
+	}
+	request := &GetTxProofRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+
+	var results blockchain.TxProof
+	if h.Light != nil {
+		results, err = h.Light.GetTxProof(request.ID)
+	} else {
+		results, err = h.Blockchain.GetTxProof(request.ID)
+	}
+	if err != nil {
+		logger.Error("Results Error:", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+	response := GetTxProofResponse{
+		Data: results,
+	}
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
+	}
+	return mdata, jrpc.OK, nil
+}
+
+type GetHeadersRequest struct {
+	Height int `json:"height"`
+}
+
+type GetHeadersResponse struct {
+	Data []blockchain.BlockHeader `json:"data"`
+}
+
+func (h *Handler) GetHeaders(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
+	}
+	request := &GetHeadersRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+
+	var results []blockchain.BlockHeader
+	if h.Light != nil {
+		results, err = h.Light.GetHeaders(request.Height)
+	} else {
+		results, err = h.Blockchain.GetHeaders(request.Height)
+	}
+	if err != nil {
+		logger.Error("Results Error:", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+	response := GetHeadersResponse{
+		Data: results,
+	}
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
+	}
+	return mdata, jrpc.OK, nil
+}
+
 type QueryTransactionsByPubkeyRequest struct {
 	PubKey string `json:"pubkey"`
 }
@@ -368,7 +722,8 @@ type TxResponse struct {
 }
 
 type ResponseData struct {
-	TxID []byte `json:"tx_id"`
+	TxID    []byte              `json:"tx_id"`
+	Receipt *blockchain.Receipt `json:"receipt,omitempty"`
 }
 
 type StartElectionRequest struct {
@@ -399,6 +754,11 @@ func (h *Handler) StartElectionTx(ctx context.Context, data json.RawMessage) (js
 		request.Data.Candidates,
 		request.Data.TotalPeople,
 	)
+	txOut.ElectionTx.BallotFormat = request.Data.BallotFormat
+	txOut.ElectionTx.MaxChoices = request.Data.MaxChoices
+	txOut.ElectionTx.Races = request.Data.Races
+	txOut.ElectionTx.CACertificate = request.Data.CACertificate
+	txOut.ElectionTx.BlindKey = request.Data.BlindKey
 
 	eTx, err = blockchain.NewTransaction(
 		blockchain.ELECTION_TX_TYPE,
@@ -514,6 +874,8 @@ func (h *Handler) StartAccreditationTx(ctx context.Context, data json.RawMessage
 		request.Data.SigWitnesses,
 		request.Data.Timestamp,
 	)
+	txAccreditationOut.AccreditationTx.VoterRoot = request.Data.VoterRoot
+	txAccreditationOut.AccreditationTx.RingCommitment = request.Data.RingCommitment
 
 	eaTx, _ = blockchain.NewTransaction(
 		blockchain.ACCREDITATION_TX_TYPE,
@@ -545,6 +907,9 @@ func (h *Handler) StartAccreditationTx(ctx context.Context, data json.RawMessage
 type StopAccreditationRequest struct {
 	Pubkey []byte               `json:"pubkey"`
 	Data   blockchain.TxAcInput `json:"data"`
+	// Certificates of the voters, checked against the serials and hashes of
+	// the input and not kept on-chain
+	Certificates [][]byte `json:"certificates"`
 }
 
 // Stop accreditation by creating new TxInput
@@ -571,6 +936,19 @@ func (h *Handler) StopAccreditationTx(ctx context.Context, data json.RawMessage)
 		request.Data.AccreditedCount,
 		request.Data.Timestamp,
 	)
+	txAcIn.AccreditationTx.Voters = request.Data.Voters
+	txAcIn.AccreditationTx.RingSize = request.Data.RingSize
+	txAcIn.AccreditationTx.CertificateSerials = request.Data.CertificateSerials
+	txAcIn.AccreditationTx.RingSecret = request.Data.RingSecret
+	txAcIn.AccreditationTx.CertificateHashes = request.Data.CertificateHashes
+
+	if len(txAcIn.AccreditationTx.CertificateSerials) > 0 {
+		err = h.Blockchain.VerifyVoterCertificates(request.Pubkey, txAcIn.AccreditationTx, request.Certificates)
+		if err != nil {
+			logger.Error("Certificate Error:", err)
+			return nil, jrpc.InvalidRequestErrorCode, err
+		}
+	}
 
 	acTx, _ = blockchain.NewTransaction(
 		blockchain.ACCREDITATION_TX_TYPE,
@@ -599,6 +977,62 @@ func (h *Handler) StopAccreditationTx(ctx context.Context, data json.RawMessage)
 	return mdata, jrpc.OK, nil
 }
 
+type RevokeCertificatesRequest struct {
+	Pubkey []byte                        `json:"pubkey"`
+	Data   blockchain.TxRevocationOutput `json:"data"`
+}
+
+// Revoke voter certificates by creating new TxOutput
+func (h *Handler) RevokeCertificatesTx(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	var rTx *blockchain.Transaction
+
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
+	}
+	request := &RevokeCertificatesRequest{}
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
+	}
+
+	txRevocationOut := blockchain.NewRevocationTxOutput(
+		request.Pubkey,
+		request.Data.TxID,
+		request.Data.Serials,
+		request.Data.Signers,
+		request.Data.SigWitnesses,
+		request.Data.Timestamp,
+	)
+
+	rTx, _ = blockchain.NewTransaction(
+		blockchain.REVOCATION_TX_TYPE,
+		request.Pubkey,
+		blockchain.TxInput{},
+		*txRevocationOut,
+	)
+	block, err := h.Blockchain.AddBlock([]*blockchain.Transaction{rTx})
+	if err != nil {
+		logger.Error("Block Error:", err)
+		return nil, jrpc.InternalErrorCode, err
+	}
+
+	fmt.Println("Block added  sucessfully: \n", block)
+
+	response := TxResponse{
+		Data: ResponseData{
+			TxID: rTx.ID,
+		},
+	}
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
+	}
+	return mdata, jrpc.OK, nil
+}
+
 type StartVotingRequest struct {
 	Pubkey []byte                    `json:"pubkey"`
 	Data   blockchain.TxVotingOutput `json:"data"`
@@ -740,6 +1174,9 @@ func (h *Handler) CreateBallotTx(ctx context.Context, data json.RawMessage) (jso
 		request.Data.SigWitnesses,
 		request.Data.Timestamp,
 	)
+	bTxOut.BallotTx.VoterProofs = request.Data.VoterProofs
+	bTxOut.BallotTx.BlindedToken = request.Data.BlindedToken
+	bTxOut.BallotTx.BlindSignature = request.Data.BlindSignature
 
 	bTx, _ = blockchain.NewTransaction(
 		blockchain.BALLOT_TX_TYPE,
@@ -798,6 +1235,10 @@ func (h *Handler) CastBallotTx(ctx context.Context, data json.RawMessage) (json.
 		request.Data.PubKeys,
 		request.Data.Timestamp,
 	)
+	bTxIn.BallotTx.Choices = request.Data.Choices
+	bTxIn.BallotTx.Races = request.Data.Races
+	bTxIn.BallotTx.Token = request.Data.Token
+	bTxIn.BallotTx.TokenSignature = request.Data.TokenSignature
 
 	bTx, _ = blockchain.NewTransaction(
 		blockchain.BALLOT_TX_TYPE,
@@ -813,9 +1254,25 @@ func (h *Handler) CastBallotTx(ctx context.Context, data json.RawMessage) (json.
 
 	fmt.Println("Block added  sucessfully: \n", block)
 
+	// Hand the voter a receipt signed by the node when it has a signer
+	var receipt *blockchain.Receipt
+	if h.Signer != nil {
+		receipt, err = blockchain.NewReceipt(*block, bTx.ID)
+		if err != nil {
+			logger.Error("Receipt Error:", err)
+			return nil, jrpc.InternalErrorCode, err
+		}
+		receipt.NodePubKey = h.Signer.PublicKey()
+		if receipt.Signature, err = h.Signer.SignReceipt(*receipt); err != nil {
+			logger.Error("Receipt Error:", err)
+			return nil, jrpc.InternalErrorCode, err
+		}
+	}
+
 	response := TxResponse{
 		Data: ResponseData{
-			TxID: bTx.ID,
+			TxID:    bTx.ID,
+			Receipt: receipt,
 		},
 	}
 	mdata, err := json.Marshal(response)
@@ -826,3 +1283,42 @@ func (h *Handler) CastBallotTx(ctx context.Context, data json.RawMessage) (json.
 
 	return mdata, jrpc.OK, nil
 }
+
+type VerifyReceiptRequest struct {
+	Receipt blockchain.Receipt `json:"receipt"`
+}
+
+type VerifyReceiptResponse struct {
+	Data VerifyReceiptResult `json:"data"`
+}
+
+type VerifyReceiptResult struct {
+	Valid  bool   `json:"valid"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (h *Handler) VerifyReceipt(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+	if data == nil {
+		return nil, jrpc.InvalidRequestErrorCode, fmt.Errorf("Empty request")
+	}
+	request := &VerifyReceiptRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		logger.Error("UnMarshal Error: ", err)
+		return nil, jrpc.InvalidRequestErrorCode, err
+	}
+
+	result := VerifyReceiptResult{Valid: true}
+	if err = h.Blockchain.VerifyReceipt(request.Receipt); err != nil {
+		result = VerifyReceiptResult{Valid: false, Reason: err.Error()}
+	}
+	response := VerifyReceiptResponse{
+		Data: result,
+	}
+	mdata, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Marshal Error: ", err)
+		return nil, jrpc.InternalErrorCode, err
+	}
+	return mdata, jrpc.OK, nil
+}
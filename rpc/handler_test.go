@@ -0,0 +1,258 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jrpc "github.com/gumeniukcom/golang-jsonrpc2"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
+)
+
+func TestQueryResults(t *testing.T) {
+	candidates := [][]byte{[]byte("A"), []byte("B")}
+	races := []blockchain.Race{
+		{ID: "mayor", Candidates: candidates},
+		{ID: "council", Candidates: candidates, BallotFormat: blockchain.APPROVAL_BALLOT},
+	}
+	tests := []struct {
+		name      string
+		races     []blockchain.Race
+		resultErr bool // QueryResults only answers single race elections
+	}{
+		{"single race", nil, false},
+		{"several races", races, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newTestHandler(t)
+			pubKey := []byte("election")
+			addElection(t, h, pubKey, candidates, func(out *blockchain.TxElectionOutput) {
+				out.Races = test.races
+			})
+			request := QueryResultsRequest{PubKey: pubKey}
+
+			var results QueryResultsResponse
+			_, err := call(t, h.QueryResults, request, &results)
+			if test.resultErr {
+				if err == nil {
+					t.Fatal("expected an error for an election with several races")
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, candidate := range candidates {
+					if votes, ok := results.Data[hex.EncodeToString(candidate)]; !ok || votes != 0 {
+						t.Fatalf("expected no votes for %s, got %v", candidate, results.Data)
+					}
+				}
+			}
+
+			var raceResults QueryRaceResultsResponse
+			if _, err = call(t, h.QueryRaceResults, request, &raceResults); err != nil {
+				t.Fatal(err)
+			}
+			expected := len(test.races)
+			if expected == 0 {
+				expected = 1
+			}
+			if len(raceResults.Data) != expected {
+				t.Fatalf("expected %d races, got %v", expected, raceResults.Data)
+			}
+		})
+	}
+}
+
+func TestGetTxProof(t *testing.T) {
+	h := newTestHandler(t)
+	tx := addElection(t, h, []byte("election"), [][]byte{[]byte("A"), []byte("B")}, nil)
+
+	tests := []struct {
+		name    string
+		id      []byte
+		wantErr bool
+	}{
+		{"election transaction", tx.ID, false},
+		{"unknown transaction", []byte("missing"), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var response GetTxProofResponse
+			_, err := call(t, h.GetTxProof, GetTxProofRequest{test.id}, &response)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			txProof := response.Data
+			if bytes.Compare(txProof.Tx.ID, tx.ID) != 0 {
+				t.Fatalf("expected a proof of %x, got %x", tx.ID, txProof.Tx.ID)
+			}
+			if txProof.Header.VerifyHash() == false {
+				t.Error("header hash does not commit to its merkle root")
+			}
+			if txProof.Header.VerifyTxProof(txProof.Leaf, txProof.Proof) == false {
+				t.Error("merkle proof does not verify")
+			}
+			if txProof.Header.VerifyTxProof(append(txProof.Leaf, 0), txProof.Proof) {
+				t.Error("merkle proof verifies for a tampered transaction")
+			}
+		})
+	}
+}
+
+func TestVerifyReceiptUntrustedNode(t *testing.T) {
+	h := newTestHandler(t)
+	tx := addElection(t, h, []byte("election"), [][]byte{[]byte("A"), []byte("B")}, nil)
+	block, err := h.Blockchain.FindTxBlock(tx.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := blockchain.NewReceipt(block, tx.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, pub := wallet.NewKeyPair()
+	receipt.Sign(*priv, pub)
+
+	var response VerifyReceiptResponse
+	if _, err = call(t, h.VerifyReceipt, VerifyReceiptRequest{*receipt}, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Data.Valid || !strings.Contains(response.Data.Reason, "not trusted") {
+		t.Fatalf("expected a receipt of an untrusted node to be rejected, got %+v", response.Data)
+	}
+}
+
+func TestLightHandlerForwardsMethods(t *testing.T) {
+	full := newTestHandler(t)
+	pubKey := []byte("election")
+	tx := addElection(t, full, pubKey, [][]byte{[]byte("A"), []byte("B")}, nil)
+	scanForwarded := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanForwarded = scanForwarded || bytes.Contains(body, []byte("ScanBallotOutputs"))
+		w.Write(full.Serve.HandleRPCJsonRawMessage(context.Background(), body))
+	}))
+	defer server.Close()
+
+	remote := NewRemoteSource(server.URL)
+	lc, err := blockchain.NewLightChain(newMemStore(), remote, [][]byte{[]byte("validator")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	light := NewLightHandler(lc, remote, jrpc.New()).(*Handler)
+
+	for _, m := range handlerMethods(full) {
+		if code, _ := serveCall(t, light.Serve, m.name, map[string]string{}, nil); code == jrpc.MethodNotFoundErrorCode {
+			t.Errorf("light node does not serve %s", m.name)
+		}
+	}
+
+	var results map[string]int
+	if code, message := serveCall(t, light.Serve, "QueryResults", QueryResultsRequest{pubKey}, &results); code != 0 {
+		t.Fatalf("QueryResults: %s", message)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected the results of the full node, got %v", results)
+	}
+
+	// View keys are not sent on to the full node
+	if code, _ := serveCall(t, light.Serve, "ScanBallotOutputs", ScanBallotOutputsRequest{pubKey, []byte("view key")}, nil); code != jrpc.InvalidRequestErrorCode || scanForwarded {
+		t.Fatalf("expected the scan to be refused by the light node, got code %d, forwarded %v", code, scanForwarded)
+	}
+
+	// Blocks of the test handler are not signed by a validator
+	if code, _ := serveCall(t, light.Serve, "GetTransaction", GetTransactionRequest{tx.ID}, nil); code == 0 {
+		t.Fatal("expected the transaction of an unsigned block to be rejected")
+	}
+}
+
+// serveCall runs the method through the JSON-RPC server and decodes the data
+// of its result, it returns the error code and message of failed calls
+func serveCall(t *testing.T, serve *jrpc.JSONRPC, method string, params, data interface{}) (int, string) {
+	t.Helper()
+	request, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": "1", "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response struct {
+		Result *struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"result"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err = json.Unmarshal(serve.HandleRPCJsonRawMessage(context.Background(), request), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error != nil {
+		return response.Error.Code, response.Error.Message
+	}
+	if data != nil {
+		if err = json.Unmarshal(response.Result.Data, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return 0, ""
+}
+
+func TestScanBallotOutputs(t *testing.T) {
+	h := newTestHandler(t)
+	addElection(t, h, []byte("election"), [][]byte{[]byte("A"), []byte("B")}, nil)
+	viewKey, err := wallet.MakeViewWallet().MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainKey, err := wallet.MakeWalletGroup().MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		viewKey []byte
+		wantErr bool
+	}{
+		{"view key", viewKey, false},
+		{"main key", mainKey, true},
+		{"no view key", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var response ScanBallotOutputsResponse
+			_, err := call(t, h.ScanBallotOutputs, ScanBallotOutputsRequest{[]byte("election"), test.viewKey}, &response)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Data) != 0 {
+				t.Errorf("expected no ballots, got %v", response.Data)
+			}
+		})
+	}
+}
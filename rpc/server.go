@@ -6,14 +6,20 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	jrpc "github.com/gumeniukcom/golang-jsonrpc2"
 	logger "github.com/sirupsen/logrus"
 	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
 	"github.com/thedhejavu/ev-blockchain-protocol/database"
 	"github.com/thedhejavu/ev-blockchain-protocol/pkg/config"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/prompt"
+	"github.com/thedhejavu/ev-blockchain-protocol/wallet"
 )
 
+// Interval between two header syncs of a light node
+const lightSyncInterval = 10 * time.Second
+
 func getStore() database.Store {
 	store, err := database.NewStore("badgerdb", "4000")
 	if err != nil {
@@ -22,15 +28,86 @@ func getStore() database.Store {
 	return store
 }
 
-func StartServer(port string) {
+// WalletSigner loads the wallet the node signs blocks and ballot receipts
+// with. The key then sits next to the RPC server, a remote signer keeps it
+// out of the node.
+func WalletSigner(walletId string) blockchain.NodeSigner {
+	if walletId == "" {
+		logger.Warn("No node wallet set, the node key will be used")
+		return nil
+	}
+	wallets, err := wallet.InitializeWallets()
+	if err != nil {
+		logger.Panic(err)
+	}
+	passphrase, err := prompt.Passphrase(fmt.Sprintf("Passphrase of wallet %s: ", walletId))
+	if err != nil {
+		logger.Panic(err)
+	}
+	w, err := wallets.Unlock(walletId, passphrase)
+	if err != nil {
+		logger.Panic(err)
+	}
+	return blockchain.NewLocalSigner(w.Main.PrivateKey, w.Main.PublicKey)
+}
+
+// StartServer serves a full node, its blocks and ballot receipts are signed by
+// the signer when one is given. Receipts of the nodes with the receipt keys
+// are verified as well as the receipts of the node.
+func StartServer(port string, signer blockchain.NodeSigner, receiptKeys [][]byte) {
 
 	serve := jrpc.New()
 	bc := blockchain.NewBlockchain(
 		getStore(),
-		config.Config{},
+		config.Config{ReceiptKeys: receiptKeys},
 	)
-	NewHandler(bc, serve)
+	// Nodes without a wallet or a remote signer sign with their node key
+	if signer == nil {
+		nodeSigner, err := bc.NodeKeySigner()
+		if err != nil {
+			logger.Panic(err)
+		}
+		logger.Infof("Signing with the node key %x", nodeSigner.PublicKey())
+		signer = nodeSigner
+	}
+	NewHandler(bc, serve, signer)
+	listen(serve, port)
+}
+
+// StartLightServer serves a light node following the full node RPC server at
+// the remote URL. Only block headers signed by the validator are kept.
+func StartLightServer(port string, remote string, validators [][]byte, genesisHash []byte) {
+
+	serve := jrpc.New()
+	store, err := database.NewStore("badgerdb", "light")
+	if err != nil {
+		logger.Panic(err)
+	}
+	source := NewRemoteSource(remote)
+	lc, err := blockchain.NewLightChain(
+		store,
+		source,
+		validators,
+		genesisHash,
+	)
+	if err != nil {
+		logger.Panic(err)
+	}
+	NewLightHandler(lc, source, serve)
+
+	// Keep following the headers of the full node
+	go func() {
+		for {
+			if _, err := lc.Sync(); err != nil {
+				logger.Error("Sync Error: ", err)
+			}
+			time.Sleep(lightSyncInterval)
+		}
+	}()
+	listen(serve, port)
+}
 
+func listen(serve *jrpc.JSONRPC, port string) {
 	http.HandleFunc("/", func(res http.ResponseWriter, req *http.Request) {
 		io.WriteString(res, "RPC SERVER LIVE!")
 	})
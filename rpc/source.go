@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jrpc "github.com/gumeniukcom/golang-jsonrpc2"
+	logger "github.com/sirupsen/logrus"
+	blockchain "github.com/thedhejavu/ev-blockchain-protocol/core"
+)
+
+// RemoteSource fetches block headers and transaction proofs from the RPC
+// server of a full node
+type RemoteSource struct {
+	client *Client
+}
+
+func NewRemoteSource(url string) *RemoteSource {
+	return &RemoteSource{NewClient(url)}
+}
+
+// GetHeaders returns the block headers of the full node from the given height
+func (r *RemoteSource) GetHeaders(height int) ([]blockchain.BlockHeader, error) {
+	var headers []blockchain.BlockHeader
+	err := r.call("GetHeaders", GetHeadersRequest{height}, &headers)
+	return headers, err
+}
+
+// GetTxProof returns a transaction of the full node with its merkle proof
+func (r *RemoteSource) GetTxProof(txId []byte) (blockchain.TxProof, error) {
+	var txProof blockchain.TxProof
+	err := r.call("GetTxProof", GetTxProofRequest{txId}, &txProof)
+	return txProof, err
+}
+
+// GetRing returns the ballot ring of the voter in the election
+func (r *RemoteSource) GetRing(pubKey, voter []byte) ([][]byte, error) {
+	var ring [][]byte
+	err := r.call("GetRing", GetRingRequest{pubKey, voter}, &ring)
+	return ring, err
+}
+
+// QueryUnUsedBallotTxs returns the unused ballot outputs of the election
+func (r *RemoteSource) QueryUnUsedBallotTxs(pubKey []byte) ([]map[string]blockchain.TxBallotOutput, error) {
+	var outputs []map[string]blockchain.TxBallotOutput
+	err := r.call("QueryUnUsedBallotTxs", QueryUnUsedBallotTxsRequest{pubKey}, &outputs)
+	return outputs, err
+}
+
+// ScanBallotOutputs lets the node scan the unused ballot outputs of the
+// election with the private view keys
+func (r *RemoteSource) ScanBallotOutputs(pubKey, viewKey []byte) ([]blockchain.ScannedBallot, error) {
+	var scanned []blockchain.ScannedBallot
+	err := r.call("ScanBallotOutputs", ScanBallotOutputsRequest{pubKey, viewKey}, &scanned)
+	return scanned, err
+}
+
+// FindTxWithTxOutput returns the transaction of the election with an output
+// of the given type
+func (r *RemoteSource) FindTxWithTxOutput(pubKey []byte, txType string) (blockchain.Transaction, error) {
+	var tx blockchain.Transaction
+	err := r.call("FindTxWithTxOutput", FindTxWithTxOutputRequest{pubKey, txType}, &tx)
+	return tx, err
+}
+
+// GetRevokedCertificates returns the hex serials of the revoked voter
+// certificates of the election
+func (r *RemoteSource) GetRevokedCertificates(pubKey []byte) ([]string, error) {
+	var serials []string
+	err := r.call("GetRevokedCertificates", GetRevokedCertificatesRequest{pubKey}, &serials)
+	return serials, err
+}
+
+// RevokeCertificates submits the revocation signed by the commission and
+// returns the ID of its transaction
+func (r *RemoteSource) RevokeCertificates(pubKey []byte, revocation blockchain.TxRevocationOutput) ([]byte, error) {
+	var data ResponseData
+	err := r.call("RevokeCertificates", RevokeCertificatesRequest{pubKey, revocation}, &data)
+	return data.TxID, err
+}
+
+// VerifyReceipt checks the ballot receipt against the chain of the node
+func (r *RemoteSource) VerifyReceipt(receipt blockchain.Receipt) (VerifyReceiptResult, error) {
+	var result VerifyReceiptResult
+	err := r.call("VerifyReceipt", VerifyReceiptRequest{receipt}, &result)
+	return result, err
+}
+
+// forward returns an RPC method running the method of the same name on the
+// full node, its response is served as is
+func (r *RemoteSource) forward(method string) jrpc.RPCMethod {
+	return func(ctx context.Context, data json.RawMessage) (json.RawMessage, int, error) {
+		response, err := r.client.Do(method, data)
+		if err != nil {
+			logger.Error("Forward Error: ", err)
+			return nil, jrpc.InternalErrorCode, err
+		}
+		if response.Body.HasError() {
+			return nil, int(response.Body.Error.Code), fmt.Errorf("%s %s", response.Body.Error.Message, response.Body.Error.Data)
+		}
+		mdata, err := json.Marshal(response.Body.Result)
+		if err != nil {
+			logger.Error("Marshal Error: ", err)
+			return nil, jrpc.InternalErrorCode, err
+		}
+		return mdata, jrpc.OK, nil
+	}
+}
+
+// call runs the method on the full node and decodes the response data
+func (r *RemoteSource) call(method string, params interface{}, result interface{}) error {
+	response, err := r.client.Do(method, params)
+	if err != nil {
+		return err
+	}
+	if response.Body.HasError() {
+		return fmt.Errorf("%s: %s %s", method, response.Body.Error.Message, response.Body.Error.Data)
+	}
+
+	data, err := json.Marshal(response.Body.Result.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
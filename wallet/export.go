@@ -0,0 +1,275 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+const (
+	privateKeyBlock  = "PRIVATE KEY"
+	certificateBlock = "CERTIFICATE"
+	seedBlock        = "HD SEED"
+
+	// keyUseHeader tells the ECDH view key apart from the main key
+	keyUseHeader = "Use"
+	keyUseView   = "view"
+)
+
+var (
+	ErrInvalidPEM          = errors.New("Invalid PEM wallet")
+	ErrCertificateMismatch = errors.New("Certificate is not issued for the main key")
+	ErrWalletExists        = errors.New("Wallet already exists")
+)
+
+// MarshalPEM exports the wallet as PEM blocks: the main ECDSA key, the view
+// RSA key and the view ECDH key as unencrypted PKCS#8 private keys followed
+// by the certificate. Wallets created from a mnemonic also export their seed so
+// election wallets can still be derived from the imported wallet.
+func (w *WalletGroup) MarshalPEM() ([]byte, error) {
+	mainKey, err := x509.MarshalPKCS8PrivateKey(&w.Main.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	viewKeys, err := w.View.MarshalPEM()
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	pem.Encode(&out, &pem.Block{Type: privateKeyBlock, Bytes: mainKey})
+	out.Write(viewKeys)
+	out.Write(w.Certificate)
+	if w.Seed != nil {
+		pem.Encode(&out, &pem.Block{Type: seedBlock, Bytes: w.Seed})
+	}
+
+	return out.Bytes(), nil
+}
+
+// MarshalPEM exports the private view keys only. They let a node scan the
+// ballots of the voter without being able to sign for the voter.
+func (w *WalletView) MarshalPEM() ([]byte, error) {
+	viewKey, err := x509.MarshalPKCS8PrivateKey(&w.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	pem.Encode(&out, &pem.Block{Type: privateKeyBlock, Bytes: viewKey})
+	if w.HybridKey != nil {
+		hybridKey, err := x509.MarshalPKCS8PrivateKey(w.HybridKey)
+		if err != nil {
+			return nil, err
+		}
+		pem.Encode(&out, &pem.Block{
+			Type:    privateKeyBlock,
+			Headers: map[string]string{keyUseHeader: keyUseView},
+			Bytes:   hybridKey,
+		})
+	}
+
+	return out.Bytes(), nil
+}
+
+// ParseViewPEM imports private view keys exported by WalletView.MarshalPEM
+func ParseViewPEM(data []byte) (*WalletView, error) {
+	var hybridKey *ecdsa.PrivateKey
+	var viewKey *rsa.PrivateKey
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != privateKeyBlock {
+			return nil, fmt.Errorf("%w: unexpected %s block", ErrInvalidPEM, block.Type)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPEM, err)
+		}
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			if viewKey != nil {
+				return nil, fmt.Errorf("%w: several view keys", ErrInvalidPEM)
+			}
+			viewKey = key
+		case *ecdsa.PrivateKey:
+			if block.Headers[keyUseHeader] != keyUseView {
+				return nil, fmt.Errorf("%w: only view keys are expected", ErrInvalidPEM)
+			}
+			if hybridKey != nil {
+				return nil, fmt.Errorf("%w: several view ECDH keys", ErrInvalidPEM)
+			}
+			hybridKey = key
+		default:
+			return nil, fmt.Errorf("%w: unsupported %T key", ErrInvalidPEM, key)
+		}
+	}
+
+	if viewKey == nil {
+		return nil, fmt.Errorf("%w: view key is required", ErrInvalidPEM)
+	}
+	publicKey, err := rsaPublicKeyPEM(&viewKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if hybridKey != nil {
+		if hybridKey.Curve.Params().Name != elliptic.P256().Params().Name {
+			return nil, fmt.Errorf("%w: view ECDH key is not a P-256 key", ErrInvalidPEM)
+		}
+		if publicKey, err = viewPublicKeyPEM(&viewKey.PublicKey, &hybridKey.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return &WalletView{*viewKey, publicKey, hybridKey}, nil
+}
+
+// ParsePEM imports a wallet exported as PEM blocks. The main and view keys
+// are told apart by their type and the ECDH view key by its header. A
+// certificate is issued for the main key and an ECDH view key is generated
+// when there is none. A seed must derive the main key.
+func ParsePEM(data []byte) (*WalletGroup, error) {
+	var mainKey, hybridKey *ecdsa.PrivateKey
+	var viewKey *rsa.PrivateKey
+	var certificate, seed []byte
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case privateKeyBlock:
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPEM, err)
+			}
+			switch key := key.(type) {
+			case *ecdsa.PrivateKey:
+				if block.Headers[keyUseHeader] == keyUseView {
+					if hybridKey != nil {
+						return nil, fmt.Errorf("%w: several view ECDH keys", ErrInvalidPEM)
+					}
+					hybridKey = key
+					continue
+				}
+				if mainKey != nil {
+					return nil, fmt.Errorf("%w: several main keys", ErrInvalidPEM)
+				}
+				mainKey = key
+			case *rsa.PrivateKey:
+				if viewKey != nil {
+					return nil, fmt.Errorf("%w: several view keys", ErrInvalidPEM)
+				}
+				viewKey = key
+			default:
+				return nil, fmt.Errorf("%w: unsupported %T key", ErrInvalidPEM, key)
+			}
+		case certificateBlock:
+			if certificate != nil {
+				return nil, fmt.Errorf("%w: several certificates", ErrInvalidPEM)
+			}
+			certificate = pem.EncodeToMemory(block)
+		case seedBlock:
+			if seed != nil {
+				return nil, fmt.Errorf("%w: several seeds", ErrInvalidPEM)
+			}
+			seed = block.Bytes
+		default:
+			return nil, fmt.Errorf("%w: unexpected %s block", ErrInvalidPEM, block.Type)
+		}
+	}
+
+	if mainKey == nil || viewKey == nil {
+		return nil, fmt.Errorf("%w: main and view keys are required", ErrInvalidPEM)
+	}
+	if mainKey.Curve.Params().Name != elliptic.P256().Params().Name {
+		return nil, fmt.Errorf("%w: main key is not a P-256 key", ErrInvalidPEM)
+	}
+	if hybridKey != nil && hybridKey.Curve.Params().Name != elliptic.P256().Params().Name {
+		return nil, fmt.Errorf("%w: view ECDH key is not a P-256 key", ErrInvalidPEM)
+	}
+	if seed != nil {
+		seedKey, err := identityMainKey(seed)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPEM, err)
+		}
+		if seedKey.D.Cmp(mainKey.D) != 0 {
+			return nil, fmt.Errorf("%w: seed does not derive the main key", ErrInvalidPEM)
+		}
+	}
+	viewPublicKey, err := rsaPublicKeyPEM(&viewKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WalletGroup{
+		Main:        &WalletMain{*mainKey, keys.FromECDSA(&mainKey.PublicKey).Bytes()},
+		View:        &WalletView{*viewKey, viewPublicKey, hybridKey},
+		Certificate: certificate,
+		Seed:        seed,
+	}
+	if certificate == nil {
+		w.Certificate = GenerateCert(&mainKey.PublicKey, mainKey)
+	}
+	if hybridKey != nil {
+		if w.View.PublicKey, err = viewPublicKeyPEM(&viewKey.PublicKey, &hybridKey.PublicKey); err != nil {
+			return nil, err
+		}
+	} else if _, err = w.UpgradeView(); err != nil {
+		return nil, err
+	}
+	if err = w.VerifyCertificate(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// VerifyCertificate checks the certificate of the wallet is issued for its
+// main key
+func (w *WalletGroup) VerifyCertificate() error {
+	block, _ := pem.Decode(w.Certificate)
+	if block == nil || block.Type != certificateBlock {
+		return fmt.Errorf("%w: no certificate", ErrCertificateMismatch)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrCertificateMismatch, err)
+	}
+	pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pub.X.Cmp(w.Main.PrivateKey.X) != 0 || pub.Y.Cmp(w.Main.PrivateKey.Y) != 0 {
+		return ErrCertificateMismatch
+	}
+	return nil
+}
+
+// ParseKeystore decodes a JSON keystore, keystores asking scrypt for more
+// work than MaxKDFParams are rejected
+func ParseKeystore(data []byte) (*Keystore, error) {
+	var ks Keystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeystore, err)
+	}
+	if ks.Version != keystoreVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidKeystore, ks.Version)
+	}
+	if err := ks.Crypto.KDFParams.Validate(); err != nil {
+		return nil, err
+	}
+	return &ks, nil
+}
@@ -0,0 +1,225 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+// pemBlocks splits the PEM export of a wallet into its blocks
+func pemBlocks(t *testing.T, data []byte) []*pem.Block {
+	t.Helper()
+	var blocks []*pem.Block
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return blocks
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+func encodeBlocks(blocks ...*pem.Block) []byte {
+	var out bytes.Buffer
+	for _, block := range blocks {
+		pem.Encode(&out, block)
+	}
+	return out.Bytes()
+}
+
+func TestPEMRoundTrip(t *testing.T) {
+	w := MakeWalletGroup()
+	data, err := w.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParsePEM(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(parsed.Main.PublicKey, w.Main.PublicKey) != 0 {
+		t.Error("main key does not match")
+	}
+	if parsed.View.PrivateKey.Equal(&w.View.PrivateKey) == false {
+		t.Error("view key does not match")
+	}
+	if parsed.View.HybridKey == nil || parsed.View.HybridKey.Equal(w.View.HybridKey) == false {
+		t.Error("view ECDH key does not match")
+	}
+	if bytes.Compare(parsed.View.PublicKey, w.View.PublicKey) != 0 {
+		t.Error("view public key does not match")
+	}
+	if bytes.Compare(parsed.Certificate, w.Certificate) != 0 {
+		t.Error("certificate does not match")
+	}
+}
+
+func TestHDWalletPEMRoundTrip(t *testing.T) {
+	w, err := MakeHDWalletGroup(bytes.Repeat([]byte{0x42}, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := w.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParsePEM(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(parsed.Seed, w.Seed) != 0 {
+		t.Fatal("seed does not match")
+	}
+	election, err := parsed.ElectionWallet([]byte("election"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := w.ElectionWallet([]byte("election"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(election.Main.PublicKey, expected.Main.PublicKey) != 0 {
+		t.Error("election wallet does not match")
+	}
+
+	// Without the ECDH view key it is derived again from the seed
+	var blocks []*pem.Block
+	for _, block := range pemBlocks(t, data) {
+		if block.Headers[keyUseHeader] != keyUseView {
+			blocks = append(blocks, block)
+		}
+	}
+	if parsed, err = ParsePEM(encodeBlocks(blocks...)); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.View.HybridKey == nil || parsed.View.HybridKey.Equal(w.View.HybridKey) == false {
+		t.Error("expected the view ECDH key to be derived from the seed")
+	}
+
+	other := pemBlocks(t, data)
+	other[len(other)-1].Bytes = bytes.Repeat([]byte{0x43}, 64)
+	if _, err = ParsePEM(encodeBlocks(other...)); errors.Is(err, ErrInvalidPEM) == false {
+		t.Fatalf("expected a seed of another wallet to be rejected, got %v", err)
+	}
+}
+
+func TestParsePEMWithoutHybridKey(t *testing.T) {
+	w := MakeWalletGroup()
+	data, err := w.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := pemBlocks(t, data)
+	var legacy []*pem.Block
+	for _, block := range blocks {
+		if block.Headers[keyUseHeader] != keyUseView {
+			legacy = append(legacy, block)
+		}
+	}
+
+	parsed, err := ParsePEM(encodeBlocks(legacy...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.View.HybridKey == nil {
+		t.Error("expected a view ECDH key to be generated")
+	}
+	payload, err := EncryptView(parsed.View.PublicKey, []byte("ballot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := parsed.View.Decrypt(payload); err != nil || string(plaintext) != "ballot" {
+		t.Errorf("expected the upgraded view key to decrypt, got %q, %v", plaintext, err)
+	}
+}
+
+func TestParsePEMInvalid(t *testing.T) {
+	w := MakeWalletGroup()
+	data, err := w.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := pemBlocks(t, data)
+	main, view, hybrid, certificate := blocks[0], blocks[1], blocks[2], blocks[3]
+
+	other, err := MakeWalletGroup().MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCertificate := pemBlocks(t, other)[3]
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, ErrInvalidPEM},
+		{"main key only", encodeBlocks(main), ErrInvalidPEM},
+		{"view keys only", encodeBlocks(view, hybrid), ErrInvalidPEM},
+		{"two main keys", encodeBlocks(main, main, view), ErrInvalidPEM},
+		{"two view keys", encodeBlocks(main, view, view), ErrInvalidPEM},
+		{"two view ECDH keys", encodeBlocks(main, view, hybrid, hybrid), ErrInvalidPEM},
+		{"two certificates", encodeBlocks(main, view, certificate, certificate), ErrInvalidPEM},
+		{"unexpected block", encodeBlocks(main, view, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: view.Bytes}), ErrInvalidPEM},
+		{"corrupted key", encodeBlocks(main, &pem.Block{Type: privateKeyBlock, Bytes: []byte("key")}), ErrInvalidPEM},
+		{"certificate of another wallet", encodeBlocks(main, view, hybrid, otherCertificate), ErrCertificateMismatch},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParsePEM(test.data); errors.Is(err, test.err) == false {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestParseViewPEM(t *testing.T) {
+	w := MakeWalletGroup()
+	data, err := w.View.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err := ParseViewPEM(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(view.PublicKey, w.View.PublicKey) != 0 {
+		t.Error("view public key does not match")
+	}
+
+	full, err := w.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseViewPEM(full); errors.Is(err, ErrInvalidPEM) == false {
+		t.Fatalf("expected the main key to be rejected, got %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	wallets, err := NewWallets(newTestStore(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := MakeWalletGroup()
+	if err = wallets.Import("alice", w); err != nil {
+		t.Fatal(err)
+	}
+	if err = wallets.Import("alice", MakeWalletGroup()); errors.Is(err, ErrWalletExists) == false {
+		t.Fatalf("expected %v, got %v", ErrWalletExists, err)
+	}
+	if err = wallets.Import("../alice", MakeWalletGroup()); errors.Is(err, ErrInvalidWalletID) == false {
+		t.Fatalf("expected %v, got %v", ErrInvalidWalletID, err)
+	}
+
+	imported, err := wallets.GetWallet("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(imported.Main.PublicKey, w.Main.PublicKey) != 0 {
+		t.Error("imported wallet does not match")
+	}
+}
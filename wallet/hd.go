@@ -0,0 +1,153 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/hdkey"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	bip39 "github.com/tyler-smith/go-bip39"
+)
+
+// Hardened derivation paths of the wallet keys. The identity keys are at
+// m/1776'/0'/0' and m/1776'/1'/0', the keys of an election at
+// m/1776'/2'/e0'/e1' and m/1776'/3'/e0'/e1' with e0 and e1 taken from the hash
+// of the election public key. The ECDH view key is the first hardened child of
+// the RSA view key.
+const (
+	HDPurpose = 1776
+
+	hdIdentityMain = 0
+	hdIdentityView = 1
+	hdElectionMain = 2
+	hdElectionView = 3
+
+	mnemonicEntropyBits = 256
+	viewKeyBits         = 2048
+)
+
+var (
+	ErrInvalidMnemonic = errors.New("Invalid mnemonic")
+	ErrNotHDWallet     = errors.New("Wallet was not created from a mnemonic")
+)
+
+// NewMnemonic generates a 24 words BIP-39 mnemonic
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(mnemonicEntropyBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// SeedFromMnemonic checks the mnemonic and derives its seed, the password
+// being the optional BIP-39 passphrase
+func SeedFromMnemonic(mnemonic, password string) ([]byte, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, password)
+	if err != nil {
+		return nil, ErrInvalidMnemonic
+	}
+	return seed, nil
+}
+
+// MakeHDWalletGroup derives the identity wallet of the seed. The same seed
+// always gives the same keys, so the wallet can be recovered from its
+// mnemonic.
+func MakeHDWalletGroup(seed []byte) (*WalletGroup, error) {
+	w, err := deriveWalletGroup(seed, []uint32{hdIdentityMain, 0}, []uint32{hdIdentityView, 0})
+	if err != nil {
+		return nil, err
+	}
+	w.Seed = seed
+	return w, nil
+}
+
+// ElectionWallet derives the wallet used for an election. Each election gets
+// its own keys which cannot be linked to the identity or other elections
+// without the seed.
+func (w *WalletGroup) ElectionWallet(electionPubKey []byte) (*WalletGroup, error) {
+	if w.Seed == nil {
+		return nil, ErrNotHDWallet
+	}
+	h := sha256.Sum256(electionPubKey)
+	e0 := binary.BigEndian.Uint32(h[0:4]) &^ hdkey.HardenedOffset
+	e1 := binary.BigEndian.Uint32(h[4:8]) &^ hdkey.HardenedOffset
+
+	return deriveWalletGroup(w.Seed, []uint32{hdElectionMain, e0, e1}, []uint32{hdElectionView, e0, e1})
+}
+
+// deriveWalletGroup derives the main and view keys at the paths under the
+// purpose of the wallet
+func deriveWalletGroup(seed []byte, mainPath, viewPath []uint32) (*WalletGroup, error) {
+	master, err := hdkey.NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	mainKey, err := master.DeriveIndexes(hardened(append([]uint32{HDPurpose}, mainPath...)))
+	if err != nil {
+		return nil, err
+	}
+	viewKey, err := master.DeriveIndexes(hardened(append([]uint32{HDPurpose}, viewPath...)))
+	if err != nil {
+		return nil, err
+	}
+
+	mainPrivate := mainKey.ECDSA()
+	viewPrivate, err := viewKey.RSA(viewKeyBits)
+	if err != nil {
+		return nil, err
+	}
+	hybridKey, err := viewKey.Child(hdkey.HardenedOffset)
+	if err != nil {
+		return nil, err
+	}
+	hybridPrivate := hybridKey.ECDSA()
+	viewPublic, err := viewPublicKeyPEM(&viewPrivate.PublicKey, &hybridPrivate.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WalletGroup{
+		Main:        &WalletMain{*mainPrivate, keys.FromECDSA(&mainPrivate.PublicKey).Bytes()},
+		View:        &WalletView{*viewPrivate, viewPublic, hybridPrivate},
+		Certificate: GenerateCert(&mainPrivate.PublicKey, mainPrivate),
+	}, nil
+}
+
+// identityMainKey derives the main key of the identity wallet of the seed,
+// without the view keys
+func identityMainKey(seed []byte) (*ecdsa.PrivateKey, error) {
+	master, err := hdkey.NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+	mainKey, err := master.DeriveIndexes(hardened([]uint32{HDPurpose, hdIdentityMain, 0}))
+	if err != nil {
+		return nil, err
+	}
+	return mainKey.ECDSA(), nil
+}
+
+// identityHybridKey derives the ECDH view key of the identity wallet of the
+// seed, without the RSA view key
+func identityHybridKey(seed []byte) (*ecdsa.PrivateKey, error) {
+	master, err := hdkey.NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+	hybridKey, err := master.DeriveIndexes(hardened([]uint32{HDPurpose, hdIdentityView, 0, 0}))
+	if err != nil {
+		return nil, err
+	}
+	return hybridKey.ECDSA(), nil
+}
+
+func hardened(indexes []uint32) []uint32 {
+	for i := range indexes {
+		indexes[i] += hdkey.HardenedOffset
+	}
+	return indexes
+}
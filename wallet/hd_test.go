@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon " +
+	"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"
+
+// hdWallet recovers the identity wallet of the test mnemonic
+func hdWallet(t *testing.T, password string) *WalletGroup {
+	t.Helper()
+	seed, err := SeedFromMnemonic(testMnemonic, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := MakeHDWalletGroup(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestSeedFromMnemonic(t *testing.T) {
+	if _, err := SeedFromMnemonic("abandon abandon abandon", ""); errors.Is(err, ErrInvalidMnemonic) == false {
+		t.Fatalf("expected %v, got %v", ErrInvalidMnemonic, err)
+	}
+
+	mnemonic, err := NewMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = SeedFromMnemonic(mnemonic, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMakeHDWalletGroup(t *testing.T) {
+	w := hdWallet(t, "")
+	recovered := hdWallet(t, "")
+
+	if bytes.Compare(recovered.Main.PublicKey, w.Main.PublicKey) != 0 {
+		t.Error("main key does not match")
+	}
+	if recovered.View.PrivateKey.Equal(&w.View.PrivateKey) == false {
+		t.Error("view key does not match")
+	}
+	if recovered.View.HybridKey.Equal(w.View.HybridKey) == false {
+		t.Error("view ECDH key does not match")
+	}
+	if bytes.Compare(recovered.View.PublicKey, w.View.PublicKey) != 0 {
+		t.Error("view public key does not match")
+	}
+
+	other := hdWallet(t, "passphrase")
+	if bytes.Compare(other.Main.PublicKey, w.Main.PublicKey) == 0 {
+		t.Error("expected the passphrase to derive other keys")
+	}
+}
+
+func TestElectionWallet(t *testing.T) {
+	w := hdWallet(t, "")
+	election := []byte("5_election_12345678")
+
+	first, err := w.ElectionWallet(election)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := hdWallet(t, "").ElectionWallet(election)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(again.Main.PublicKey, first.Main.PublicKey) != 0 {
+		t.Error("election main key does not match")
+	}
+	if bytes.Compare(again.View.PublicKey, first.View.PublicKey) != 0 {
+		t.Error("election view key does not match")
+	}
+
+	other, err := w.ElectionWallet([]byte("6_election_12345678"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		wallet *WalletGroup
+	}{
+		{"identity", w},
+		{"another election", other},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if bytes.Compare(test.wallet.Main.PublicKey, first.Main.PublicKey) == 0 {
+				t.Error("main keys are linked")
+			}
+			if test.wallet.View.PrivateKey.Equal(&first.View.PrivateKey) {
+				t.Error("view keys are linked")
+			}
+			if test.wallet.View.HybridKey.Equal(first.View.HybridKey) {
+				t.Error("view ECDH keys are linked")
+			}
+		})
+	}
+
+	if _, err = MakeWalletGroup().ElectionWallet(election); errors.Is(err, ErrNotHDWallet) == false {
+		t.Fatalf("expected %v, got %v", ErrNotHDWallet, err)
+	}
+}
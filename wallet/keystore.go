@@ -0,0 +1,286 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keystoreVersion = 1
+	keystoreKDF     = "scrypt"
+	keystoreCipher  = "aes-256-gcm"
+	keystoreExt     = ".json"
+	keySize         = 32
+	saltSize        = 32
+)
+
+var (
+	ErrWrongPassphrase = errors.New("Wrong passphrase or corrupted keystore")
+	ErrInvalidKeystore = errors.New("Invalid keystore")
+	ErrInvalidWalletID = errors.New("Invalid wallet ID")
+	ErrWalletLocked    = errors.New("Wallet is locked")
+
+	walletIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+)
+
+// KDFParams are the scrypt parameters deriving the keystore key from the
+// passphrase
+type KDFParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+var (
+	// StandardKDFParams takes around a hundred milliseconds and 32MB of memory
+	StandardKDFParams = KDFParams{N: 1 << 15, R: 8, P: 1}
+
+	// LightKDFParams is only meant for throwaway wallets such as tests
+	LightKDFParams = KDFParams{N: 1 << 12, R: 8, P: 1}
+
+	// MaxKDFParams bounds the work of imported keystores, around a second
+	// and 256MB of memory for each parallel pass
+	MaxKDFParams = KDFParams{N: 1 << 18, R: 8, P: 4}
+)
+
+// Validate checks the parameters are usable by scrypt and within the bounds
+// of MaxKDFParams
+func (params KDFParams) Validate() error {
+	if params.N < 2 || params.N&(params.N-1) != 0 {
+		return fmt.Errorf("%w: scrypt N must be a power of two above 1", ErrInvalidKeystore)
+	}
+	if params.N > MaxKDFParams.N || params.R < 1 || params.R > MaxKDFParams.R || params.P < 1 || params.P > MaxKDFParams.P {
+		return fmt.Errorf("%w: scrypt parameters N=%d r=%d p=%d above N=%d r=%d p=%d", ErrInvalidKeystore,
+			params.N, params.R, params.P, MaxKDFParams.N, MaxKDFParams.R, MaxKDFParams.P)
+	}
+	if len(params.Salt) != saltSize {
+		return fmt.Errorf("%w: salt size", ErrInvalidKeystore)
+	}
+	return nil
+}
+
+// Keystore is a wallet encrypted with a key derived from a passphrase. The
+// public keys are kept in clear so a locked wallet can still be listed, they
+// are authenticated with the ciphertext.
+type Keystore struct {
+	Version       int            `json:"version"`
+	ID            string         `json:"id"`
+	MainPublicKey []byte         `json:"main_public_key"`
+	ViewPublicKey []byte         `json:"view_public_key"`
+	Crypto        KeystoreCrypto `json:"crypto"`
+}
+
+// KeystoreCrypto holds the encrypted private keys
+type KeystoreCrypto struct {
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdf_params"`
+	Cipher     string    `json:"cipher"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// walletSecrets is the plaintext of a keystore
+type walletSecrets struct {
+	Main        []byte `json:"main"`
+	View        []byte `json:"view"`
+	Certificate []byte `json:"certificate"`
+	Seed        []byte `json:"seed,omitempty"`
+	ViewHybrid  []byte `json:"view_hybrid,omitempty"`
+}
+
+// keySecrets is the plaintext of a keystore holding a single private key
+type keySecrets struct {
+	Key []byte `json:"key"`
+}
+
+// ValidateWalletID checks the wallet ID can be used as a keystore file name
+func ValidateWalletID(id string) error {
+	if !walletIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidWalletID, id)
+	}
+	return nil
+}
+
+// EncryptWallet encrypts the private keys of the wallet with the passphrase
+func EncryptWallet(id string, w *WalletGroup, passphrase []byte, params KDFParams) (*Keystore, error) {
+	if err := ValidateWalletID(id); err != nil {
+		return nil, err
+	}
+	mainKey, err := x509.MarshalECPrivateKey(&w.Main.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	secrets := walletSecrets{
+		Main:        mainKey,
+		View:        x509.MarshalPKCS1PrivateKey(&w.View.PrivateKey),
+		Certificate: w.Certificate,
+		Seed:        w.Seed,
+	}
+	if w.View.HybridKey != nil {
+		if secrets.ViewHybrid, err = x509.MarshalECPrivateKey(w.View.HybridKey); err != nil {
+			return nil, err
+		}
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	return seal(&Keystore{
+		ID:            id,
+		MainPublicKey: w.Main.PublicKey,
+		ViewPublicKey: w.View.PublicKey,
+	}, plaintext, passphrase, params)
+}
+
+// EncryptKey encrypts a private key kept apart from the wallets, such as the
+// blind signature key of the commission. The public key is kept in clear as
+// the main public key of the keystore.
+func EncryptKey(id string, privateKey, publicKey, passphrase []byte, params KDFParams) (*Keystore, error) {
+	if err := ValidateWalletID(id); err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(keySecrets{Key: privateKey})
+	if err != nil {
+		return nil, err
+	}
+	return seal(&Keystore{ID: id, MainPublicKey: publicKey}, plaintext, passphrase, params)
+}
+
+// seal encrypts the plaintext into the keystore with a key derived from the
+// passphrase
+func seal(ks *Keystore, plaintext, passphrase []byte, params KDFParams) (*Keystore, error) {
+	params.Salt = make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, params.Salt); err != nil {
+		return nil, err
+	}
+	ks.Version = keystoreVersion
+	ks.Crypto = KeystoreCrypto{
+		KDF:       keystoreKDF,
+		KDFParams: params,
+		Cipher:    keystoreCipher,
+	}
+
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	ks.Crypto.Nonce = make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, ks.Crypto.Nonce); err != nil {
+		return nil, err
+	}
+	ks.Crypto.Ciphertext = aead.Seal(nil, ks.Crypto.Nonce, plaintext, ks.additionalData())
+
+	return ks, nil
+}
+
+// Decrypt unlocks the wallet with the passphrase
+func (ks *Keystore) Decrypt(passphrase []byte) (*WalletGroup, error) {
+	plaintext, err := ks.open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets walletSecrets
+	if err = json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeystore, err)
+	}
+	mainKey, err := x509.ParseECPrivateKey(secrets.Main)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeystore, err)
+	}
+	viewKey, err := x509.ParsePKCS1PrivateKey(secrets.View)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeystore, err)
+	}
+	if !keys.Equal(keys.FromECDSA(&mainKey.PublicKey).Bytes(), ks.MainPublicKey) {
+		return nil, fmt.Errorf("%w: main key does not match its public key", ErrInvalidKeystore)
+	}
+	// Keystores of earlier versions have no hybrid view key
+	var hybridKey *ecdsa.PrivateKey
+	if secrets.ViewHybrid != nil {
+		if hybridKey, err = x509.ParseECPrivateKey(secrets.ViewHybrid); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidKeystore, err)
+		}
+	}
+
+	return &WalletGroup{
+		Main:        &WalletMain{*mainKey, ks.MainPublicKey},
+		View:        &WalletView{*viewKey, ks.ViewPublicKey, hybridKey},
+		Certificate: secrets.Certificate,
+		Seed:        secrets.Seed,
+	}, nil
+}
+
+// DecryptKey returns the private key of a keystore created by EncryptKey
+func (ks *Keystore) DecryptKey(passphrase []byte) ([]byte, error) {
+	plaintext, err := ks.open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var secrets keySecrets
+	if err = json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeystore, err)
+	}
+	if len(secrets.Key) == 0 {
+		return nil, fmt.Errorf("%w: no private key", ErrInvalidKeystore)
+	}
+	return secrets.Key, nil
+}
+
+// open checks the format of the keystore and decrypts its plaintext
+func (ks *Keystore) open(passphrase []byte) ([]byte, error) {
+	if ks.Version != keystoreVersion || ks.Crypto.KDF != keystoreKDF || ks.Crypto.Cipher != keystoreCipher {
+		return nil, fmt.Errorf("%w: unsupported version %d, %s, %s", ErrInvalidKeystore, ks.Version, ks.Crypto.KDF, ks.Crypto.Cipher)
+	}
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(ks.Crypto.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: nonce size", ErrInvalidKeystore)
+	}
+	plaintext, err := aead.Open(nil, ks.Crypto.Nonce, ks.Crypto.Ciphertext, ks.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// aead derives the keystore key from the passphrase
+func (ks *Keystore) aead(passphrase []byte) (cipher.AEAD, error) {
+	params := ks.Crypto.KDFParams
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(passphrase, params.Salt, params.N, params.R, params.P, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeystore, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the clear part of the keystore to the ciphertext
+func (ks *Keystore) additionalData() []byte {
+	header := *ks
+	header.Crypto.Nonce = nil
+	header.Crypto.Ciphertext = nil
+	data, _ := json.Marshal(header)
+	return data
+}
@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseKeystoreKDFParams(t *testing.T) {
+	passphrase := []byte("passphrase")
+	ks, err := EncryptWallet("alice", MakeWalletGroup(), passphrase, LightKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(params *KDFParams)
+		valid bool
+	}{
+		{"light", func(params *KDFParams) {}, true},
+		{"maximum", func(params *KDFParams) { params.N, params.R, params.P = MaxKDFParams.N, MaxKDFParams.R, MaxKDFParams.P }, true},
+		{"N too large", func(params *KDFParams) { params.N = MaxKDFParams.N << 1 }, false},
+		{"N not a power of two", func(params *KDFParams) { params.N = 3000 }, false},
+		{"N of one", func(params *KDFParams) { params.N = 1 }, false},
+		{"r too large", func(params *KDFParams) { params.R = MaxKDFParams.R + 1 }, false},
+		{"p too large", func(params *KDFParams) { params.P = MaxKDFParams.P + 1 }, false},
+		{"p of zero", func(params *KDFParams) { params.P = 0 }, false},
+		{"short salt", func(params *KDFParams) { params.Salt = params.Salt[:8] }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			copied := *ks
+			copied.Crypto.KDFParams.Salt = append([]byte{}, ks.Crypto.KDFParams.Salt...)
+			test.setup(&copied.Crypto.KDFParams)
+			data, err := json.Marshal(copied)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = ParseKeystore(data)
+			if test.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !test.valid && errors.Is(err, ErrInvalidKeystore) == false {
+				t.Fatalf("expected %v, got %v", ErrInvalidKeystore, err)
+			}
+		})
+	}
+}
+
+func TestDecryptRejectsUnboundedKDFParams(t *testing.T) {
+	passphrase := []byte("passphrase")
+	ks, err := EncryptWallet("alice", MakeWalletGroup(), passphrase, LightKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Crypto.KDFParams.N = 1 << 30
+
+	if _, err = ks.Decrypt(passphrase); errors.Is(err, ErrInvalidKeystore) == false {
+		t.Fatalf("expected %v, got %v", ErrInvalidKeystore, err)
+	}
+}
+
+func TestEncryptKey(t *testing.T) {
+	passphrase := []byte("passphrase")
+	key, public := []byte("private key"), []byte("public key")
+	ks, err := EncryptKey("blind", key, public, passphrase, LightKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, key) {
+		t.Fatal("expected the private key to be encrypted")
+	}
+	if ks, err = ParseKeystore(data); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(ks.MainPublicKey, public) != 0 {
+		t.Error("expected the public key in clear")
+	}
+
+	decrypted, err := ks.DecryptKey(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(decrypted, key) != 0 {
+		t.Error("decrypted key does not match")
+	}
+	if _, err = ks.DecryptKey([]byte("wrong")); errors.Is(err, ErrWrongPassphrase) == false {
+		t.Fatalf("expected %v, got %v", ErrWrongPassphrase, err)
+	}
+	if _, err = ks.Decrypt(passphrase); errors.Is(err, ErrInvalidKeystore) == false {
+		t.Fatalf("expected the key not to unlock as a wallet, got %v", err)
+	}
+
+	wallet, err := EncryptWallet("alice", MakeWalletGroup(), passphrase, LightKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wallet.DecryptKey(passphrase); errors.Is(err, ErrInvalidKeystore) == false {
+		t.Fatalf("expected a wallet not to decrypt as a key, got %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/dgraph-io/badger"
+	filesystem "github.com/thedhejavu/ev-blockchain-protocol/pkg/fs"
+)
+
+// Wallet storage backends
+const (
+	STORE_FILE     = "file"
+	STORE_BADGERDB = "badgerdb"
+)
+
+var (
+	ErrWalletNotFound = errors.New("Wallet not found")
+	ErrUnknownStore   = errors.New("Unknown wallet storage")
+
+	// DefaultStore is the backend used by InitializeWallets
+	DefaultStore = STORE_FILE
+
+	walletsDBPath   = path.Join(Root, "/storage/wallets_db")
+	walletKeyPrefix = []byte("wallet_")
+
+	// Stores opened by InitializeWallets are shared by the whole process, a
+	// badger database can only be opened once
+	openStores   = map[string]WalletStore{}
+	openStoresMu sync.Mutex
+)
+
+// WalletStore keeps the encrypted keystores of the wallets. Implementations
+// are safe for concurrent use.
+type WalletStore interface {
+	// Get returns the keystore of the wallet, ErrWalletNotFound when missing
+	Get(userId string) (*Keystore, error)
+	// Put saves the keystore, replacing the previous one
+	Put(ks *Keystore) error
+	// Delete removes the keystore of the wallet
+	Delete(userId string) error
+	// List returns the keystores sorted by wallet ID
+	List() ([]*Keystore, error)
+	Close() error
+}
+
+// NewWalletStore opens the wallet storage of the given type
+func NewWalletStore(storeType string) (WalletStore, error) {
+	switch storeType {
+	case STORE_FILE:
+		return NewFileStore(walletsPath), nil
+	case STORE_BADGERDB:
+		return NewBadgerStore(walletsDBPath)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownStore, storeType)
+}
+
+// defaultWalletStore opens the default store once per process
+func defaultWalletStore() (WalletStore, error) {
+	openStoresMu.Lock()
+	defer openStoresMu.Unlock()
+
+	if store, ok := openStores[DefaultStore]; ok {
+		return store, nil
+	}
+	store, err := NewWalletStore(DefaultStore)
+	if err != nil {
+		return nil, err
+	}
+	openStores[DefaultStore] = store
+	return store, nil
+}
+
+// CloseStores closes the stores opened by InitializeWallets
+func CloseStores() error {
+	openStoresMu.Lock()
+	defer openStoresMu.Unlock()
+
+	var err error
+	for storeType, store := range openStores {
+		if closeErr := store.Close(); err == nil {
+			err = closeErr
+		}
+		delete(openStores, storeType)
+	}
+	return err
+}
+
+// FileStore keeps every keystore in its own JSON file, readable by its owner
+// only
+type FileStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFileStore returns a store of the keystore files of the directory, the
+// directory is created on the first write
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) file(userId string) string {
+	return path.Join(s.dir, userId+keystoreExt)
+}
+
+func (s *FileStore) Get(userId string) (*Keystore, error) {
+	if err := ValidateWalletID(userId); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	content, err := ioutil.ReadFile(s.file(userId))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrWalletNotFound, userId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeystore(content)
+}
+
+// Put writes the keystore to a temporary file renamed over the previous one,
+// a failed write never leaves a truncated keystore behind
+func (s *FileStore) Put(ks *Keystore) error {
+	if err := ValidateWalletID(ks.ID); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = filesystem.ExistOrCreate(s.dir); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.dir, "."+ks.ID+keystoreExt+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(filesystem.OwnerReadWrite); err == nil {
+		if _, err = tmp.Write(content); err == nil {
+			err = tmp.Sync()
+		}
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.file(ks.ID))
+}
+
+func (s *FileStore) Delete(userId string) error {
+	if err := ValidateWalletID(userId); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.file(userId))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrWalletNotFound, userId)
+	}
+	return err
+}
+
+func (s *FileStore) List() ([]*Keystore, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files, err := filepath.Glob(path.Join(s.dir, "*"+keystoreExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	keystores := make([]*Keystore, 0, len(files))
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		ks, err := ParseKeystore(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		keystores = append(keystores, ks)
+	}
+	return keystores, nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// BadgerStore keeps the keystores in a badger database, every write is a
+// transaction
+type BadgerStore struct {
+	db *badger.DB
+}
+
+// NewBadgerStore opens the badger database of the directory, it is created
+// if it doesn't exist
+func NewBadgerStore(dir string) (*BadgerStore, error) {
+	opts := badger.DefaultOptions(dir)
+	opts.Logger = nil
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStore{db}, nil
+}
+
+func walletKey(userId string) []byte {
+	return append(append([]byte{}, walletKeyPrefix...), userId...)
+}
+
+func (s *BadgerStore) Get(userId string) (*Keystore, error) {
+	var ks *Keystore
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(walletKey(userId))
+		if err == badger.ErrKeyNotFound {
+			return fmt.Errorf("%w: %s", ErrWalletNotFound, userId)
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(content []byte) error {
+			ks, err = ParseKeystore(content)
+			return err
+		})
+	})
+	return ks, err
+}
+
+func (s *BadgerStore) Put(ks *Keystore) error {
+	if err := ValidateWalletID(ks.ID); err != nil {
+		return err
+	}
+	content, err := json.Marshal(ks)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(walletKey(ks.ID), content)
+	})
+}
+
+func (s *BadgerStore) Delete(userId string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(walletKey(userId)); err == badger.ErrKeyNotFound {
+			return fmt.Errorf("%w: %s", ErrWalletNotFound, userId)
+		} else if err != nil {
+			return err
+		}
+		return txn.Delete(walletKey(userId))
+	})
+}
+
+func (s *BadgerStore) List() ([]*Keystore, error) {
+	var keystores []*Keystore
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = walletKeyPrefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(walletKeyPrefix); it.ValidForPrefix(walletKeyPrefix); it.Next() {
+			err := it.Item().Value(func(content []byte) error {
+				ks, err := ParseKeystore(content)
+				if err != nil {
+					return fmt.Errorf("%s: %w", it.Item().Key(), err)
+				}
+				keystores = append(keystores, ks)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return keystores, err
+}
+
+func (s *BadgerStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,50 @@
+package wallet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"syscall"
+	"testing"
+)
+
+// A write failing halfway, here on the file size limit, keeps the previous
+// keystore and leaves no temporary file behind
+func TestFileStoreFailedPut(t *testing.T) {
+	store := newTestStore(t)
+	saved := newTestKeystore(t, "alice")
+	if err := store.Put(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_FSIZE, &limit); err != nil {
+		t.Fatal(err)
+	}
+	small := limit
+	small.Cur = 64
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &small); err != nil {
+		t.Skip("file size limit unavailable:", err)
+	}
+	err := store.Put(newTestKeystore(t, "alice"))
+	if restoreErr := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit); restoreErr != nil {
+		t.Fatal(restoreErr)
+	}
+	if err == nil {
+		t.Fatal("expected the write to fail")
+	}
+
+	got, err := store.Get("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(got.MainPublicKey, saved.MainPublicKey) != 0 || bytes.Compare(got.Crypto.Ciphertext, saved.Crypto.Ciphertext) != 0 {
+		t.Error("expected the previous keystore to be kept")
+	}
+	files, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected the temporary file to be removed, got %d files", len(files))
+	}
+}
@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+// testStores opens an empty store of every backend
+func testStores(t *testing.T) map[string]WalletStore {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "wallets_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	badgerStore, err := NewBadgerStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { badgerStore.Close() })
+
+	return map[string]WalletStore{
+		STORE_FILE:     newTestStore(t),
+		STORE_BADGERDB: badgerStore,
+	}
+}
+
+func newTestKeystore(t *testing.T, userId string) *Keystore {
+	t.Helper()
+	ks, err := EncryptWallet(userId, MakeWalletGroup(), []byte("passphrase"), LightKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func TestStore(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Get("alice"); errors.Is(err, ErrWalletNotFound) == false {
+				t.Fatalf("expected %v, got %v", ErrWalletNotFound, err)
+			}
+			alice, bob := newTestKeystore(t, "alice"), newTestKeystore(t, "bob")
+			for _, ks := range []*Keystore{bob, alice} {
+				if err := store.Put(ks); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := store.Get("alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Compare(got.MainPublicKey, alice.MainPublicKey) != 0 || bytes.Compare(got.Crypto.Ciphertext, alice.Crypto.Ciphertext) != 0 {
+				t.Error("keystore does not match")
+			}
+
+			// Put replaces the keystore
+			replaced := newTestKeystore(t, "alice")
+			if err = store.Put(replaced); err != nil {
+				t.Fatal(err)
+			}
+			if got, err = store.Get("alice"); err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Compare(got.MainPublicKey, replaced.MainPublicKey) != 0 {
+				t.Error("expected the keystore to be replaced")
+			}
+
+			list, err := store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 || list[0].ID != "alice" || list[1].ID != "bob" {
+				t.Fatalf("expected alice and bob, got %d keystores", len(list))
+			}
+
+			if err = store.Delete("alice"); err != nil {
+				t.Fatal(err)
+			}
+			if err = store.Delete("alice"); errors.Is(err, ErrWalletNotFound) == false {
+				t.Fatalf("expected %v, got %v", ErrWalletNotFound, err)
+			}
+			if _, err = store.Get("alice"); errors.Is(err, ErrWalletNotFound) == false {
+				t.Fatalf("expected %v, got %v", ErrWalletNotFound, err)
+			}
+			if list, err = store.List(); err != nil || len(list) != 1 {
+				t.Fatalf("expected bob only, got %d keystores, %v", len(list), err)
+			}
+
+			invalid := *bob
+			invalid.ID = "../alice"
+			if err = store.Put(&invalid); errors.Is(err, ErrInvalidWalletID) == false {
+				t.Fatalf("expected %v, got %v", ErrInvalidWalletID, err)
+			}
+		})
+	}
+}
+
+func TestStoreConcurrentPut(t *testing.T) {
+	const writers = 8
+	keystores := make([]*Keystore, writers)
+	for i := range keystores {
+		keystores[i] = newTestKeystore(t, "shared")
+	}
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			errs := make(chan error, 2*writers)
+			for i := 0; i < writers; i++ {
+				wg.Add(2)
+				go func(i int) {
+					defer wg.Done()
+					errs <- store.Put(keystores[i])
+				}(i)
+				go func(i int) {
+					defer wg.Done()
+					ks := *keystores[i]
+					ks.ID = fmt.Sprintf("wallet%d", i)
+					errs <- store.Put(&ks)
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The shared keystore is one of the writes, never a mix of them
+			shared, err := store.Get("shared")
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, ks := range keystores {
+				if bytes.Compare(shared.Crypto.Ciphertext, ks.Crypto.Ciphertext) == 0 {
+					found = bytes.Compare(shared.MainPublicKey, ks.MainPublicKey) == 0
+				}
+			}
+			if found == false {
+				t.Error("expected the keystore of one of the writers")
+			}
+			list, err := store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != writers+1 {
+				t.Errorf("expected %d keystores, got %d", writers+1, len(list))
+			}
+		})
+	}
+}
@@ -11,11 +11,24 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"math/big"
 	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/ecies"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/identity"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/signer"
+)
+
+var ErrInvalidViewKey = errors.New("Invalid public view key")
+
+// PEM labels of the public view keys. ECDH keys of earlier versions are
+// labelled "EC PUBLIC KEY" and are still accepted.
+const (
+	rsaPublicKeyBlock      = "RSA PUBLIC KEY"
+	ecPublicKeyBlock       = "PUBLIC KEY"
+	legacyECPublicKeyBlock = "EC PUBLIC KEY"
 )
 
 var (
@@ -25,9 +38,11 @@ var (
 
 // https://golang.org/pkg/crypto/ecdsa/
 type WalletView struct {
-	// RSA algorithm
+	// RSA algorithm, secret messages of earlier versions are encrypted with it
 	PrivateKey rsa.PrivateKey
 	PublicKey  []byte
+	// ECDH key of the hybrid encryption
+	HybridKey *ecdsa.PrivateKey
 }
 
 type WalletMain struct {
@@ -40,27 +55,96 @@ type WalletGroup struct {
 	Main        *WalletMain
 	View        *WalletView
 	Certificate []byte
+
+	// Seed of the wallets created from a mnemonic, election wallets are
+	// derived from it
+	Seed []byte
 }
 
+// Decrypt decrypts a secret message encrypted to the view key. Hybrid
+// ciphertexts are decrypted with the ECDH key, the others are RSA ciphertexts
+// of earlier versions.
 func (w *WalletView) Decrypt(ciphertext []byte) ([]byte, error) {
-	block, _ := pem.Decode(w.PublicKey)
-	if block == nil {
-		return nil, errors.New("private key error!")
+	if ecies.IsHybrid(ciphertext) && w.HybridKey != nil {
+		message, err := ecies.Decrypt(w.HybridKey, ciphertext)
+		// An RSA ciphertext can start like a hybrid one
+		if err == nil || len(ciphertext) != w.PrivateKey.Size() {
+			return message, err
+		}
 	}
 	return rsa.DecryptPKCS1v15(rand.Reader, &w.PrivateKey, ciphertext)
 }
 
 func (w *WalletView) Encrypt(origData []byte) ([]byte, error) {
-	block, _ := pem.Decode(w.PublicKey)
-	if block == nil {
-		return nil, errors.New("public key error")
+	return EncryptView(w.PublicKey, origData)
+}
+
+// EncryptView encrypts a secret message to a public view key. View keys with
+// an ECDH key use the hybrid encryption, view keys of earlier versions only
+// have an RSA key.
+func EncryptView(viewPublicKey []byte, message []byte) ([]byte, error) {
+	var rsaKey *rsa.PublicKey
+	for rest := viewPublicKey; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		switch block.Type {
+		case rsaPublicKeyBlock, ecPublicKeyBlock, legacyECPublicKeyBlock:
+		default:
+			return nil, fmt.Errorf("%w: unexpected %s block", ErrInvalidViewKey, block.Type)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch pub := pub.(type) {
+		case *ecdsa.PublicKey:
+			return ecies.Encrypt(pub, message)
+		case *rsa.PublicKey:
+			rsaKey = pub
+		}
 	}
-	pubInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if rsaKey == nil {
+		return nil, ErrInvalidViewKey
+	}
+	return rsa.EncryptPKCS1v15(rand.Reader, rsaKey, message)
+}
+
+// Upgrade adds an ECDH key to a view key of an earlier version, it reports
+// whether the key changed
+func (w *WalletView) Upgrade() (bool, error) {
+	if w.HybridKey != nil {
+		return false, nil
+	}
+	hybridKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	return true, w.setHybridKey(hybridKey)
+}
+
+// UpgradeView adds an ECDH key to the view key of a wallet of an earlier
+// version. Wallets created from a mnemonic derive it from their seed so it is
+// recovered with the mnemonic.
+func (w *WalletGroup) UpgradeView() (bool, error) {
+	if w.View.HybridKey != nil || w.Seed == nil {
+		return w.View.Upgrade()
+	}
+	hybridKey, err := identityHybridKey(w.Seed)
+	if err != nil {
+		return false, err
+	}
+	return true, w.View.setHybridKey(hybridKey)
+}
+
+func (w *WalletView) setHybridKey(hybridKey *ecdsa.PrivateKey) error {
+	publicKey, err := viewPublicKeyPEM(&w.PrivateKey.PublicKey, &hybridKey.PublicKey)
+	if err != nil {
+		return err
 	}
-	pub := pubInterface.(*rsa.PublicKey)
-	return rsa.EncryptPKCS1v15(rand.Reader, pub, origData)
+	w.HybridKey, w.PublicKey = hybridKey, publicKey
+	return nil
 }
 
 func (w *WalletView) CanDecrypt(payload []byte) bool {
@@ -82,9 +166,15 @@ func (w *WalletGroup) String() string {
 	return strings.Join(lines, "\n")
 }
 
+// GenerateCert creates the self-signed certificate of a wallet. Voters are
+// certified by the election commission with the identity package.
 func GenerateCert(pub interface{}, priv *ecdsa.PrivateKey) []byte {
+	serial, err := identity.NewSerial()
+	if err != nil {
+		log.Fatalf("Failed to create certificate serial: %s\n", err)
+	}
 	template := x509.Certificate{
-		SerialNumber: big.NewInt(1),
+		SerialNumber: serial,
 		Subject: pkix.Name{
 			Organization: []string{"DID, Inc."},
 		},
@@ -110,7 +200,7 @@ func GenerateCert(pub interface{}, priv *ecdsa.PrivateKey) []byte {
 	return cert.Bytes()
 }
 
-// Generate new Key Pair using ecdsa
+// Generate new Key Pair using ecdsa, the public key is SEC1 compressed
 func NewKeyPair() (*ecdsa.PrivateKey, []byte) {
 	curve := elliptic.P256()
 
@@ -119,11 +209,43 @@ func NewKeyPair() (*ecdsa.PrivateKey, []byte) {
 		log.Panic(err)
 	}
 
-	pub := append(private.PublicKey.X.Bytes(), private.PublicKey.Y.Bytes()...)
+	pub := keys.FromECDSA(&private.PublicKey).Bytes()
 
 	return private, pub
 }
 
+// Migrate re-encodes a public key stored with the legacy X||Y encoding from
+// the private key of the wallet, it reports whether the key changed
+func (w *WalletMain) Migrate() bool {
+	if !keys.IsLegacy(w.PublicKey) {
+		return false
+	}
+	w.PublicKey = keys.FromECDSA(&w.PrivateKey.PublicKey).Bytes()
+	return true
+}
+
+// NewSchemeKeyPair generates a new key pair of a registered signature scheme,
+// the public key is tagged with the scheme
+func NewSchemeKeyPair(scheme signer.Scheme) (signer.Signer, []byte, error) {
+	s, err := signer.Generate(scheme)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, s.PublicKey(), nil
+}
+
+// Signer returns a signer of the given scheme using the main key of the
+// wallet. Only the P-256 schemes can use it.
+func (w *WalletMain) Signer(scheme signer.Scheme) (signer.Signer, error) {
+	switch scheme {
+	case signer.ECDSAP256:
+		return signer.NewECDSASigner(&w.PrivateKey), nil
+	case signer.SchnorrP256:
+		return signer.NewSchnorrSigner(&w.PrivateKey), nil
+	}
+	return nil, fmt.Errorf("%w: main wallet key cannot sign with scheme 0x%02x", signer.ErrUnknownScheme, byte(scheme))
+}
+
 func NewRSAKeyPair() (*rsa.PrivateKey, []byte) {
 	// Generate RSA Keys
 	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
@@ -132,18 +254,42 @@ func NewRSAKeyPair() (*rsa.PrivateKey, []byte) {
 		log.Panic(err)
 	}
 
-	publicKey := &privateKey.PublicKey
-	pubASN1, err := x509.MarshalPKIXPublicKey(publicKey)
+	pubBytes, err := rsaPublicKeyPEM(&privateKey.PublicKey)
 	if err != nil {
 		log.Error(err)
 	}
 
-	pubBytes := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PUBLIC KEY",
+	return privateKey, pubBytes
+}
+
+// rsaPublicKeyPEM encodes the public view key
+func rsaPublicKeyPEM(publicKey *rsa.PublicKey) ([]byte, error) {
+	pubASN1, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  rsaPublicKeyBlock,
 		Bytes: pubASN1,
-	})
+	}), nil
+}
 
-	return privateKey, pubBytes
+// viewPublicKeyPEM encodes the public view key with its RSA and ECDH keys
+func viewPublicKeyPEM(rsaKey *rsa.PublicKey, hybridKey *ecdsa.PublicKey) ([]byte, error) {
+	public, err := rsaPublicKeyPEM(rsaKey)
+	if err != nil {
+		return nil, err
+	}
+	pubASN1, err := x509.MarshalPKIXPublicKey(hybridKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(public, pem.EncodeToMemory(&pem.Block{
+		Type:  ecPublicKeyBlock,
+		Bytes: pubASN1,
+	})...), nil
 }
 
 func MakeMainWallet() *WalletMain {
@@ -152,8 +298,16 @@ func MakeMainWallet() *WalletMain {
 }
 
 func MakeViewWallet() *WalletView {
-	private, public := NewRSAKeyPair()
-	return &WalletView{*private, public}
+	private, _ := NewRSAKeyPair()
+	hybridKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Panic(err)
+	}
+	public, err := viewPublicKeyPEM(&private.PublicKey, &hybridKey.PublicKey)
+	if err != nil {
+		log.Panic(err)
+	}
+	return &WalletView{*private, public, hybridKey}
 }
 
 func MakeWalletGroup() *WalletGroup {
@@ -0,0 +1,55 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestViewPublicKeyPEM(t *testing.T) {
+	w := MakeViewWallet()
+
+	var types []string
+	for rest := w.PublicKey; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		types = append(types, block.Type)
+	}
+	if len(types) != 2 || types[0] != rsaPublicKeyBlock || types[1] != ecPublicKeyBlock {
+		t.Fatalf("unexpected view public key blocks %v", types)
+	}
+
+	legacy := bytes.Replace(w.PublicKey, []byte("-----BEGIN PUBLIC KEY-----"), []byte("-----BEGIN EC PUBLIC KEY-----"), 1)
+	legacy = bytes.Replace(legacy, []byte("-----END PUBLIC KEY-----"), []byte("-----END EC PUBLIC KEY-----"), 1)
+	other := bytes.Replace(w.PublicKey, []byte("PUBLIC KEY-----"), []byte("CERTIFICATE-----"), -1)
+
+	tests := []struct {
+		name      string
+		publicKey []byte
+		err       error
+	}{
+		{"public key label", w.PublicKey, nil},
+		{"legacy EC label", legacy, nil},
+		{"unexpected label", other, ErrInvalidViewKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ciphertext, err := EncryptView(test.publicKey, []byte("ballot"))
+			if test.err != nil {
+				if errors.Is(err, test.err) == false {
+					t.Fatalf("expected %v, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plaintext, err := w.Decrypt(ciphertext); err != nil || string(plaintext) != "ballot" {
+				t.Errorf("expected the ballot, got %q, %v", plaintext, err)
+			}
+		})
+	}
+}
@@ -7,15 +7,21 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"sync"
 )
 
 type Wallets struct {
-	Wallets map[string]*WalletGroup
+	Wallets   map[string]*WalletGroup
+	keystores map[string]*Keystore
+	store     WalletStore
+
+	// Guards the wallets and keystores maps
+	mu sync.RWMutex
 }
 
 var (
@@ -27,32 +33,218 @@ var (
 	walletsFilename = "wallets.data"
 )
 
+// InitializeWallets loads the keystores of the saved wallets from the default
+// store. They stay locked until unlocked with their passphrase.
 func InitializeWallets() (*Wallets, error) {
-	wallets := Wallets{map[string]*WalletGroup{}}
-	err := wallets.LoadFile()
+	store, err := defaultWalletStore()
+	if err != nil {
+		return nil, err
+	}
+	return NewWallets(store)
+}
+
+// NewWallets loads the keystores of the wallets saved in the store
+func NewWallets(store WalletStore) (*Wallets, error) {
+	wallets := Wallets{
+		Wallets:   map[string]*WalletGroup{},
+		keystores: map[string]*Keystore{},
+		store:     store,
+	}
+	err := wallets.LoadKeystores()
 
 	return &wallets, err
 }
+
+// GetWallet returns a wallet created or unlocked in this session
 func (ws *Wallets) GetWallet(userId string) (WalletGroup, error) {
-	var wallet *WalletGroup
-	var ok bool
-	w := *ws
-	if wallet, ok = w.Wallets[userId]; !ok {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	wallet, ok := ws.Wallets[userId]
+	if !ok {
+		if _, ok = ws.keystores[userId]; ok {
+			return *new(WalletGroup), fmt.Errorf("%w: %s", ErrWalletLocked, userId)
+		}
 		return *new(WalletGroup), errors.New("Invalid ID")
 	}
 
 	return *wallet, nil
 }
 
+// Unlock decrypts the keystore of the wallet with the passphrase
+func (ws *Wallets) Unlock(userId string, passphrase []byte) (WalletGroup, error) {
+	ws.mu.RLock()
+	wallet, unlocked := ws.Wallets[userId]
+	ks, ok := ws.keystores[userId]
+	ws.mu.RUnlock()
+	if unlocked {
+		return *wallet, nil
+	}
+	if !ok {
+		return *new(WalletGroup), errors.New("Invalid ID")
+	}
+
+	// The key derivation is slow, the other wallets stay usable meanwhile
+	wallet, err := ks.Decrypt(passphrase)
+	if err != nil {
+		return *new(WalletGroup), err
+	}
+
+	// Keystores of earlier versions get a view key for the hybrid encryption
+	// and the SEC1 encoding of their main key
+	upgraded, err := wallet.UpgradeView()
+	if err != nil {
+		return *new(WalletGroup), err
+	}
+	if wallet.Main != nil && wallet.Main.Migrate() {
+		upgraded = true
+	}
+	if upgraded {
+		if ks, err = EncryptWallet(userId, wallet, passphrase, StandardKDFParams); err != nil {
+			return *new(WalletGroup), err
+		}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	// Another call unlocked the wallet meanwhile
+	if unlockedWallet, ok := ws.Wallets[userId]; ok {
+		return *unlockedWallet, nil
+	}
+	if upgraded {
+		if err = ws.store.Put(ks); err != nil {
+			return *new(WalletGroup), err
+		}
+		ws.keystores[userId] = ks
+	}
+	ws.Wallets[userId] = wallet
+
+	return *wallet, nil
+}
+
 func (ws *Wallets) AddWallet(userId string) string {
 	wallet := MakeWalletGroup()
 	userId = fmt.Sprintf("%s", userId)
 
+	ws.mu.Lock()
 	ws.Wallets[userId] = wallet
+	ws.mu.Unlock()
 
 	return userId
 }
 
+// Import adds a wallet created elsewhere, it must be saved to be kept
+func (ws *Wallets) Import(userId string, w *WalletGroup) error {
+	if err := ValidateWalletID(userId); err != nil {
+		return err
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	_, unlocked := ws.Wallets[userId]
+	_, saved := ws.keystores[userId]
+	if unlocked || saved {
+		return fmt.Errorf("%w: %s", ErrWalletExists, userId)
+	}
+	ws.Wallets[userId] = w
+
+	return nil
+}
+
+// Keystore returns the encrypted keystore of a saved wallet
+func (ws *Wallets) Keystore(userId string) (*Keystore, error) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	ks, ok := ws.keystores[userId]
+	if !ok {
+		return nil, errors.New("Invalid ID")
+	}
+	return ks, nil
+}
+
+// IDs returns the sorted IDs of the saved and unlocked wallets
+func (ws *Wallets) IDs() []string {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	ids := make([]string, 0, len(ws.keystores))
+	for userId := range ws.keystores {
+		ids = append(ids, userId)
+	}
+	for userId := range ws.Wallets {
+		if _, ok := ws.keystores[userId]; !ok {
+			ids = append(ids, userId)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Delete removes the wallet and its keystore from the store
+func (ws *Wallets) Delete(userId string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	_, unlocked := ws.Wallets[userId]
+	_, saved := ws.keystores[userId]
+	if !unlocked && !saved {
+		return errors.New("Invalid ID")
+	}
+	if saved {
+		if err := ws.store.Delete(userId); err != nil {
+			return err
+		}
+	}
+	delete(ws.Wallets, userId)
+	delete(ws.keystores, userId)
+
+	return nil
+}
+
+// LoadKeystores reads the keystore of every saved wallet
+func (ws *Wallets) LoadKeystores() error {
+	keystores, err := ws.store.List()
+	if err != nil {
+		return err
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for _, ks := range keystores {
+		ws.keystores[ks.ID] = ks
+	}
+
+	return nil
+}
+
+// Save encrypts the wallet with the passphrase into its keystore
+func (ws *Wallets) Save(userId string, passphrase []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	return ws.save(userId, passphrase)
+}
+
+func (ws *Wallets) save(userId string, passphrase []byte) error {
+	wallet, ok := ws.Wallets[userId]
+	if !ok {
+		return errors.New("Invalid ID")
+	}
+	ks, err := EncryptWallet(userId, wallet, passphrase, StandardKDFParams)
+	if err != nil {
+		return err
+	}
+	if err = ws.store.Put(ks); err != nil {
+		return err
+	}
+	ws.keystores[userId] = ks
+
+	return nil
+}
+
+// LoadFile reads the unencrypted wallets file written by earlier versions
 func (ws *Wallets) LoadFile() error {
 	walletsFile := path.Join(walletsPath, walletsFilename)
 
@@ -72,25 +264,45 @@ func (ws *Wallets) LoadFile() error {
 		return err
 	}
 
-	ws.Wallets = wallets.Wallets
+	// Wallets saved before the SEC1 encoding keep working once loaded, their
+	// view keys are upgraded when they are encrypted by MigrateFile
+	for _, w := range wallets.Wallets {
+		if w.Main != nil {
+			w.Main.Migrate()
+		}
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for userId, w := range wallets.Wallets {
+		ws.Wallets[userId] = w
+	}
 
 	return nil
 }
-func (ws *Wallets) Save() {
-	walletsFile := path.Join(walletsPath, walletsFilename)
-
-	var content bytes.Buffer
 
-	gob.Register(elliptic.P256())
-
-	encoder := gob.NewEncoder(&content)
-	err := encoder.Encode(ws)
-	if err != nil {
-		log.Panic(err)
+// MigrateFile encrypts the wallets of the unencrypted wallets file with the
+// passphrase, then removes the file
+func (ws *Wallets) MigrateFile(passphrase []byte) ([]string, error) {
+	if err := ws.LoadFile(); err != nil {
+		return nil, err
 	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
-	err = ioutil.WriteFile(walletsFile, content.Bytes(), 0644)
-	if err != nil {
-		log.Panic(err)
+	var migrated []string
+	for userId := range ws.Wallets {
+		if _, ok := ws.keystores[userId]; ok {
+			continue
+		}
+		if _, err := ws.Wallets[userId].UpgradeView(); err != nil {
+			return migrated, err
+		}
+		if err := ws.save(userId, passphrase); err != nil {
+			return migrated, err
+		}
+		migrated = append(migrated, userId)
 	}
+	sort.Strings(migrated)
+
+	return migrated, os.Remove(path.Join(walletsPath, walletsFilename))
 }
@@ -0,0 +1,165 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/thedhejavu/ev-blockchain-protocol/pkg/crypto/keys"
+)
+
+func newTestStore(t *testing.T) *FileStore {
+	dir, err := ioutil.TempDir("", "wallets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return NewFileStore(dir)
+}
+
+func legacyPublicKey(w *WalletMain) []byte {
+	return append(w.PrivateKey.X.Bytes(), w.PrivateKey.Y.Bytes()...)
+}
+
+func TestUnlockMigratesLegacyKey(t *testing.T) {
+	passphrase := []byte("passphrase")
+	store := newTestStore(t)
+
+	w := MakeWalletGroup()
+	w.Main.PublicKey = legacyPublicKey(w.Main)
+	if keys.IsLegacy(w.Main.PublicKey) == false {
+		t.Fatal("expected a legacy key")
+	}
+	ks, err := EncryptWallet("legacy", w, passphrase, LightKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Put(ks); err != nil {
+		t.Fatal(err)
+	}
+
+	wallets, err := NewWallets(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlocked, err := wallets.Unlock("legacy", passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := keys.FromECDSA(&w.Main.PrivateKey.PublicKey).Bytes()
+	if bytes.Compare(unlocked.Main.PublicKey, compressed) != 0 {
+		t.Errorf("expected the unlocked key to be migrated, got %x", unlocked.Main.PublicKey)
+	}
+
+	saved, err := store.Get("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(saved.MainPublicKey, compressed) != 0 {
+		t.Errorf("expected the migrated key to be saved, got %x", saved.MainPublicKey)
+	}
+	reloaded, err := saved.Decrypt(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(reloaded.Main.PublicKey, compressed) != 0 {
+		t.Errorf("expected the saved wallet to hold the migrated key, got %x", reloaded.Main.PublicKey)
+	}
+}
+
+func TestUnlockDerivesHDViewKey(t *testing.T) {
+	passphrase := []byte("passphrase")
+	store := newTestStore(t)
+
+	seed := bytes.Repeat([]byte{0x42}, 64)
+	w, err := MakeHDWalletGroup(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := w.View.HybridKey
+	// Keystores of earlier versions have no ECDH view key
+	w.View.HybridKey = nil
+	w.View.PublicKey, err = rsaPublicKeyPEM(&w.View.PrivateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := EncryptWallet("hd", w, passphrase, LightKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Put(ks); err != nil {
+		t.Fatal(err)
+	}
+
+	wallets, err := NewWallets(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlocked, err := wallets.Unlock("hd", passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unlocked.View.HybridKey == nil || unlocked.View.HybridKey.Equal(expected) == false {
+		t.Fatal("expected the view ECDH key to be derived from the seed")
+	}
+	saved, err := store.Get("hd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := saved.Decrypt(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.View.HybridKey == nil || reloaded.View.HybridKey.Equal(expected) == false {
+		t.Error("expected the upgraded view key to be saved")
+	}
+}
+
+func TestUnlockConcurrent(t *testing.T) {
+	passphrase := []byte("passphrase")
+	store := newTestStore(t)
+	if err := store.Put(newTestKeystore(t, "alice")); err != nil {
+		t.Fatal(err)
+	}
+	wallets, err := NewWallets(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = wallets.Unlock("alice", []byte("wrong")); err == nil {
+		t.Fatal("expected the wrong passphrase to be refused")
+	}
+	if _, err = wallets.GetWallet("alice"); errors.Is(err, ErrWalletLocked) == false {
+		t.Fatalf("expected %v, got %v", ErrWalletLocked, err)
+	}
+
+	const unlocks = 4
+	results := make([]WalletGroup, unlocks)
+	errs := make([]error, unlocks)
+	var wg sync.WaitGroup
+	for i := 0; i < unlocks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = wallets.Unlock("alice", passphrase)
+		}(i)
+	}
+	wg.Wait()
+
+	unlocked, err := wallets.GetWallet("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if results[i].Main != unlocked.Main {
+			t.Error("expected every unlock to return the wallet kept by the first one")
+		}
+	}
+}